@@ -0,0 +1,149 @@
+package advisory
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+)
+
+// The risk levels, from the lowest. They are common to all standards, whose
+// categories map onto them.
+const (
+	RiskLow      = "low"
+	RiskModerate = "moderate"
+	RiskElevated = "elevated"
+	RiskHigh     = "high"
+	RiskVeryHigh = "very_high"
+	RiskSevere   = "severe"
+)
+
+const (
+	GroupChildren     = "children"
+	GroupElderly      = "elderly"
+	GroupAsthma       = "asthma"
+	GroupHeartDisease = "heart_disease"
+)
+
+const (
+	ActionEnjoyOutdoors  = "enjoy_outdoors"
+	ActionWatchSymptoms  = "watch_symptoms"
+	ActionReduceExertion = "reduce_exertion"
+	ActionAvoidExertion  = "avoid_exertion"
+	ActionMoveIndoors    = "move_indoors"
+	ActionAvoidOutdoors  = "avoid_outdoors"
+	ActionKeepMedication = "keep_medication"
+	ActionCloseWindows   = "close_windows"
+	ActionWearMask       = "wear_mask"
+)
+
+// risks are the risk levels of the categories of every standard, from its
+// level 1.
+var risks = map[string][]string{
+	aqi.USEPA:  {RiskLow, RiskModerate, RiskElevated, RiskHigh, RiskVeryHigh, RiskSevere},
+	aqi.EUCAQI: {RiskLow, RiskLow, RiskModerate, RiskElevated, RiskHigh},
+	aqi.INNAQI: {RiskLow, RiskModerate, RiskElevated, RiskHigh, RiskVeryHigh, RiskSevere},
+}
+
+// rank orders the risk levels.
+var rank = map[string]int{
+	RiskLow:      0,
+	RiskModerate: 1,
+	RiskElevated: 2,
+	RiskHigh:     3,
+	RiskVeryHigh: 4,
+	RiskSevere:   5,
+}
+
+type guidance struct {
+	actions []string
+	groups  map[string][]string
+}
+
+// groupOrder is the order affected groups are listed in.
+var groupOrder = []string{GroupChildren, GroupElderly, GroupAsthma, GroupHeartDisease}
+
+var guidances = map[string]guidance{
+	RiskLow: {
+		actions: []string{ActionEnjoyOutdoors},
+	},
+	RiskModerate: {
+		actions: []string{ActionEnjoyOutdoors},
+		groups: map[string][]string{
+			GroupAsthma: {ActionReduceExertion, ActionWatchSymptoms},
+		},
+	},
+	RiskElevated: {
+		actions: []string{ActionWatchSymptoms},
+		groups: map[string][]string{
+			GroupChildren:     {ActionReduceExertion},
+			GroupElderly:      {ActionReduceExertion},
+			GroupAsthma:       {ActionReduceExertion, ActionKeepMedication},
+			GroupHeartDisease: {ActionReduceExertion, ActionWatchSymptoms},
+		},
+	},
+	RiskHigh: {
+		actions: []string{ActionReduceExertion},
+		groups: map[string][]string{
+			GroupChildren:     {ActionAvoidExertion},
+			GroupElderly:      {ActionAvoidExertion},
+			GroupAsthma:       {ActionAvoidExertion, ActionKeepMedication},
+			GroupHeartDisease: {ActionAvoidExertion, ActionWatchSymptoms},
+		},
+	},
+	RiskVeryHigh: {
+		actions: []string{ActionAvoidExertion, ActionCloseWindows},
+		groups: map[string][]string{
+			GroupChildren:     {ActionMoveIndoors},
+			GroupElderly:      {ActionMoveIndoors},
+			GroupAsthma:       {ActionMoveIndoors, ActionKeepMedication},
+			GroupHeartDisease: {ActionMoveIndoors, ActionWatchSymptoms},
+		},
+	},
+	RiskSevere: {
+		actions: []string{ActionAvoidOutdoors, ActionCloseWindows, ActionWearMask},
+		groups: map[string][]string{
+			GroupChildren:     {ActionAvoidOutdoors},
+			GroupElderly:      {ActionAvoidOutdoors},
+			GroupAsthma:       {ActionAvoidOutdoors, ActionKeepMedication},
+			GroupHeartDisease: {ActionAvoidOutdoors, ActionWatchSymptoms},
+		},
+	},
+}
+
+// Risk returns the risk level of the index.
+func Risk(index entities.AQI) string {
+	levels := risks[index.Standard]
+	if len(levels) == 0 {
+		return RiskLow
+	}
+
+	return levels[min(max(index.Level, 1), len(levels))-1]
+}
+
+// For returns the advisory of the risk level, with its messages in the
+// locale.
+func For(risk, locale string) entities.Advisory {
+	catalog := catalogFor(locale)
+	guidance := guidances[risk]
+
+	advisory := entities.Advisory{
+		Risk:           risk,
+		Summary:        catalog.risk(risk),
+		Actions:        catalog.actions(guidance.actions),
+		AffectedGroups: []entities.GroupAdvisory{},
+	}
+
+	for _, group := range groupOrder {
+		actions, ok := guidance.groups[group]
+		if !ok {
+			continue
+		}
+
+		advisory.AffectedGroups = append(advisory.AffectedGroups, entities.GroupAdvisory{
+			Group:   group,
+			Name:    catalog.group(group),
+			Actions: catalog.actions(actions),
+		})
+	}
+
+	return advisory
+}
@@ -0,0 +1,164 @@
+package advisory
+
+import (
+	"cmp"
+	"embed"
+	"encoding/json"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the locale of requests in none of the catalogs, and the
+// fallback of messages missing from the others.
+const DefaultLocale = "en"
+
+//go:embed catalogs
+var catalogsFS embed.FS
+
+// catalog holds the messages of a locale, by risk level, group and action.
+type catalog struct {
+	Risks   map[string]string `json:"risks"`
+	Groups  map[string]string `json:"groups"`
+	Actions map[string]string `json:"actions"`
+}
+
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]catalog {
+	entries, err := catalogsFS.ReadDir("catalogs")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]catalog, len(entries))
+
+	for _, entry := range entries {
+		data, err := catalogsFS.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var c catalog
+		if err = json.Unmarshal(data, &c); err != nil {
+			panic("advisory catalog " + entry.Name() + ": " + err.Error())
+		}
+
+		loaded[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = c
+	}
+
+	return loaded
+}
+
+// Locales returns the locales with a catalog.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+// Locale picks the locale of the first of the language preferences with a
+// catalog, or DefaultLocale. A preference is either a language tag or an
+// Accept-Language header, whose languages are tried in their order of
+// quality.
+func Locale(preferences ...string) string {
+	for _, preference := range preferences {
+		for _, language := range acceptedLanguages(preference) {
+			primary, _, _ := strings.Cut(language, "-")
+			primary = strings.ToLower(strings.TrimSpace(primary))
+
+			if _, ok := catalogs[primary]; ok {
+				return primary
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// acceptedLanguages returns the languages of an Accept-Language header, the
+// most preferred first.
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		language string
+		quality  float64
+	}
+
+	var languages []weighted
+
+	for _, part := range strings.Split(header, ",") {
+		language, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if language == "" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = value
+			}
+		}
+
+		if quality > 0 {
+			languages = append(languages, weighted{language, quality})
+		}
+	}
+
+	slices.SortStableFunc(languages, func(a, b weighted) int {
+		return cmp.Compare(b.quality, a.quality)
+	})
+
+	ordered := make([]string, len(languages))
+	for i, language := range languages {
+		ordered[i] = language.language
+	}
+
+	return ordered
+}
+
+func catalogFor(locale string) catalog {
+	if c, ok := catalogs[locale]; ok {
+		return c
+	}
+
+	return catalogs[DefaultLocale]
+}
+
+func (c catalog) risk(risk string) string {
+	return message(c.Risks, catalogs[DefaultLocale].Risks, risk)
+}
+
+func (c catalog) group(group string) string {
+	return message(c.Groups, catalogs[DefaultLocale].Groups, group)
+}
+
+func (c catalog) actions(codes []string) []entities.AdviceAction {
+	actions := make([]entities.AdviceAction, len(codes))
+	for i, code := range codes {
+		actions[i] = entities.AdviceAction{
+			Code:    code,
+			Message: message(c.Actions, catalogs[DefaultLocale].Actions, code),
+		}
+	}
+
+	return actions
+}
+
+// message returns the message of the key, falling back to the default
+// locale and then to the key itself.
+func message(messages, fallback map[string]string, key string) string {
+	if msg, ok := messages[key]; ok {
+		return msg
+	}
+
+	if msg, ok := fallback[key]; ok {
+		return msg
+	}
+
+	return key
+}
@@ -0,0 +1,74 @@
+package advisory
+
+import (
+	"cmp"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"math"
+	"slices"
+)
+
+// hour is the step of the forecast datapoints, in seconds.
+const hour = 3600
+
+// Outlook splits the indexed forecast into periods of consecutive hours of
+// the same risk level.
+func Outlook(forecast []entities.IndexedAirPollutionDatapoint) []entities.AirQualityPeriod {
+	periods := []entities.AirQualityPeriod{}
+
+	var sum, hours int
+
+	for i, datapoint := range forecast {
+		risk := Risk(datapoint.AQI)
+
+		if i == 0 || risk != periods[len(periods)-1].Risk || datapoint.Dt != periods[len(periods)-1].End {
+			periods = append(periods, entities.AirQualityPeriod{Start: datapoint.Dt, Risk: risk})
+			sum, hours = 0, 0
+		}
+
+		period := &periods[len(periods)-1]
+		period.End = datapoint.Dt + hour
+		period.PeakAQI = max(period.PeakAQI, datapoint.AQI.Value)
+
+		sum += datapoint.AQI.Value
+		hours++
+		period.MeanAQI = int(math.Round(float64(sum) / float64(hours)))
+	}
+
+	return periods
+}
+
+// SafestWindows returns up to limit periods of the outlook at its lowest risk
+// level, the cleanest and then the longest first, with the advisory of that
+// level in the locale.
+func SafestWindows(outlook []entities.AirQualityPeriod, limit int, locale string) []entities.AirQualityPeriod {
+	if len(outlook) == 0 {
+		return []entities.AirQualityPeriod{}
+	}
+
+	lowest := slices.MinFunc(outlook, func(a, b entities.AirQualityPeriod) int {
+		return cmp.Compare(rank[a.Risk], rank[b.Risk])
+	}).Risk
+
+	var windows []entities.AirQualityPeriod
+	for _, period := range outlook {
+		if period.Risk == lowest {
+			windows = append(windows, period)
+		}
+	}
+
+	slices.SortStableFunc(windows, func(a, b entities.AirQualityPeriod) int {
+		return cmp.Or(
+			cmp.Compare(a.MeanAQI, b.MeanAQI),
+			cmp.Compare(b.End-b.Start, a.End-a.Start),
+		)
+	})
+
+	windows = windows[:min(limit, len(windows))]
+
+	advisory := For(lowest, locale)
+	for i := range windows {
+		windows[i].Advisory = &advisory
+	}
+
+	return windows
+}
@@ -0,0 +1,151 @@
+package aqi
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"math"
+)
+
+const (
+	// USEPA is the AQI of the US Environmental Protection Agency, from 0 to
+	// 500.
+	USEPA = "us_epa"
+	// EUCAQI is the European Common Air Quality Index, from 0 to 100 and
+	// beyond.
+	EUCAQI = "eu_caqi"
+	// INNAQI is the National Air Quality Index of India, from 0 to 500.
+	INNAQI = "in_naqi"
+
+	// Default is the standard of requests that do not choose one.
+	Default = USEPA
+)
+
+// The pollutants of the components, named after their JSON fields.
+const (
+	PM25 = "pm2_5"
+	PM10 = "pm10"
+	O3   = "o3"
+	NO   = "no"
+	NO2  = "no2"
+	SO2  = "so2"
+	CO   = "co"
+	NH3  = "nh3"
+)
+
+var ErrUnknownStandard = errors.New("unknown air quality standard")
+
+// ErrNoDatapoints is returned when there is no datapoint to compute the
+// index of.
+var ErrNoDatapoints = errors.New("no air pollution datapoints")
+
+// Index computes the air quality index of the components under standard. The
+// index is the highest sub-index of the pollutants the standard covers, and
+// its pollutant the dominant one.
+//
+// The components are hourly concentrations, which Index applies as they are
+// to breakpoints some standards define over longer averaging periods.
+func Index(standard string, components entities.AirPollutionComponents) (entities.AQI, error) {
+	std, ok := standards[standard]
+	if !ok {
+		return entities.AQI{}, fmt.Errorf("%w: %s", ErrUnknownStandard, standard)
+	}
+
+	concentrations := Concentrations(components)
+
+	index := entities.AQI{
+		Standard:   standard,
+		SubIndices: make(map[string]int, len(std.pollutants)),
+	}
+
+	for i, pollutant := range std.pollutants {
+		subIndex := pollutant.subIndex(concentrations[pollutant.name])
+		index.SubIndices[pollutant.name] = subIndex
+
+		if i == 0 || subIndex > index.Value {
+			index.Value = subIndex
+			index.DominantPollutant = pollutant.name
+		}
+	}
+
+	index.Level, index.Category = std.category(index.Value)
+
+	return index, nil
+}
+
+// Concentrations returns the concentration in µg/m³ of every pollutant of
+// the components.
+func Concentrations(components entities.AirPollutionComponents) map[string]float64 {
+	return map[string]float64{
+		PM25: components.PM25,
+		PM10: components.PM10,
+		O3:   components.O3,
+		NO:   components.NO,
+		NO2:  components.NO2,
+		SO2:  components.SO2,
+		CO:   components.CO,
+		NH3:  components.NH3,
+	}
+}
+
+// breakpoint maps the concentrations from lo to hi linearly onto the indices
+// from indexLo to indexHi.
+type breakpoint struct {
+	lo, hi           float64
+	indexLo, indexHi int
+}
+
+type pollutant struct {
+	name string
+	// convert converts µg/m³, the unit of the components, into the unit of
+	// the breakpoints.
+	convert func(float64) float64
+	// digits is how many decimals the concentration is truncated to before
+	// looking up its breakpoint, so that it cannot fall between two of them.
+	// It is negative for contiguous breakpoints.
+	digits      int
+	breakpoints []breakpoint
+}
+
+type category struct {
+	// upTo is the highest index of the category; that of the last category
+	// is ignored.
+	upTo  int
+	label string
+}
+
+type standard struct {
+	pollutants []pollutant
+	categories []category
+}
+
+// subIndex computes the sub-index of the concentration in µg/m³. Past the
+// last breakpoint, the sub-index keeps rising at the rate of the last one.
+func (p pollutant) subIndex(concentration float64) int {
+	c := p.convert(max(concentration, 0))
+	if p.digits >= 0 {
+		scale := math.Pow10(p.digits)
+		c = math.Floor(c*scale+1e-9) / scale
+	}
+
+	bp := p.breakpoints[len(p.breakpoints)-1]
+	for _, candidate := range p.breakpoints {
+		if c <= candidate.hi {
+			bp = candidate
+			break
+		}
+	}
+
+	index := float64(bp.indexHi-bp.indexLo)/(bp.hi-bp.lo)*(c-bp.lo) + float64(bp.indexLo)
+	return int(math.Round(max(index, 0)))
+}
+
+func (s standard) category(value int) (int, string) {
+	for i, category := range s.categories[:len(s.categories)-1] {
+		if value <= category.upTo {
+			return i + 1, category.label
+		}
+	}
+
+	return len(s.categories), s.categories[len(s.categories)-1].label
+}
@@ -0,0 +1,49 @@
+package aqi
+
+import "github.com/SamPariatIL/weather-wrapper/entities"
+
+// Current computes the index of the current air pollution, whose list holds
+// a single datapoint.
+func Current(airPollution *entities.AirPollution, standard string) (*entities.CurrentAirPollutionResponse, error) {
+	if len(airPollution.List) == 0 {
+		return nil, ErrNoDatapoints
+	}
+
+	datapoint := airPollution.List[0]
+
+	index, err := Index(standard, datapoint.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CurrentAirPollutionResponse{
+		Dt:                     datapoint.Dt,
+		AQI:                    index,
+		AirPollutionComponents: datapoint.Components,
+		Latitude:               airPollution.Lat,
+		Longitude:              airPollution.Lon,
+	}, nil
+}
+
+// Indexed computes the index of every datapoint of the air pollution.
+func Indexed(airPollution *entities.AirPollution, standard string) (*entities.IndexedAirPollution, error) {
+	indexed := &entities.IndexedAirPollution{
+		Coord: airPollution.Coord,
+		List:  make([]entities.IndexedAirPollutionDatapoint, len(airPollution.List)),
+	}
+
+	for i, datapoint := range airPollution.List {
+		index, err := Index(standard, datapoint.Components)
+		if err != nil {
+			return nil, err
+		}
+
+		indexed.List[i] = entities.IndexedAirPollutionDatapoint{
+			Dt:         datapoint.Dt,
+			AQI:        index,
+			Components: datapoint.Components,
+		}
+	}
+
+	return indexed, nil
+}
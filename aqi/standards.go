@@ -0,0 +1,206 @@
+package aqi
+
+// molarVolume is the volume in litres of a mole of gas at 25 °C and 1 atm,
+// which converts between µg/m³ and ppb.
+const molarVolume = 24.45
+
+// ppb converts µg/m³ of a gas of the molecular weight into ppb.
+func ppb(molecularWeight float64) func(float64) float64 {
+	return func(concentration float64) float64 {
+		return concentration * molarVolume / molecularWeight
+	}
+}
+
+// ppm converts µg/m³ of a gas of the molecular weight into ppm.
+func ppm(molecularWeight float64) func(float64) float64 {
+	return func(concentration float64) float64 {
+		return concentration * molarVolume / molecularWeight / 1000
+	}
+}
+
+func microgramsPerCubicMetre(concentration float64) float64 {
+	return concentration
+}
+
+func milligramsPerCubicMetre(concentration float64) float64 {
+	return concentration / 1000
+}
+
+var standards = map[string]standard{
+	// https://www.airnow.gov/publications/air-quality-index/technical-assistance-document-for-reporting-the-daily-aqi/
+	USEPA: {
+		pollutants: []pollutant{
+			{PM25, microgramsPerCubicMetre, 1, []breakpoint{
+				{0, 9, 0, 50},
+				{9.1, 35.4, 51, 100},
+				{35.5, 55.4, 101, 150},
+				{55.5, 125.4, 151, 200},
+				{125.5, 225.4, 201, 300},
+				{225.5, 325.4, 301, 500},
+			}},
+			{PM10, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 54, 0, 50},
+				{55, 154, 51, 100},
+				{155, 254, 101, 150},
+				{255, 354, 151, 200},
+				{355, 424, 201, 300},
+				{425, 604, 301, 500},
+			}},
+			// The 8-hour breakpoints end at 0.2 ppm, at an index of 300. Past
+			// that the concentration maps straight onto 301-500, up to the
+			// top of the 1-hour breakpoints, so the index keeps rising with
+			// it.
+			{O3, ppm(48), 3, []breakpoint{
+				{0, 0.054, 0, 50},
+				{0.055, 0.07, 51, 100},
+				{0.071, 0.085, 101, 150},
+				{0.086, 0.105, 151, 200},
+				{0.106, 0.2, 201, 300},
+				{0.201, 0.604, 301, 500},
+			}},
+			{NO2, ppb(46.01), 0, []breakpoint{
+				{0, 53, 0, 50},
+				{54, 100, 51, 100},
+				{101, 360, 101, 150},
+				{361, 649, 151, 200},
+				{650, 1249, 201, 300},
+				{1250, 2049, 301, 500},
+			}},
+			{SO2, ppb(64.07), 0, []breakpoint{
+				{0, 35, 0, 50},
+				{36, 75, 51, 100},
+				{76, 185, 101, 150},
+				{186, 304, 151, 200},
+				{305, 604, 201, 300},
+				{605, 1004, 301, 500},
+			}},
+			{CO, ppm(28.01), 1, []breakpoint{
+				{0, 4.4, 0, 50},
+				{4.5, 9.4, 51, 100},
+				{9.5, 12.4, 101, 150},
+				{12.5, 15.4, 151, 200},
+				{15.5, 30.4, 201, 300},
+				{30.5, 50.4, 301, 500},
+			}},
+		},
+		categories: []category{
+			{50, "Good"},
+			{100, "Moderate"},
+			{150, "Unhealthy for Sensitive Groups"},
+			{200, "Unhealthy"},
+			{300, "Very Unhealthy"},
+			{0, "Hazardous"},
+		},
+	},
+	// The hourly background grid of https://www.airqualitynow.eu/about_indices_definition.php
+	EUCAQI: {
+		pollutants: []pollutant{
+			{NO2, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 50, 0, 25},
+				{50, 100, 25, 50},
+				{100, 200, 50, 75},
+				{200, 400, 75, 100},
+			}},
+			{PM10, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 25, 0, 25},
+				{25, 50, 25, 50},
+				{50, 90, 50, 75},
+				{90, 180, 75, 100},
+			}},
+			{O3, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 60, 0, 25},
+				{60, 120, 25, 50},
+				{120, 180, 50, 75},
+				{180, 240, 75, 100},
+			}},
+			{PM25, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 15, 0, 25},
+				{15, 30, 25, 50},
+				{30, 55, 50, 75},
+				{55, 110, 75, 100},
+			}},
+			{CO, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 5000, 0, 25},
+				{5000, 7500, 25, 50},
+				{7500, 10000, 50, 75},
+				{10000, 20000, 75, 100},
+			}},
+			{SO2, microgramsPerCubicMetre, -1, []breakpoint{
+				{0, 50, 0, 25},
+				{50, 100, 25, 50},
+				{100, 350, 50, 75},
+				{350, 500, 75, 100},
+			}},
+		},
+		categories: []category{
+			{25, "Very Low"},
+			{50, "Low"},
+			{75, "Medium"},
+			{100, "High"},
+			{0, "Very High"},
+		},
+	},
+	// https://cpcb.nic.in/National-Air-Quality-Index/, whose severe band has
+	// no upper concentration and so extends the very poor one.
+	INNAQI: {
+		pollutants: []pollutant{
+			{PM10, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 50, 0, 50},
+				{51, 100, 51, 100},
+				{101, 250, 101, 200},
+				{251, 350, 201, 300},
+				{351, 430, 301, 400},
+			}},
+			{PM25, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 30, 0, 50},
+				{31, 60, 51, 100},
+				{61, 90, 101, 200},
+				{91, 120, 201, 300},
+				{121, 250, 301, 400},
+			}},
+			{NO2, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 40, 0, 50},
+				{41, 80, 51, 100},
+				{81, 180, 101, 200},
+				{181, 280, 201, 300},
+				{281, 400, 301, 400},
+			}},
+			{O3, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 50, 0, 50},
+				{51, 100, 51, 100},
+				{101, 168, 101, 200},
+				{169, 208, 201, 300},
+				{209, 748, 301, 400},
+			}},
+			{CO, milligramsPerCubicMetre, 1, []breakpoint{
+				{0, 1, 0, 50},
+				{1.1, 2, 51, 100},
+				{2.1, 10, 101, 200},
+				{10.1, 17, 201, 300},
+				{17.1, 34, 301, 400},
+			}},
+			{SO2, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 40, 0, 50},
+				{41, 80, 51, 100},
+				{81, 380, 101, 200},
+				{381, 800, 201, 300},
+				{801, 1600, 301, 400},
+			}},
+			{NH3, microgramsPerCubicMetre, 0, []breakpoint{
+				{0, 200, 0, 50},
+				{201, 400, 51, 100},
+				{401, 800, 101, 200},
+				{801, 1200, 201, 300},
+				{1201, 1800, 301, 400},
+			}},
+		},
+		categories: []category{
+			{50, "Good"},
+			{100, "Satisfactory"},
+			{200, "Moderate"},
+			{300, "Poor"},
+			{400, "Very Poor"},
+			{0, "Severe"},
+		},
+	},
+}
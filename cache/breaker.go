@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned without calling the backend while the circuit
+// breaker of a BreakerCache is open.
+var ErrUnavailable = errors.New("cache unavailable")
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerCache is a Cache that stops calling its backend after repeated
+// failures, so requests fall through to upstream right away instead of
+// waiting on the timeouts of a backend that is down.
+type BreakerCache interface {
+	Cache
+	// State returns the state of the circuit breaker.
+	State() string
+}
+
+// breakerCache opens after threshold consecutive failures and fails calls
+// fast for openDuration. After that a single probe call is let through:
+// success closes the breaker, failure opens it again. Misses and calls
+// cancelled by their caller are not failures.
+type breakerCache struct {
+	backend             Cache
+	mu                  sync.Mutex
+	threshold           int
+	openDuration        time.Duration
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	probing             bool
+}
+
+func NewBreakerCache(backend Cache, threshold int, openDuration time.Duration) BreakerCache {
+	return &breakerCache{
+		backend:      backend,
+		threshold:    threshold,
+		openDuration: openDuration,
+		state:        BreakerClosed,
+	}
+}
+
+func (bc *breakerCache) State() string {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.state
+}
+
+// call runs fn on the backend unless the breaker is open, and records its
+// outcome.
+func (bc *breakerCache) call(ctx context.Context, fn func() error) error {
+	if !bc.allow() {
+		return ErrUnavailable
+	}
+
+	err := fn()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.probing = false
+
+	if err == nil || errors.Is(err, ErrMiss) {
+		bc.state = BreakerClosed
+		bc.consecutiveFailures = 0
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return err
+	}
+
+	bc.consecutiveFailures++
+
+	if bc.state == BreakerHalfOpen || bc.consecutiveFailures >= bc.threshold {
+		bc.state = BreakerOpen
+		bc.openedAt = time.Now()
+	}
+
+	return err
+}
+
+// allow reports whether a call may go to the backend.
+func (bc *breakerCache) allow() bool {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	switch bc.state {
+	case BreakerOpen:
+		if time.Since(bc.openedAt) < bc.openDuration {
+			return false
+		}
+
+		bc.state = BreakerHalfOpen
+		bc.probing = true
+		return true
+	case BreakerHalfOpen:
+		if bc.probing {
+			return false
+		}
+
+		bc.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (bc *breakerCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := bc.call(ctx, func() (err error) {
+		value, err = bc.backend.Get(ctx, key)
+		return err
+	})
+
+	return value, err
+}
+
+func (bc *breakerCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	var values [][]byte
+	err := bc.call(ctx, func() (err error) {
+		values, err = bc.backend.MGet(ctx, keys...)
+		return err
+	})
+
+	return values, err
+}
+
+func (bc *breakerCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return bc.call(ctx, func() error {
+		return bc.backend.Set(ctx, key, value, ttl)
+	})
+}
+
+func (bc *breakerCache) MSet(ctx context.Context, items ...Item) error {
+	return bc.call(ctx, func() error {
+		return bc.backend.MSet(ctx, items...)
+	})
+}
+
+func (bc *breakerCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	var set bool
+	err := bc.call(ctx, func() (err error) {
+		set, err = bc.backend.SetNX(ctx, key, value, ttl)
+		return err
+	})
+
+	return set, err
+}
+
+func (bc *breakerCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := bc.call(ctx, func() (err error) {
+		ttl, err = bc.backend.TTL(ctx, key)
+		return err
+	})
+
+	return ttl, err
+}
+
+func (bc *breakerCache) Delete(ctx context.Context, keys ...string) error {
+	return bc.call(ctx, func() error {
+		return bc.backend.Delete(ctx, keys...)
+	})
+}
+
+func (bc *breakerCache) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	var deleted bool
+	err := bc.call(ctx, func() (err error) {
+		deleted, err = bc.backend.CompareAndDelete(ctx, key, value)
+		return err
+	})
+
+	return deleted, err
+}
+
+func (bc *breakerCache) Scan(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := bc.call(ctx, func() (err error) {
+		keys, err = bc.backend.Scan(ctx, pattern)
+		return err
+	})
+
+	return keys, err
+}
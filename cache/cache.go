@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned for keys that are not in the cache.
+var ErrMiss = errors.New("cache miss")
+
+// Item is a value to store under Key for TTL; a TTL of 0 never expires.
+type Item struct {
+	Key   string
+	Value []byte
+	TTL   time.Duration
+}
+
+// Cache is a key value store with expiring keys, backed by Redis, by an
+// in-process LRU or by both.
+type Cache interface {
+	// Get returns the value of key, or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// MGet returns the values of keys in their order, with nil for the keys
+	// that are not in the cache.
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	// Set stores value under key for ttl; a ttl of 0 never expires.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// MSet stores every item.
+	MSet(ctx context.Context, items ...Item) error
+	// SetNX stores value under key unless the key exists, and reports
+	// whether it did.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// TTL returns the remaining time to live of key, 0 when it never
+	// expires, or ErrMiss.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, keys ...string) error
+	// CompareAndDelete deletes key only while it holds value, and reports
+	// whether it did.
+	CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
+	// Scan returns the keys matching the glob pattern, in no particular
+	// order.
+	Scan(ctx context.Context, pattern string) ([]string, error)
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key   string
+	value []byte
+	// expiresAt is zero for entries that never expire.
+	expiresAt time.Time
+}
+
+func (le *lruEntry) expired(now time.Time) bool {
+	return !le.expiresAt.IsZero() && !now.Before(le.expiresAt)
+}
+
+// lruCache holds at most maxEntries keys in process, evicting the least
+// recently used. Expired entries are dropped when they are next read.
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	recency    *list.List
+}
+
+func NewLRUCache(maxEntries int) Cache {
+	return &lruCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+func (lc *lruCache) Get(_ context.Context, key string) ([]byte, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry := lc.get(key, time.Now())
+	if entry == nil {
+		return nil, ErrMiss
+	}
+
+	return bytes.Clone(entry.value), nil
+}
+
+func (lc *lruCache) MGet(_ context.Context, keys ...string) ([][]byte, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := time.Now()
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if entry := lc.get(key, now); entry != nil {
+			values[i] = bytes.Clone(entry.value)
+		}
+	}
+
+	return values, nil
+}
+
+func (lc *lruCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.set(key, value, ttl)
+	return nil
+}
+
+func (lc *lruCache) MSet(_ context.Context, items ...Item) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for _, item := range items {
+		lc.set(item.Key, item.Value, item.TTL)
+	}
+
+	return nil
+}
+
+func (lc *lruCache) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.get(key, time.Now()) != nil {
+		return false, nil
+	}
+
+	lc.set(key, value, ttl)
+	return true, nil
+}
+
+func (lc *lruCache) TTL(_ context.Context, key string) (time.Duration, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := time.Now()
+
+	entry := lc.get(key, now)
+	if entry == nil {
+		return 0, ErrMiss
+	}
+
+	if entry.expiresAt.IsZero() {
+		return 0, nil
+	}
+
+	return entry.expiresAt.Sub(now), nil
+}
+
+func (lc *lruCache) Delete(_ context.Context, keys ...string) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := lc.entries[key]; ok {
+			lc.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (lc *lruCache) CompareAndDelete(_ context.Context, key string, value []byte) (bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry := lc.get(key, time.Now())
+	if entry == nil || !bytes.Equal(entry.value, value) {
+		return false, nil
+	}
+
+	lc.remove(lc.entries[key])
+	return true, nil
+}
+
+func (lc *lruCache) Scan(_ context.Context, pattern string) ([]string, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := time.Now()
+
+	var keys []string
+	for key, element := range lc.entries {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched && !element.Value.(*lruEntry).expired(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// get returns the live entry of key and marks it as recently used.
+func (lc *lruCache) get(key string, now time.Time) *lruEntry {
+	element, ok := lc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if entry.expired(now) {
+		lc.remove(element)
+		return nil
+	}
+
+	lc.recency.MoveToFront(element)
+	return entry
+}
+
+func (lc *lruCache) set(key string, value []byte, ttl time.Duration) {
+	entry := &lruEntry{key: key, value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, ok := lc.entries[key]; ok {
+		element.Value = entry
+		lc.recency.MoveToFront(element)
+		return
+	}
+
+	lc.entries[key] = lc.recency.PushFront(entry)
+
+	for lc.recency.Len() > lc.maxEntries {
+		lc.remove(lc.recency.Back())
+	}
+}
+
+func (lc *lruCache) remove(element *list.Element) {
+	lc.recency.Remove(element)
+	delete(lc.entries, element.Value.(*lruEntry).key)
+}
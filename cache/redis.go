@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var compareAndDelete = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type redisCache struct {
+	redisClient *redis.Client
+}
+
+func NewRedisCache(rc *redis.Client) Cache {
+	return &redisCache{
+		redisClient: rc,
+	}
+}
+
+func (rc *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := rc.redisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+
+	return value, err
+}
+
+func (rc *redisCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	results, err := rc.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// MGET answers nil for missing keys and strings for the others.
+	values := make([][]byte, len(keys))
+	for i, result := range results {
+		if value, ok := result.(string); ok {
+			values[i] = []byte(value)
+		}
+	}
+
+	return values, nil
+}
+
+func (rc *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return rc.redisClient.Set(ctx, key, value, ttl).Err()
+}
+
+// MSet pipelines a SET per item, as MSET cannot give the keys a TTL.
+func (rc *redisCache) MSet(ctx context.Context, items ...Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	_, err := rc.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, item := range items {
+			pipe.Set(ctx, item.Key, item.Value, item.TTL)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (rc *redisCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return rc.redisClient.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (rc *redisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := rc.redisClient.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL answers -2 for missing keys and -1 for keys without a TTL.
+	switch ttl {
+	case -2:
+		return 0, ErrMiss
+	case -1:
+		return 0, nil
+	default:
+		return ttl, nil
+	}
+}
+
+func (rc *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return rc.redisClient.Del(ctx, keys...).Err()
+}
+
+func (rc *redisCache) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	deleted, err := compareAndDelete.Run(ctx, rc.redisClient, []string{key}, value).Int()
+	return deleted == 1, err
+}
+
+func (rc *redisCache) Scan(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+
+	// SCAN walks the keyspace in batches instead of blocking Redis like KEYS.
+	iter := rc.redisClient.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	return keys, iter.Err()
+}
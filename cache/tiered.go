@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// tieredCache puts a local cache in front of a shared one. Reads are served
+// locally for at most localTTL, so other replicas' writes show up after that
+// long. Writes go to both tiers, and reads keep being served locally while
+// the shared tier fails. Locks and TTLs are answered by the shared
+// tier only, as they must agree across replicas.
+type tieredCache struct {
+	local    Cache
+	shared   Cache
+	localTTL time.Duration
+}
+
+func NewTieredCache(local, shared Cache, localTTL time.Duration) Cache {
+	return &tieredCache{
+		local:    local,
+		shared:   shared,
+		localTTL: localTTL,
+	}
+}
+
+func (tc *tieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := tc.local.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+
+	value, err = tc.shared.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without the remaining TTL of the shared entry the local copy could
+	// outlive it, so it is not kept.
+	ttl, err := tc.shared.TTL(ctx, key)
+	if err == nil {
+		_ = tc.local.Set(ctx, key, value, tc.localTTLFor(ttl))
+	}
+
+	return value, nil
+}
+
+// MGet reads the keys missing locally from the shared tier in one call. Their
+// values are not copied locally, as that would take the TTL of every key.
+func (tc *tieredCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values, err := tc.local.MGet(ctx, keys...)
+	if err != nil {
+		values = make([][]byte, len(keys))
+	}
+
+	var missing []string
+	var missingAt []int
+	for i, value := range values {
+		if value == nil {
+			missing = append(missing, keys[i])
+			missingAt = append(missingAt, i)
+		}
+	}
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	shared, err := tc.shared.MGet(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range shared {
+		values[missingAt[i]] = value
+	}
+
+	return values, nil
+}
+
+func (tc *tieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// The local copy is written even when the shared tier fails, so this
+	// replica keeps serving it while Redis is down.
+	return errors.Join(tc.local.Set(ctx, key, value, tc.localTTLFor(ttl)), tc.shared.Set(ctx, key, value, ttl))
+}
+
+func (tc *tieredCache) MSet(ctx context.Context, items ...Item) error {
+	localItems := make([]Item, len(items))
+	for i, item := range items {
+		localItems[i] = Item{Key: item.Key, Value: item.Value, TTL: tc.localTTLFor(item.TTL)}
+	}
+
+	return errors.Join(tc.local.MSet(ctx, localItems...), tc.shared.MSet(ctx, items...))
+}
+
+func (tc *tieredCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	set, err := tc.shared.SetNX(ctx, key, value, ttl)
+	if set {
+		_ = tc.local.Delete(ctx, key)
+	}
+
+	return set, err
+}
+
+func (tc *tieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return tc.shared.TTL(ctx, key)
+}
+
+func (tc *tieredCache) Delete(ctx context.Context, keys ...string) error {
+	return errors.Join(tc.local.Delete(ctx, keys...), tc.shared.Delete(ctx, keys...))
+}
+
+func (tc *tieredCache) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	_ = tc.local.Delete(ctx, key)
+	return tc.shared.CompareAndDelete(ctx, key, value)
+}
+
+// localTTLFor keeps local copies from outliving the shared entry.
+func (tc *tieredCache) localTTLFor(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < tc.localTTL {
+		return ttl
+	}
+
+	return tc.localTTL
+}
+
+// Scan answers from the shared tier, which holds every key of the local one.
+func (tc *tieredCache) Scan(ctx context.Context, pattern string) ([]string, error) {
+	return tc.shared.Scan(ctx, pattern)
+}
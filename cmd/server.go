@@ -1,40 +1,109 @@
 package cmd
 
 import (
+	"expvar"
 	"github.com/SamPariatIL/weather-wrapper/config"
 	_ "github.com/SamPariatIL/weather-wrapper/docs"
+	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/mailer"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
 	"github.com/SamPariatIL/weather-wrapper/repository"
 	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
 	"github.com/SamPariatIL/weather-wrapper/vendors"
 	"github.com/gofiber/fiber/v2"
+	fiberAdaptor "github.com/gofiber/fiber/v2/middleware/adaptor"
 	fiberCors "github.com/gofiber/fiber/v2/middleware/cors"
 	fiberLogger "github.com/gofiber/fiber/v2/middleware/logger"
 	fiberRecover "github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
 	"go.uber.org/zap"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
-func setupRoutes(app *fiber.App, logger *zap.Logger) {
+// setupRoutes registers the routes on app and returns the function that
+// stops the background work they started.
+func setupRoutes(app *fiber.App, logger *zap.Logger) func() {
+	conf := config.GetConfig()
 	redisClient := vendors.GetRedisClient()
-	authClient := vendors.GetFirebaseAuth()
+	cacheStore := vendors.GetCache()
+	identityProvider := vendors.GetIdentityProvider()
+	postgresDB := vendors.GetPostgresDB()
 
-	userRepo := repository.NewUserRepository(authClient, logger)
-	userService := services.NewUserService(userRepo, logger)
+	userRepo := repository.NewUserRepository(identityProvider, logger)
+	smtpMailer := mailer.NewSMTPMailer(conf.MailerConfig, logger)
+	userService := services.NewUserService(userRepo, smtpMailer, logger)
 	userHandler := handlers.NewUserHandler(userService, logger)
+	userService.Start()
 
-	weatherRepo := repository.NewWeatherRepository(redisClient, logger)
-	weatherService := services.NewWeatherService(weatherRepo, logger)
+	apiKeyRepo := repository.NewAPIKeyRepository(postgresDB, logger)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, identityProvider, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
+
+	usageRepo := repository.NewUsageRepository(postgresDB, redisClient, logger)
+	usageService := services.NewUsageService(usageRepo, logger)
+	usageHandler := handlers.NewUsageHandler(usageService, logger)
+	usageService.Start()
+
+	upstreamClient := upstream.NewClient(conf, logger)
+	fallbackRepo := repository.NewFallbackRepository(cacheStore, conf.UpstreamConfig.FallbackRetention, logger)
+	lockRepo := repository.NewLockRepository(cacheStore, logger)
+	refreshPool := services.NewRefreshPool(conf.CacheConfig.RefreshWorkers, logger)
+
+	healthService := services.NewHealthService(upstreamClient, redisClient, vendors.GetCacheBreaker(), logger)
+	healthHandler := handlers.NewHealthHandler(healthService, logger)
+
+	weatherRepo := repository.NewWeatherRepository(cacheStore, conf.CacheConfig, logger)
+	weatherService := services.NewWeatherService(weatherRepo, fallbackRepo, lockRepo, upstreamClient, refreshPool, conf, logger)
 	weatherHandler := handlers.NewWeatherHandler(weatherService, logger)
 
-	geocodingRepo := repository.NewGeocodingRepository(redisClient, logger)
-	geocodingService := services.NewGeocodingService(geocodingRepo, logger)
+	geocodingRepo := repository.NewGeocodingRepository(cacheStore, conf.CacheConfig, logger)
+	geocodingService := services.NewGeocodingService(geocodingRepo, fallbackRepo, lockRepo, upstreamClient, refreshPool, conf, logger)
 	geocodingHandler := handlers.NewGeocodingHandler(geocodingService, logger)
 
-	airPollutionRepo := repository.NewAirPollutionRepository(redisClient, logger)
-	airPollutionService := services.NewAirPollutionService(airPollutionRepo, logger)
-	airPollutionHandler := handlers.NewAirPollutionHandler(airPollutionService, logger)
+	airPollutionRepo := repository.NewAirPollutionRepository(cacheStore, conf.CacheConfig, logger)
+	airPollutionService := services.NewAirPollutionService(airPollutionRepo, fallbackRepo, lockRepo, upstreamClient, refreshPool, conf, logger)
+	adviceService := services.NewAdviceService(airPollutionService, logger)
+	airPollutionSummaryService := services.NewAirPollutionSummaryService(airPollutionService, weatherService, logger)
+	airPollutionHandler := handlers.NewAirPollutionHandler(airPollutionService, adviceService, airPollutionSummaryService, logger)
+
+	cacheAdminRepo := repository.NewCacheAdminRepository(cacheStore, logger)
+	cacheAdminService := services.NewCacheAdminService(cacheAdminRepo, logger)
+	cacheAdminHandler := handlers.NewCacheAdminHandler(cacheAdminService, logger)
+
+	authMiddleware := middlewares.NewAuthMiddleware(identityProvider, apiKeyService, logger)
+	authorizationMiddleware := middlewares.NewAuthorizationMiddleware(logger)
+	rateLimitMiddleware := middlewares.NewRateLimitMiddleware(redisClient, logger)
+	usageMiddleware := middlewares.NewUsageMiddleware(usageService, logger)
+
+	// Every route guarded by rbacMiddleware.Authorize must be declared here.
+	// An empty role list means any authenticated caller.
+	rbacMiddleware := middlewares.NewRBACMiddleware(middlewares.RolePolicy{
+		"POST /api/v1/users/token":        {},
+		"PUT /api/v1/users/:uid":          {},
+		"DELETE /api/v1/users/:uid":       {},
+		"PUT /api/v1/users/:uid/roles":    {entities.RoleAdmin},
+		"DELETE /api/v1/users/:uid/roles": {entities.RoleAdmin},
+
+		"POST /api/v1/users/:uid/api-keys":            {},
+		"GET /api/v1/users/:uid/api-keys":             {},
+		"POST /api/v1/users/:uid/api-keys/:id/rotate": {},
+		"DELETE /api/v1/users/:uid/api-keys/:id":      {},
+
+		"GET /api/v1/usage": {},
+
+		"GET /api/v1/metrics": {entities.RoleAdmin},
+
+		"GET /api/v1/admin/cache/entries":    {entities.RoleAdmin},
+		"GET /api/v1/admin/cache/entry":      {entities.RoleAdmin},
+		"DELETE /api/v1/admin/cache/entries": {entities.RoleAdmin},
+		"DELETE /api/v1/admin/cache":         {entities.RoleAdmin},
+	}, logger)
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
@@ -43,30 +112,71 @@ func setupRoutes(app *fiber.App, logger *zap.Logger) {
 	health.Get("/", func(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusOK).SendString("Weather wrapper is running woohoo!!")
 	})
+	health.Get("/health", healthHandler.GetHealth)
+	health.Get("/metrics", authMiddleware.Authenticate, rbacMiddleware.Authorize, fiberAdaptor.HTTPHandler(expvar.Handler()))
 
 	apiDocs := v1.Group("/swagger")
 	apiDocs.Get("*", swagger.HandlerDefault)
 
 	weatherV1 := v1.Group("/weather")
+	if conf.AuthConfig.ProtectWeather {
+		weatherV1.Use(authMiddleware.Authenticate, authMiddleware.RequireScope(entities.ScopeWeatherRead))
+	}
+	weatherV1.Use(rateLimitMiddleware.Limit("weather"), usageMiddleware.Meter, middlewares.TrackFreshness)
 	weatherV1.Get("/now", weatherHandler.GetCurrentWeather)
 	weatherV1.Get("/forecast", weatherHandler.GetFiveDayForecast)
 
 	geocodingV1 := v1.Group("/geocode")
+	if conf.AuthConfig.ProtectGeocode {
+		geocodingV1.Use(authMiddleware.Authenticate, authMiddleware.RequireScope(entities.ScopeGeocodeRead))
+	}
+	geocodingV1.Use(rateLimitMiddleware.Limit("geocode"), usageMiddleware.Meter, middlewares.TrackFreshness)
 	geocodingV1.Get("/", geocodingHandler.GetGeocodeForCity)
 	geocodingV1.Get("/reverse", geocodingHandler.GetCityFromLatLon)
 
 	usersV1 := v1.Group("/users")
-	usersV1.Get("/token", userHandler.GenerateToken)
+	usersV1.Post("/token", authMiddleware.VerifyToken, rbacMiddleware.Authorize, userHandler.GenerateToken)
+	usersV1.Post("/token/exchange", rateLimitMiddleware.Limit("token-exchange"), userHandler.ExchangeToken)
+	usersV1.Post("/token/refresh", rateLimitMiddleware.Limit("token-refresh"), userHandler.RefreshToken)
 	usersV1.Post("/signup", userHandler.CreateUser)
 	usersV1.Post("/verify", userHandler.SendVerificationEmail)
 	usersV1.Post("/reset-password", userHandler.ResetPassword)
-	usersV1.Put("/:uid", userHandler.UpdateUser)
-	usersV1.Delete("/:uid", userHandler.DeleteUser)
+	usersV1.Get("/action", userHandler.ApplyAction)
+	usersV1.Post("/action", userHandler.ApplyAction)
+	usersV1.Put("/:uid", authMiddleware.VerifyToken, rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, userHandler.UpdateUser)
+	usersV1.Delete("/:uid", authMiddleware.VerifyToken, rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, userHandler.DeleteUser)
+	usersV1.Put("/:uid/roles", authMiddleware.VerifyToken, rbacMiddleware.Authorize, userHandler.SetUserRoles)
+	usersV1.Delete("/:uid/roles", authMiddleware.VerifyToken, rbacMiddleware.Authorize, userHandler.ClearUserRoles)
+
+	apiKeysV1 := usersV1.Group("/:uid/api-keys", authMiddleware.VerifyToken)
+	apiKeysV1.Post("/", rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, apiKeyHandler.CreateAPIKey)
+	apiKeysV1.Get("/", rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, apiKeyHandler.ListAPIKeys)
+	apiKeysV1.Post("/:id/rotate", rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, apiKeyHandler.RotateAPIKey)
+	apiKeysV1.Delete("/:id", rbacMiddleware.Authorize, authorizationMiddleware.RequireOwnerOrAdmin, apiKeyHandler.RevokeAPIKey)
 
 	airPollutionV1 := v1.Group("/air-pollution")
+	if conf.AuthConfig.ProtectAirPollution {
+		airPollutionV1.Use(authMiddleware.Authenticate, authMiddleware.RequireScope(entities.ScopeAirRead))
+	}
+	airPollutionV1.Use(rateLimitMiddleware.Limit("air-pollution"), usageMiddleware.Meter, middlewares.TrackFreshness)
 	airPollutionV1.Get("/now", airPollutionHandler.GetCurrentAirPollution)
 	airPollutionV1.Get("/forecast", airPollutionHandler.GetAirPollutionForecast)
 	airPollutionV1.Get("/history", airPollutionHandler.GetHistoricalAirPollution)
+	airPollutionV1.Get("/advice", airPollutionHandler.GetAdvice)
+
+	usageV1 := v1.Group("/usage")
+	usageV1.Get("/", authMiddleware.Authenticate, rbacMiddleware.Authorize, usageHandler.GetUsage)
+
+	cacheAdminV1 := v1.Group("/admin/cache", authMiddleware.Authenticate)
+	cacheAdminV1.Get("/entries", rbacMiddleware.Authorize, cacheAdminHandler.ListEntries)
+	cacheAdminV1.Get("/entry", rbacMiddleware.Authorize, cacheAdminHandler.GetEntry)
+	cacheAdminV1.Delete("/entries", rbacMiddleware.Authorize, cacheAdminHandler.PurgeEntries)
+	cacheAdminV1.Delete("/", rbacMiddleware.Authorize, cacheAdminHandler.Flush)
+
+	return func() {
+		refreshPool.Stop()
+		usageService.Stop()
+	}
 }
 
 func RunServer() {
@@ -88,12 +198,34 @@ func RunServer() {
 	app := fiber.New()
 	app.Use(fiberLogger.New())
 	app.Use(fiberRecover.New())
-	app.Use(fiberCors.New())
-
-	setupRoutes(app, logger)
+	app.Use(fiberCors.New(fiberCors.Config{
+		ExposeHeaders: strings.Join([]string{
+			middlewares.HeaderRateLimitLimit,
+			middlewares.HeaderRateLimitRemaining,
+			middlewares.HeaderRateLimitReset,
+			fiber.HeaderRetryAfter,
+		}, ", "),
+	}))
+
+	stopBackgroundWork := setupRoutes(app, logger)
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		err := app.Shutdown()
+		if err != nil {
+			log.Println("An error occurred shutting down Fiber", err)
+		}
+	}()
 
 	err = app.Listen(":8181")
 	if err != nil {
 		log.Fatal("An error occurred setting up Fiber", err)
 	}
+
+	// Listen returns once the server has shut down and served the requests
+	// in flight.
+	stopBackgroundWork()
 }
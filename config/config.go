@@ -1,9 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,10 +17,18 @@ var (
 
 type Config struct {
 	AirPollutionConfig AirPollutionConfig
+	AuthConfig         AuthConfig
+	CacheConfig        CacheConfig
 	FirebaseConfig     FirebaseConfig
 	GeocodeConfig      GeocodeConfig
+	IdentityConfig     IdentityConfig
+	MailerConfig       MailerConfig
+	PlanConfig         PlanConfig
 	RedisConfig        RedisConfig
 	PostgresConfig     PostgresConfig
+	RateLimitConfig    RateLimitConfig
+	UpstreamConfig     UpstreamConfig
+	UsageConfig        UsageConfig
 	WeatherConfig      WeatherConfig
 }
 
@@ -36,7 +46,14 @@ type RedisConfig struct {
 	Addr     string
 	Password string
 	DB       int
-	Timeout  time.Duration
+	// Timeout bounds the ping at startup and in the health check.
+	Timeout time.Duration
+	// DialTimeout, ReadTimeout and WriteTimeout bound every command, so a
+	// hung Redis slows requests down by at most that long before they fall
+	// through to upstream.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
 }
 
 type PostgresConfig struct {
@@ -66,6 +83,162 @@ type FirebaseConfig struct {
 type AirPollutionConfig struct {
 	APIKey  string
 	BaseURL string
+	// HistoryChunk bounds the range of a single upstream history request;
+	// longer ranges are split and fetched by up to HistoryWorkers requests
+	// at once.
+	HistoryChunk   time.Duration
+	HistoryWorkers int
+	// HistoryMaxSpan is the longest range a history request may ask for.
+	HistoryMaxSpan time.Duration
+}
+
+type PlanConfig struct {
+	// Default is the plan of callers that have none assigned.
+	Default string
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	Window  time.Duration
+	// Limits maps a route group to the requests each plan may make per window.
+	Limits map[string]map[string]int
+}
+
+type UsageConfig struct {
+	BufferSize    int
+	FlushInterval time.Duration
+	// Quotas maps a plan to the requests it may make per calendar month.
+	// Plans without a quota are unlimited.
+	Quotas map[string]int64
+}
+
+type IdentityConfig struct {
+	// Provider is either "firebase" or "local".
+	Provider string
+	// JWTAlgorithm is either "HS256" or "EdDSA" and only applies to the
+	// local provider, as do the other fields.
+	JWTAlgorithm    string
+	JWTSecret       string
+	JWTPrivateKey   string
+	JWTIssuer       string
+	TokenTTL        time.Duration
+	RefreshTokenTTL time.Duration
+	ActionURL       string
+	// FirebaseAPIKey is the web API key used to sign users in with a
+	// password when the firebase provider is selected.
+	FirebaseAPIKey string
+}
+
+type MailerConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// Timeout bounds connecting to the SMTP server and delivering one email.
+	Timeout time.Duration
+	// QueueSize is the number of emails waiting to be sent before new ones
+	// are dropped.
+	QueueSize int
+}
+
+type UpstreamConfig struct {
+	// Timeout bounds a single upstream request of endpoints without their
+	// own entry in Timeouts.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// Retries is the number of extra attempts of a GET that failed with a
+	// transport error or a 5xx response.
+	Retries        int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// BreakerThreshold is the number of consecutive failures after which
+	// the circuit breaker of an endpoint opens for BreakerOpenDuration.
+	BreakerThreshold    int
+	BreakerOpenDuration time.Duration
+	// FallbackRetention is how long the last known response of a request
+	// is kept to be served while its endpoint is unavailable.
+	FallbackRetention time.Duration
+}
+
+type CacheConfig struct {
+	// Backend is "redis", "memory" for a bounded in-process LRU, or "tiered"
+	// for the LRU in front of Redis.
+	Backend string
+	// LRUSize is the number of entries the in-process LRU holds.
+	LRUSize int
+	// LocalTTL bounds how long the tiered backend serves an entry from the
+	// LRU before reading Redis again.
+	LocalTTL time.Duration
+	// LockTTL bounds how long a replica holds the lock on a cache key while
+	// it fetches the value from upstream.
+	LockTTL time.Duration
+	// LockWait is how long a cache miss waits for the replica holding the
+	// lock before calling upstream itself.
+	LockWait         time.Duration
+	LockPollInterval time.Duration
+	// RefreshWorkers bounds how many stale entries are refreshed in the
+	// background at once.
+	RefreshWorkers int
+	// SnapRules maps upstream endpoints to the rule snapping their
+	// coordinates to shared cache cells. Endpoints without a rule cache
+	// every coordinate on its own.
+	SnapRules map[string]SnapRule
+	// ReadLegacyKeys serves entries cached under the keys used before
+	// snapping, copying them to the snapped key. It can be turned off once
+	// the hard TTLs of those entries have passed.
+	ReadLegacyKeys bool
+	// TTLPolicies maps upstream endpoints to how long their responses are
+	// cached. The defaults are overridden per endpoint by the JSON file at
+	// CACHE_TTL_FILE, and that by CACHE_TTLS.
+	TTLPolicies map[string]TTLPolicy
+	// BreakerThreshold is the number of consecutive Redis failures after
+	// which the cache stops calling Redis for BreakerOpenDuration.
+	BreakerThreshold    int
+	BreakerOpenDuration time.Duration
+}
+
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+	CacheBackendTiered = "tiered"
+
+	SnapGeohash = "geohash"
+	SnapGrid    = "grid"
+
+	TTLFixed            = "fixed"
+	TTLForecastBoundary = "boundary"
+	TTLImmutablePast    = "immutable_past"
+)
+
+// SnapRule is written "geohash:<precision>" or "grid:<step in degrees>".
+type SnapRule struct {
+	Scheme    string
+	Precision int
+	Step      float64
+}
+
+// TTLPolicy is written "fixed:<soft>:<hard>", "boundary:<interval>:<hard>"
+// or "immutable_past:<soft>:<hard>:<retention>", in seconds.
+//
+// Fixed responses are fresh for Soft and kept for Hard. Boundary responses
+// are fresh until the next multiple of Interval since the Unix epoch, when
+// upstream publishes its next forecast step, and kept for Hard after it.
+// Immutable past responses covering only hours that have ended never change
+// and are kept fresh for Retention; those reaching into the current hour are
+// fixed.
+type TTLPolicy struct {
+	Policy    string
+	Soft      time.Duration
+	Hard      time.Duration
+	Interval  time.Duration
+	Retention time.Duration
+}
+
+type AuthConfig struct {
+	ProtectWeather      bool
+	ProtectGeocode      bool
+	ProtectAirPollution bool
 }
 
 func getEnv(key, fallback string) string {
@@ -87,6 +260,69 @@ func parseEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+func parseEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+		log.Printf("Failed to parse %s, using fallback %t", key, fallback)
+	}
+
+	return fallback
+}
+
+// parseRateLimitRules parses rules written as "group:plan=limit", separated
+// by commas, e.g. "weather:free=60,weather:pro=600".
+func parseRateLimitRules(key, fallback string) map[string]map[string]int {
+	limits := make(map[string]map[string]int)
+
+	for _, rule := range strings.Split(getEnv(key, fallback), ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		groupPlan, limitString, found := strings.Cut(rule, "=")
+		group, plan, hasPlan := strings.Cut(groupPlan, ":")
+		limit, err := strconv.Atoi(limitString)
+		if !found || !hasPlan || group == "" || plan == "" || err != nil || limit <= 0 {
+			log.Printf("Failed to parse rate limit rule %q in %s, skipping it", rule, key)
+			continue
+		}
+
+		if limits[group] == nil {
+			limits[group] = make(map[string]int)
+		}
+		limits[group][plan] = limit
+	}
+
+	return limits
+}
+
+// parseQuotas parses quotas written as "plan=limit", separated by commas,
+// e.g. "free=10000,pro=1000000".
+func parseQuotas(key, fallback string) map[string]int64 {
+	quotas := make(map[string]int64)
+
+	for _, rule := range strings.Split(getEnv(key, fallback), ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		plan, quotaString, found := strings.Cut(rule, "=")
+		quota, err := strconv.ParseInt(quotaString, 10, 64)
+		if !found || plan == "" || err != nil || quota < 0 {
+			log.Printf("Failed to parse quota %q in %s, skipping it", rule, key)
+			continue
+		}
+
+		quotas[plan] = quota
+	}
+
+	return quotas
+}
+
 func loadConfig() (*Config, error) {
 	var config Config
 
@@ -115,10 +351,13 @@ func loadConfig() (*Config, error) {
 	}
 
 	config.RedisConfig = RedisConfig{
-		Addr:     getEnv(RedisAddress, ""),
-		Password: getEnv(RedisPassword, ""),
-		DB:       parseEnvInt(RedisDB, 0),
-		Timeout:  time.Second * time.Duration(parseEnvInt(RedisTimeout, 10)),
+		Addr:         getEnv(RedisAddress, ""),
+		Password:     getEnv(RedisPassword, ""),
+		DB:           parseEnvInt(RedisDB, 0),
+		Timeout:      time.Second * time.Duration(parseEnvInt(RedisTimeout, 10)),
+		DialTimeout:  time.Millisecond * time.Duration(parseEnvInt(RedisDialTimeout, 1000)),
+		ReadTimeout:  time.Millisecond * time.Duration(parseEnvInt(RedisReadTimeout, 500)),
+		WriteTimeout: time.Millisecond * time.Duration(parseEnvInt(RedisWriteTimeout, 500)),
 	}
 
 	config.PostgresConfig = PostgresConfig{
@@ -132,8 +371,81 @@ func loadConfig() (*Config, error) {
 	}
 
 	config.AirPollutionConfig = AirPollutionConfig{
-		APIKey:  getEnv(AirPollutionApiKey, ""),
-		BaseURL: getEnv(AirPollutionBaseUrl, ""),
+		APIKey:         getEnv(AirPollutionApiKey, ""),
+		BaseURL:        getEnv(AirPollutionBaseUrl, ""),
+		HistoryChunk:   time.Second * time.Duration(parseEnvInt(AirPollutionHistoryChunk, 604800)),
+		HistoryWorkers: parseEnvInt(AirPollutionHistoryWorkers, 4),
+		HistoryMaxSpan: time.Second * time.Duration(parseEnvInt(AirPollutionHistoryMaxSpan, 31536000)),
+	}
+
+	config.RateLimitConfig = RateLimitConfig{
+		Enabled: parseEnvBool(RateLimitEnabled, true),
+		Window:  time.Second * time.Duration(parseEnvInt(RateLimitWindow, 60)),
+		Limits:  parseRateLimitRules(RateLimitRules, defaultRateLimitRules),
+	}
+
+	config.PlanConfig = PlanConfig{
+		Default: getEnv(DefaultPlan, "free"),
+	}
+
+	config.UsageConfig = UsageConfig{
+		BufferSize:    parseEnvInt(UsageBufferSize, 10000),
+		FlushInterval: time.Second * time.Duration(parseEnvInt(UsageFlushInterval, 10)),
+		Quotas:        parseQuotas(UsageQuotas, defaultUsageQuotas),
+	}
+
+	config.IdentityConfig = IdentityConfig{
+		Provider:        getEnv(IdentityProvider, "firebase"),
+		JWTAlgorithm:    getEnv(IdentityJWTAlgorithm, "HS256"),
+		JWTSecret:       getEnv(IdentityJWTSecret, ""),
+		JWTPrivateKey:   getEnv(IdentityJWTPrivateKey, ""),
+		JWTIssuer:       getEnv(IdentityJWTIssuer, "weather-wrapper"),
+		TokenTTL:        time.Second * time.Duration(parseEnvInt(IdentityTokenTTL, 3600)),
+		RefreshTokenTTL: time.Second * time.Duration(parseEnvInt(IdentityRefreshTokenTTL, 2592000)),
+		ActionURL:       getEnv(IdentityActionURL, "http://localhost:8181/api/v1/users/action"),
+		FirebaseAPIKey:  getEnv(IdentityFirebaseAPIKey, ""),
+	}
+
+	config.MailerConfig = MailerConfig{
+		Host:      getEnv(SmtpHost, "localhost"),
+		Port:      parseEnvInt(SmtpPort, 1025),
+		Username:  getEnv(SmtpUsername, ""),
+		Password:  getEnv(SmtpPassword, ""),
+		From:      getEnv(SmtpFrom, "Weather Wrapper <no-reply@weather-wrapper.local>"),
+		Timeout:   time.Second * time.Duration(parseEnvInt(SmtpTimeout, 10)),
+		QueueSize: parseEnvInt(SmtpQueueSize, 100),
+	}
+
+	config.UpstreamConfig = UpstreamConfig{
+		Timeout:             time.Second * time.Duration(parseEnvInt(UpstreamTimeout, 5)),
+		Timeouts:            parseDurations(UpstreamTimeouts, defaultUpstreamTimeouts),
+		Retries:             parseEnvInt(UpstreamRetries, 2),
+		RetryBaseDelay:      time.Millisecond * time.Duration(parseEnvInt(UpstreamRetryBaseDelay, 100)),
+		RetryMaxDelay:       time.Millisecond * time.Duration(parseEnvInt(UpstreamRetryMaxDelay, 2000)),
+		BreakerThreshold:    parseEnvInt(UpstreamBreakerThreshold, 5),
+		BreakerOpenDuration: time.Second * time.Duration(parseEnvInt(UpstreamBreakerOpenDuration, 30)),
+		FallbackRetention:   time.Second * time.Duration(parseEnvInt(UpstreamFallbackRetention, 604800)),
+	}
+
+	config.CacheConfig = CacheConfig{
+		Backend:             getEnv(CacheBackend, CacheBackendRedis),
+		LRUSize:             parseEnvInt(CacheLRUSize, 10000),
+		LocalTTL:            time.Second * time.Duration(parseEnvInt(CacheLocalTTL, 30)),
+		LockTTL:             time.Second * time.Duration(parseEnvInt(CacheLockTTL, 10)),
+		LockWait:            time.Millisecond * time.Duration(parseEnvInt(CacheLockWait, 5000)),
+		LockPollInterval:    time.Millisecond * time.Duration(parseEnvInt(CacheLockPollInterval, 100)),
+		RefreshWorkers:      parseEnvInt(CacheRefreshWorkers, 16),
+		SnapRules:           parseSnapRules(CacheSnapping, defaultCacheSnapping),
+		ReadLegacyKeys:      parseEnvBool(CacheLegacyKeys, true),
+		TTLPolicies:         parseTTLPolicies(CacheTTLs, CacheTTLFile, defaultCacheTTLs),
+		BreakerThreshold:    parseEnvInt(CacheBreakerThreshold, 5),
+		BreakerOpenDuration: time.Second * time.Duration(parseEnvInt(CacheBreakerOpenDuration, 10)),
+	}
+
+	config.AuthConfig = AuthConfig{
+		ProtectWeather:      parseEnvBool(AuthProtectWeather, false),
+		ProtectGeocode:      parseEnvBool(AuthProtectGeocode, false),
+		ProtectAirPollution: parseEnvBool(AuthProtectAirPollution, false),
 	}
 
 	return &config, nil
@@ -150,3 +462,209 @@ func GetConfig() *Config {
 
 	return conf
 }
+
+// parseSnapRules parses rules written as "endpoint=scheme:parameter",
+// separated by commas.
+func parseSnapRules(key, fallback string) map[string]SnapRule {
+	rules := make(map[string]SnapRule)
+
+	for _, entry := range strings.Split(getEnv(key, fallback), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, ruleString, found := strings.Cut(entry, "=")
+		scheme, parameter, hasParameter := strings.Cut(ruleString, ":")
+
+		rule := SnapRule{Scheme: scheme}
+		var err error
+
+		switch scheme {
+		case SnapGeohash:
+			rule.Precision, err = strconv.Atoi(parameter)
+			if err == nil && (rule.Precision < 1 || rule.Precision > 12) {
+				err = strconv.ErrRange
+			}
+		case SnapGrid:
+			rule.Step, err = strconv.ParseFloat(parameter, 64)
+			if err == nil && (rule.Step <= 0 || rule.Step > 90) {
+				err = strconv.ErrRange
+			}
+		default:
+			err = strconv.ErrSyntax
+		}
+
+		if !found || !hasParameter || name == "" || err != nil {
+			log.Printf("Failed to parse snap rule %q in %s, skipping it", entry, key)
+			continue
+		}
+
+		rules[name] = rule
+	}
+
+	return rules
+}
+
+// parseDurations parses durations written as "name=seconds", separated by
+// commas, e.g. "air_pollution_history=15".
+func parseDurations(key, fallback string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+
+	for _, rule := range strings.Split(getEnv(key, fallback), ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		name, secondsString, found := strings.Cut(rule, "=")
+		seconds, err := strconv.Atoi(secondsString)
+		if !found || name == "" || err != nil || seconds <= 0 {
+			log.Printf("Failed to parse duration %q in %s, skipping it", rule, key)
+			continue
+		}
+
+		durations[name] = time.Second * time.Duration(seconds)
+	}
+
+	return durations
+}
+
+// parseTTLPolicies parses policies written as "endpoint=policy", separated by
+// commas, from the fallback, then overrides them with the JSON file named by
+// fileKey and the policies in key.
+func parseTTLPolicies(key, fileKey, fallback string) map[string]TTLPolicy {
+	policies := make(map[string]TTLPolicy)
+
+	for name, policy := range parseTTLPolicyList(fallback, "the defaults") {
+		policies[name] = policy
+	}
+
+	if path := getEnv(fileKey, ""); path != "" {
+		for name, policy := range readTTLPolicyFile(path) {
+			policies[name] = policy
+		}
+	}
+
+	for name, policy := range parseTTLPolicyList(getEnv(key, ""), key) {
+		policies[name] = policy
+	}
+
+	return policies
+}
+
+func parseTTLPolicyList(list, source string) map[string]TTLPolicy {
+	policies := make(map[string]TTLPolicy)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, policyString, found := strings.Cut(entry, "=")
+		fields := strings.Split(policyString, ":")
+
+		durations := make([]time.Duration, 0, len(fields)-1)
+		var err error
+		for _, field := range fields[1:] {
+			var seconds int
+			seconds, err = strconv.Atoi(field)
+			if err != nil {
+				break
+			}
+			durations = append(durations, time.Second*time.Duration(seconds))
+		}
+
+		policy := TTLPolicy{Policy: fields[0]}
+		switch {
+		case err != nil:
+		case fields[0] == TTLFixed && len(durations) == 2:
+			policy.Soft, policy.Hard = durations[0], durations[1]
+		case fields[0] == TTLForecastBoundary && len(durations) == 2:
+			policy.Interval, policy.Hard = durations[0], durations[1]
+		case fields[0] == TTLImmutablePast && len(durations) == 3:
+			policy.Soft, policy.Hard, policy.Retention = durations[0], durations[1], durations[2]
+		default:
+			err = strconv.ErrSyntax
+		}
+
+		if !found || name == "" || err != nil || !validTTLPolicy(policy) {
+			log.Printf("Failed to parse TTL policy %q in %s, skipping it", entry, source)
+			continue
+		}
+
+		policies[name] = policy
+	}
+
+	return policies
+}
+
+// readTTLPolicyFile reads policies from a JSON object mapping endpoints to
+// policies, with durations such as "10m", e.g.
+// {"current_weather": {"policy": "fixed", "soft": "10m", "hard": "1h"}}.
+func readTTLPolicyFile(path string) map[string]TTLPolicy {
+	policies := make(map[string]TTLPolicy)
+
+	policiesJSON, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read TTL policies from %s, skipping them: %v", path, err)
+		return policies
+	}
+
+	var entries map[string]struct {
+		Policy    string `json:"policy"`
+		Soft      string `json:"soft"`
+		Hard      string `json:"hard"`
+		Interval  string `json:"interval"`
+		Retention string `json:"retention"`
+	}
+
+	err = json.Unmarshal(policiesJSON, &entries)
+	if err != nil {
+		log.Printf("Failed to parse TTL policies in %s, skipping them: %v", path, err)
+		return policies
+	}
+
+	for name, entry := range entries {
+		policy := TTLPolicy{Policy: entry.Policy}
+
+		var errs [4]error
+		policy.Soft, errs[0] = parseOptionalDuration(entry.Soft)
+		policy.Hard, errs[1] = parseOptionalDuration(entry.Hard)
+		policy.Interval, errs[2] = parseOptionalDuration(entry.Interval)
+		policy.Retention, errs[3] = parseOptionalDuration(entry.Retention)
+
+		if errs != [4]error{} || !validTTLPolicy(policy) {
+			log.Printf("Failed to parse TTL policy of %s in %s, skipping it", name, path)
+			continue
+		}
+
+		policies[name] = policy
+	}
+
+	return policies
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// validTTLPolicy reports whether policy has every duration it needs, with a
+// soft TTL no longer than the hard one. A zero hard TTL would never expire.
+func validTTLPolicy(policy TTLPolicy) bool {
+	switch policy.Policy {
+	case TTLFixed:
+		return policy.Hard > 0 && policy.Soft >= 0 && policy.Soft <= policy.Hard
+	case TTLForecastBoundary:
+		return policy.Interval > 0 && policy.Hard > 0
+	case TTLImmutablePast:
+		return policy.Hard > 0 && policy.Soft >= 0 && policy.Soft <= policy.Hard && policy.Retention > 0
+	default:
+		return false
+	}
+}
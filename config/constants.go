@@ -19,10 +19,13 @@ const (
 	WeatherApiKey  = "WEATHER_API_KEY"
 	WeatherBaseUrl = "WEATHER_BASE_URL"
 
-	RedisAddress  = "REDIS_ADDRESS"
-	RedisPassword = "REDIS_PASSWORD"
-	RedisDB       = "REDIS_DB"
-	RedisTimeout  = "REDIS_TIMEOUT"
+	RedisAddress      = "REDIS_ADDRESS"
+	RedisPassword     = "REDIS_PASSWORD"
+	RedisDB           = "REDIS_DB"
+	RedisTimeout      = "REDIS_TIMEOUT"
+	RedisDialTimeout  = "REDIS_DIAL_TIMEOUT"
+	RedisReadTimeout  = "REDIS_READ_TIMEOUT"
+	RedisWriteTimeout = "REDIS_WRITE_TIMEOUT"
 
 	PostgresDatabase = "POSTGRES_DATABASE"
 	PostgresPassword = "POSTGRES_PASSWORD"
@@ -32,6 +35,87 @@ const (
 	PostgresUser     = "POSTGRES_USER"
 	PostgresTimezone = "POSTGRES_TIMEZONE"
 
-	AirPollutionApiKey  = "AIR_POLLUTION_API_KEY"
-	AirPollutionBaseUrl = "AIR_POLLUTION_BASE_URL"
+	AirPollutionApiKey         = "AIR_POLLUTION_API_KEY"
+	AirPollutionBaseUrl        = "AIR_POLLUTION_BASE_URL"
+	AirPollutionHistoryChunk   = "AIR_POLLUTION_HISTORY_CHUNK"
+	AirPollutionHistoryWorkers = "AIR_POLLUTION_HISTORY_WORKERS"
+	AirPollutionHistoryMaxSpan = "AIR_POLLUTION_HISTORY_MAX_SPAN"
+
+	AuthProtectWeather      = "AUTH_PROTECT_WEATHER"
+	AuthProtectGeocode      = "AUTH_PROTECT_GEOCODE"
+	AuthProtectAirPollution = "AUTH_PROTECT_AIR_POLLUTION"
+
+	RateLimitEnabled = "RATE_LIMIT_ENABLED"
+	RateLimitWindow  = "RATE_LIMIT_WINDOW"
+	RateLimitRules   = "RATE_LIMIT_RULES"
+
+	DefaultPlan = "DEFAULT_PLAN"
+
+	UsageBufferSize    = "USAGE_BUFFER_SIZE"
+	UsageFlushInterval = "USAGE_FLUSH_INTERVAL"
+	UsageQuotas        = "USAGE_QUOTAS"
+
+	IdentityProvider        = "IDENTITY_PROVIDER"
+	IdentityJWTAlgorithm    = "IDENTITY_JWT_ALGORITHM"
+	IdentityJWTSecret       = "IDENTITY_JWT_SECRET"
+	IdentityJWTPrivateKey   = "IDENTITY_JWT_PRIVATE_KEY"
+	IdentityJWTIssuer       = "IDENTITY_JWT_ISSUER"
+	IdentityTokenTTL        = "IDENTITY_TOKEN_TTL"
+	IdentityRefreshTokenTTL = "IDENTITY_REFRESH_TOKEN_TTL"
+	IdentityActionURL       = "IDENTITY_ACTION_URL"
+	IdentityFirebaseAPIKey  = "IDENTITY_FIREBASE_API_KEY"
+
+	UpstreamTimeout             = "UPSTREAM_TIMEOUT"
+	UpstreamTimeouts            = "UPSTREAM_TIMEOUTS"
+	UpstreamRetries             = "UPSTREAM_RETRIES"
+	UpstreamRetryBaseDelay      = "UPSTREAM_RETRY_BASE_DELAY"
+	UpstreamRetryMaxDelay       = "UPSTREAM_RETRY_MAX_DELAY"
+	UpstreamBreakerThreshold    = "UPSTREAM_BREAKER_THRESHOLD"
+	UpstreamBreakerOpenDuration = "UPSTREAM_BREAKER_OPEN_DURATION"
+	UpstreamFallbackRetention   = "UPSTREAM_FALLBACK_RETENTION"
+
+	CacheBackend             = "CACHE_BACKEND"
+	CacheLRUSize             = "CACHE_LRU_SIZE"
+	CacheLocalTTL            = "CACHE_LOCAL_TTL"
+	CacheLockTTL             = "CACHE_LOCK_TTL"
+	CacheLockWait            = "CACHE_LOCK_WAIT"
+	CacheLockPollInterval    = "CACHE_LOCK_POLL_INTERVAL"
+	CacheRefreshWorkers      = "CACHE_REFRESH_WORKERS"
+	CacheSnapping            = "CACHE_SNAPPING"
+	CacheLegacyKeys          = "CACHE_LEGACY_KEYS"
+	CacheTTLs                = "CACHE_TTLS"
+	CacheTTLFile             = "CACHE_TTL_FILE"
+	CacheBreakerThreshold    = "CACHE_BREAKER_THRESHOLD"
+	CacheBreakerOpenDuration = "CACHE_BREAKER_OPEN_DURATION"
+
+	SmtpHost      = "SMTP_HOST"
+	SmtpPort      = "SMTP_PORT"
+	SmtpUsername  = "SMTP_USERNAME"
+	SmtpPassword  = "SMTP_PASSWORD"
+	SmtpFrom      = "SMTP_FROM"
+	SmtpTimeout   = "SMTP_TIMEOUT"
+	SmtpQueueSize = "SMTP_QUEUE_SIZE"
 )
+
+const defaultRateLimitRules = "weather:free=60,weather:pro=600," +
+	"geocode:free=30,geocode:pro=300," +
+	"air-pollution:free=60,air-pollution:pro=600," +
+	"token-exchange:free=10,token-refresh:free=30"
+
+const defaultUsageQuotas = "free=10000,pro=1000000"
+
+// Geohash precision 6 is a cell of about 1.2 x 0.6 km, a grid step of 0.05
+// degrees about 5.5 km; air pollution data is much coarser than the weather.
+const defaultCacheSnapping = "current_weather=geohash:6,weather_forecast=geohash:5," +
+	"current_air_pollution=grid:0.05,air_pollution_forecast=grid:0.05,air_pollution_history=grid:0.05," +
+	"geocode_reverse=geohash:7"
+
+// Current weather is updated about every 10 minutes and the 5 day forecast
+// in 3 hour steps; the air pollution forecast is hourly and its history never
+// changes once an hour has ended.
+const defaultCacheTTLs = "current_weather=fixed:600:3600,weather_forecast=boundary:10800:10800," +
+	"current_air_pollution=fixed:300:3600,air_pollution_forecast=boundary:3600:3600," +
+	"air_pollution_history=immutable_past:300:3600:2592000," +
+	"geocode_direct=fixed:86400:604800,geocode_reverse=fixed:86400:604800"
+
+const defaultUpstreamTimeouts = "air_pollution_history=15"
@@ -1 +1,13 @@
 package config
+
+import "github.com/SamPariatIL/weather-wrapper/entities"
+
+// EntityDBMapping lists the entities persisted in Postgres. They are
+// auto-migrated when the connection is set up.
+func EntityDBMapping() []any {
+	return []any{
+		&entities.APIKey{},
+		&entities.UsageRollup{},
+		&entities.LocalUser{},
+	}
+}
@@ -15,9 +15,367 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/cache": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove every key this service wrote to the cache, locks and rate limits included. Usage quotas and the keys of other applications sharing the Redis DB are kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Flush cache",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CachePurge"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/admin/cache/entries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List cached entries with their age and remaining TTL, by resource and by bounding box or the cell containing a point",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List cache entries",
+                "parameters": [
+                    {
+                        "enum": [
+                            "current_weather",
+                            "weather_forecast",
+                            "current_air_pollution",
+                            "air_pollution_forecast",
+                            "air_pollution_history",
+                            "geocode_direct",
+                            "geocode_reverse",
+                            "last_known"
+                        ],
+                        "type": "string",
+                        "description": "Resource",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box south edge",
+                        "name": "minLat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box west edge",
+                        "name": "minLong",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box north edge",
+                        "name": "maxLat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box east edge",
+                        "name": "maxLong",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude of a point, instead of a bounding box",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of a point, instead of a bounding box",
+                        "name": "long",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CacheEntries"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove the cached entries of a resource, of a bounding box or of the cell containing a point",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Purge cache entries",
+                "parameters": [
+                    {
+                        "enum": [
+                            "current_weather",
+                            "weather_forecast",
+                            "current_air_pollution",
+                            "air_pollution_forecast",
+                            "air_pollution_history",
+                            "geocode_direct",
+                            "geocode_reverse",
+                            "last_known"
+                        ],
+                        "type": "string",
+                        "description": "Resource, required without a location",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box south edge",
+                        "name": "minLat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box west edge",
+                        "name": "minLong",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box north edge",
+                        "name": "maxLat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Bounding box east edge",
+                        "name": "maxLong",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude of a point, instead of a bounding box",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of a point, instead of a bounding box",
+                        "name": "long",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CachePurge"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/admin/cache/entry": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the age and remaining TTL of a cached entry by its key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get cache entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CacheEntryInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/air-pollution/advice": {
+            "get": {
+                "description": "Get the health advisory of the current air quality for a given city, the outlook of the forecast by risk level and the periods when outdoor activity is safest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "air-pollution"
+                ],
+                "summary": "Get air quality advice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Latitude",
+                        "name": "lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longitude",
+                        "name": "long",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "us_epa",
+                            "eu_caqi",
+                            "in_naqi"
+                        ],
+                        "type": "string",
+                        "description": "Air quality index standard",
+                        "name": "standard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of the messages, overriding the Accept-Language header",
+                        "name": "lang",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AirQualityAdvice"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
         "/air-pollution/forecast": {
             "get": {
-                "description": "Get air pollution forecast for a given city",
+                "description": "Get air pollution forecast for a given city, summarized by day in the local time of the location: the min, mean and max of every pollutant, the hour of the peak air quality index and the hours above the WHO guideline values. In raw mode, the hourly datapoints instead, which with a standard carry their air quality index under it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -42,24 +400,59 @@ const docTemplate = `{
                         "name": "long",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "us_epa",
+                            "eu_caqi",
+                            "in_naqi"
+                        ],
+                        "type": "string",
+                        "description": "Air quality index standard",
+                        "name": "standard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the hourly datapoints instead of daily summaries",
+                        "name": "raw",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AirPollutionForecastResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
         },
         "/air-pollution/history": {
             "get": {
-                "description": "Get historical air pollution for a given city",
+                "description": "Get historical air pollution for a given city, summarized by day in the local time of the location like the forecast. Ranges longer than the configured maximum span are rejected, and long ranges are fetched in parallel chunks. In raw mode, the hourly datapoints are streamed back instead, and with a standard carry their air quality index under it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -87,35 +480,70 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Start Date (Epoch)",
+                        "description": "Start Date (Epoch), not before 1606435200",
                         "name": "start",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "End Date (Epoch)",
+                        "description": "End Date (Epoch), not in the future",
                         "name": "end",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "us_epa",
+                            "eu_caqi",
+                            "in_naqi"
+                        ],
+                        "type": "string",
+                        "description": "Air quality index standard",
+                        "name": "standard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream the hourly datapoints instead of daily summaries",
+                        "name": "raw",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.HistoricalAirPollutionResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
         },
         "/air-pollution/now": {
             "get": {
-                "description": "Get current air pollution for a given city",
+                "description": "Get current air pollution for a given city, along with its air quality index under the requested standard (US EPA by default) and the health advisory of its risk level",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,6 +568,23 @@ const docTemplate = `{
                         "name": "long",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "us_epa",
+                            "eu_caqi",
+                            "in_naqi"
+                        ],
+                        "type": "string",
+                        "description": "Air quality index standard",
+                        "name": "standard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of the advisory, overriding the Accept-Language header",
+                        "name": "lang",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -149,8 +594,23 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
@@ -184,8 +644,23 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
@@ -226,33 +701,125 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
         },
-        "/users/reset-password": {
-            "post": {
-                "description": "Send a verification email to reset password",
-                "consumes": [
-                    "application/json"
-                ],
+        "/health": {
+            "get": {
+                "description": "Get the health of the service, whether Redis is reachable and the state of the circuit breaker of every upstream endpoint. The service keeps serving while degraded",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "health"
                 ],
-                "summary": "Reset password",
-                "parameters": [
-                    {
-                        "description": "Email body",
-                        "name": "email",
+                "summary": "Get health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Health"
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get daily totals for a month and monthly totals for the year up to it. Admins can pass uid to view any user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Get usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Month (YYYY-MM), defaults to the current month",
+                        "name": "month",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, admin only",
+                        "name": "uid",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/users/action": {
+            "post": {
+                "description": "Verify the email or reset the password with the code of a link sent by email. Following a link verifies the email; resetting the password needs a POST with the new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Apply an emailed action",
+                "parameters": [
+                    {
+                        "description": "Action",
+                        "name": "action",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entities.EmailBody"
+                            "$ref": "#/definitions/entities.ActionBody"
                         }
                     }
                 ],
@@ -263,12 +830,55 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
         },
+        "/users/reset-password": {
+            "post": {
+                "description": "Send an email with a link to reset the password. The response is the same whether or not the address belongs to an account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Reset password",
+                "parameters": [
+                    {
+                        "description": "Email body",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.EmailBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
         "/users/signup": {
             "post": {
                 "description": "Create a new user",
@@ -300,6 +910,9 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -308,7 +921,12 @@ const docTemplate = `{
         },
         "/users/token": {
             "post": {
-                "description": "Generate a token",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mint a custom token for a user, to be exchanged for an ID token by a client SDK. Users can only mint tokens for themselves unless they are admins.",
                 "consumes": [
                     "application/json"
                 ],
@@ -332,11 +950,118 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CustomToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/users/token/exchange": {
+            "post": {
+                "description": "Sign in with an email and password and get an ID token plus a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Exchange credentials for tokens",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.CredentialsBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Session"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/users/token/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new ID token and refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "refreshToken",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.RefreshTokenBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Session"
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -345,7 +1070,7 @@ const docTemplate = `{
         },
         "/users/verify": {
             "post": {
-                "description": "Send a verification email",
+                "description": "Send a verification email. The response is the same whether or not the address belongs to an account.",
                 "consumes": [
                     "application/json"
                 ],
@@ -368,20 +1093,25 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "202": {
+                        "description": "Accepted"
                     },
                     "400": {
                         "description": "Bad Request"
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "422": {
+                        "description": "Unprocessable Entity"
                     }
                 }
             }
         },
         "/users/{uid}": {
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Update a user",
                 "consumes": [
                     "application/json"
@@ -418,12 +1148,26 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             },
             "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Delete a user",
                 "consumes": [
                     "application/json"
@@ -448,15 +1192,26 @@ const docTemplate = `{
                     "200": {
                         "description": "OK"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/weather/forecast": {
+        "/users/{uid}/api-keys": {
             "get": {
-                "description": "Get 5-day forecast for a given latitude and longitude",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the API keys of a user",
                 "consumes": [
                     "application/json"
                 ],
@@ -464,22 +1219,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "weather"
+                    "api-keys"
                 ],
-                "summary": "Get 5-day forecast",
+                "summary": "List API keys",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Latitude",
-                        "name": "lat",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Longitude",
-                        "name": "long",
-                        "in": "query",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -487,18 +1235,24 @@ const docTemplate = `{
                     "200": {
                         "description": "OK"
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
-            }
-        },
-        "/weather/now": {
-            "get": {
-                "description": "Get current weather for a given latitude and longitude",
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create an API key for a user. The key is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -506,49 +1260,892 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "weather"
+                    "api-keys"
                 ],
-                "summary": "Get current weather",
+                "summary": "Create API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Latitude",
-                        "name": "lat",
-                        "in": "query",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Longitude",
-                        "name": "long",
-                        "in": "query",
-                        "required": true
+                        "description": "API key details",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.CreateAPIKeyBody"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created"
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "entities.EmailBody": {
-            "type": "object",
-            "required": [
-                "email"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
+        },
+        "/users/{uid}/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke an API key so it can no longer be used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/users/{uid}/api-keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace an API key's secret, keeping its name, scopes and expiry. Revoked and expired keys cannot be rotated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Rotate API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/users/{uid}/roles": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the roles custom claim of a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set user roles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Roles body",
+                        "name": "roles",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.RolesBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the roles custom claim from a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Clear user roles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/weather/forecast": {
+            "get": {
+                "description": "Get 5-day forecast for a given latitude and longitude",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "weather"
+                ],
+                "summary": "Get 5-day forecast",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Latitude",
+                        "name": "lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longitude",
+                        "name": "long",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
+        "/weather/now": {
+            "get": {
+                "description": "Get current weather for a given latitude and longitude",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "weather"
+                ],
+                "summary": "Get current weather",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Latitude",
+                        "name": "lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longitude",
+                        "name": "long",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "entities.AQI": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "dominantPollutant": {
+                    "type": "string"
+                },
+                "level": {
+                    "description": "Level is the rank of the category, from 1 for the cleanest.",
+                    "type": "integer"
+                },
+                "standard": {
+                    "type": "string"
+                },
+                "subIndices": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.ActionBody": {
+            "type": "object",
+            "required": [
+                "mode",
+                "oobCode"
+            ],
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "verifyEmail",
+                        "resetPassword"
+                    ],
+                    "example": "resetPassword"
+                },
+                "newPassword": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "newpassword"
+                },
+                "oobCode": {
+                    "type": "string",
+                    "example": "eyJhbGciOi..."
+                }
+            }
+        },
+        "entities.AdviceAction": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Advisory": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AdviceAction"
+                    }
+                },
+                "affectedGroups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.GroupAdvisory"
+                    }
+                },
+                "risk": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.AirPollutionForecastResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.DailyAirPollution"
+                    }
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "standard": {
+                    "type": "string"
+                },
+                "timezoneEstimated": {
+                    "description": "TimezoneEstimated is set when the offset of the location could not be\nfetched, and TimezoneOffset is that of the nautical time zone of the\nlongitude instead, or when the location may have been at another\noffset at some of the datapoints, such as across a daylight saving\ntime change.",
+                    "type": "boolean"
+                },
+                "timezoneOffset": {
+                    "description": "TimezoneOffset is the current offset from UTC of the local time of the\nlocation, in seconds. Every day is bucketed with it, so the days past\na daylight saving time change are shifted by that change.",
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.AirQualityAdvice": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/entities.CurrentAirQualityAdvice"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "outlook": {
+                    "description": "Outlook splits the forecast into periods of the same risk level.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AirQualityPeriod"
+                    }
+                },
+                "safestWindows": {
+                    "description": "SafestWindows are the forecast periods of the lowest risk level, the\ncleanest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AirQualityPeriod"
+                    }
+                }
+            }
+        },
+        "entities.AirQualityPeriod": {
+            "type": "object",
+            "properties": {
+                "advisory": {
+                    "$ref": "#/definitions/entities.Advisory"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "meanAqi": {
+                    "type": "integer"
+                },
+                "peakAqi": {
+                    "type": "integer"
+                },
+                "risk": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.CacheEntries": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.CacheEntryInfo"
+                    }
+                },
+                "truncated": {
+                    "description": "Truncated is set when more entries matched than the limit.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "entities.CacheEntryInfo": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "description": "Age is the number of seconds since the value was fetched upstream.",
+                    "type": "integer",
+                    "example": 120
+                },
+                "cell": {
+                    "description": "Cell is the snapped cell of the entry, Lat and Lon its center or the\ncoordinates of a last known response.",
+                    "type": "string",
+                    "example": "geohash:tdr1v9"
+                },
+                "fetchedAt": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "weather-wrapper:v1:current_weather_geohash:tdr1v9"
+                },
+                "lat": {
+                    "type": "number",
+                    "example": 12.9716
+                },
+                "lon": {
+                    "type": "number",
+                    "example": 77.5946
+                },
+                "resource": {
+                    "type": "string",
+                    "example": "current_weather"
+                },
+                "stale": {
+                    "type": "boolean"
+                },
+                "staleAt": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "TTL is the number of seconds until the entry expires, -1 when it\nnever does.",
+                    "type": "integer",
+                    "example": 3480
+                }
+            }
+        },
+        "entities.CacheHealth": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "type": "string"
+                },
+                "breaker": {
+                    "description": "Breaker is the state of the circuit breaker in front of Redis, empty\nfor the memory backend.",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "redis": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.CachePurge": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "entities.CircuitBreaker": {
+            "type": "object",
+            "properties": {
+                "consecutiveFailures": {
+                    "type": "integer"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "openedAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.CreateAPIKeyBody": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2030-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "nightly-sync"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "weather:read",
+                        "air:read"
+                    ]
+                }
+            }
+        },
+        "entities.CredentialsBody": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
                     "example": "test@test.com"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "testpassword"
+                }
+            }
+        },
+        "entities.CurrentAirQualityAdvice": {
+            "type": "object",
+            "properties": {
+                "advisory": {
+                    "$ref": "#/definitions/entities.Advisory"
+                },
+                "aqi": {
+                    "$ref": "#/definitions/entities.AQI"
+                },
+                "dt": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.CustomToken": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.DailyAirPollution": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "exceedanceHours": {
+                    "description": "ExceedanceHours counts, by pollutant, the hours above its WHO\nguideline value.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "hours": {
+                    "type": "integer"
+                },
+                "peakAqi": {
+                    "$ref": "#/definitions/entities.PeakAQI"
+                },
+                "pollutants": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/entities.PollutantSummary"
+                    }
+                }
+            }
+        },
+        "entities.EmailBody": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "test@test.com"
+                }
+            }
+        },
+        "entities.GroupAdvisory": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AdviceAction"
+                    }
+                },
+                "group": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Health": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.CircuitBreaker"
+                    }
+                },
+                "cache": {
+                    "$ref": "#/definitions/entities.CacheHealth"
+                },
+                "status": {
+                    "description": "Status is degraded while Redis is down or any circuit breaker, of the\ncache or of an upstream endpoint, is not closed.",
+                    "type": "string"
+                }
+            }
+        },
+        "entities.HistoricalAirPollutionResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.DailyAirPollution"
+                    }
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "standard": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "timezoneEstimated": {
+                    "type": "boolean"
+                },
+                "timezoneOffset": {
+                    "description": "TimezoneOffset and TimezoneEstimated are those of\nAirPollutionForecastResponse.",
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.PeakAQI": {
+            "type": "object",
+            "properties": {
+                "aqi": {
+                    "$ref": "#/definitions/entities.AQI"
+                },
+                "dt": {
+                    "type": "integer"
+                },
+                "hour": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.PollutantSummary": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                }
+            }
+        },
+        "entities.RefreshTokenBody": {
+            "type": "object",
+            "required": [
+                "refreshToken"
+            ],
+            "properties": {
+                "refreshToken": {
+                    "type": "string",
+                    "example": "AMf-vBx..."
+                }
+            }
+        },
+        "entities.RolesBody": {
+            "type": "object",
+            "required": [
+                "roles"
+            ],
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "reader",
+                        "partner"
+                    ]
+                }
+            }
+        },
+        "entities.Session": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "idToken": {
+                    "type": "string"
+                },
+                "refreshToken": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
                 }
             }
         },
@@ -567,9 +2164,7 @@ const docTemplate = `{
         "entities.UserDetails": {
             "type": "object",
             "required": [
-                "disabled",
                 "email",
-                "emailVerified",
                 "name",
                 "password",
                 "phoneNumber"
@@ -612,6 +2207,19 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Firebase ID token, prefixed with \"Bearer \"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
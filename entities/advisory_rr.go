@@ -0,0 +1,57 @@
+package entities
+
+// AdviceQuery is the query of the air quality advice endpoint. Lang picks
+// the language of the messages, overriding the Accept-Language header.
+type AdviceQuery struct {
+	Lat      *float64 `query:"lat" validate:"required,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required,gte=-180,lte=180"`
+	Standard string   `query:"standard" validate:"omitempty,oneof=us_epa eu_caqi in_naqi"`
+	Lang     string   `query:"lang"`
+}
+
+// Advisory is the health guidance for a risk level of the air quality: what
+// everyone should do, and what the groups sensitive at that level should.
+type Advisory struct {
+	Risk           string          `json:"risk"`
+	Summary        string          `json:"summary"`
+	Actions        []AdviceAction  `json:"actions"`
+	AffectedGroups []GroupAdvisory `json:"affectedGroups"`
+}
+
+type AdviceAction struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type GroupAdvisory struct {
+	Group   string         `json:"group"`
+	Name    string         `json:"name"`
+	Actions []AdviceAction `json:"actions"`
+}
+
+type AirQualityAdvice struct {
+	Locale  string                  `json:"locale"`
+	Current CurrentAirQualityAdvice `json:"current"`
+	// Outlook splits the forecast into periods of the same risk level.
+	Outlook []AirQualityPeriod `json:"outlook"`
+	// SafestWindows are the forecast periods of the lowest risk level, the
+	// cleanest first.
+	SafestWindows []AirQualityPeriod `json:"safestWindows"`
+}
+
+type CurrentAirQualityAdvice struct {
+	Dt       int      `json:"dt"`
+	AQI      AQI      `json:"aqi"`
+	Advisory Advisory `json:"advisory"`
+}
+
+// AirQualityPeriod is a run of forecast hours from Start until End, both
+// unix seconds, with End exclusive.
+type AirQualityPeriod struct {
+	Start    int       `json:"start"`
+	End      int       `json:"end"`
+	Risk     string    `json:"risk"`
+	PeakAQI  int       `json:"peakAqi"`
+	MeanAQI  int       `json:"meanAqi"`
+	Advisory *Advisory `json:"advisory,omitempty"`
+}
@@ -1,5 +1,36 @@
 package entities
 
+// AirPollutionQuery is the query of the current air pollution endpoint.
+// Standard is the air quality index to compute, and Lang the language of the
+// advisory.
+type AirPollutionQuery struct {
+	Lat      *float64 `query:"lat" validate:"required,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required,gte=-180,lte=180"`
+	Standard string   `query:"standard" validate:"omitempty,oneof=us_epa eu_caqi in_naqi"`
+	Lang     string   `query:"lang"`
+}
+
+// AirPollutionForecastQuery is the query of the air pollution forecast
+// endpoint. Raw asks for the hourly datapoints instead of daily summaries.
+type AirPollutionForecastQuery struct {
+	Lat      *float64 `query:"lat" validate:"required,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required,gte=-180,lte=180"`
+	Standard string   `query:"standard" validate:"omitempty,oneof=us_epa eu_caqi in_naqi"`
+	Raw      bool     `query:"raw"`
+}
+
+// HistoricalAirPollutionQuery is the query of the air pollution history
+// endpoint. Upstream keeps history from 2020-11-27 00:00 UTC, 1606435200, up
+// to now.
+type HistoricalAirPollutionQuery struct {
+	Lat      *float64 `query:"lat" validate:"required,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required,gte=-180,lte=180"`
+	Start    *int64   `query:"start" validate:"required,gte=1606435200"`
+	End      *int64   `query:"end" validate:"required,gtefield=Start,notfuture"`
+	Standard string   `query:"standard" validate:"omitempty,oneof=us_epa eu_caqi in_naqi"`
+	Raw      bool     `query:"raw"`
+}
+
 type AirPollutionComponents struct {
 	CO   float64 `json:"co"`
 	NO   float64 `json:"no"`
@@ -13,24 +44,111 @@ type AirPollutionComponents struct {
 
 type AirPollution struct {
 	Coord
-	List []struct {
-		Dt   int `json:"dt"`
-		Main struct {
-			AQI int `json:"aqi"`
-		}
-		Components AirPollutionComponents `json:"components"`
-	} `json:"list"`
+	List []AirPollutionDatapoint `json:"list"`
+}
+
+// AirPollutionDatapoint is the air pollution of one hour, starting at Dt.
+type AirPollutionDatapoint struct {
+	Dt   int `json:"dt"`
+	Main struct {
+		AQI int `json:"aqi"`
+	}
+	Components AirPollutionComponents `json:"components"`
+}
+
+// AQI is an air quality index computed from the pollutant components under
+// one of the national standards.
+type AQI struct {
+	Standard string `json:"standard"`
+	Value    int    `json:"value"`
+	Category string `json:"category"`
+	// Level is the rank of the category, from 1 for the cleanest.
+	Level             int            `json:"level"`
+	DominantPollutant string         `json:"dominantPollutant"`
+	SubIndices        map[string]int `json:"subIndices"`
 }
 
 type CurrentAirPollutionResponse struct {
-	AQI int `json:"aqi"`
+	Dt  int `json:"dt"`
+	AQI AQI `json:"aqi"`
 	AirPollutionComponents
-	Latitude  float32 `json:"latitude"`
-	Longitude float32 `json:"longitude"`
+	Latitude  float32   `json:"latitude"`
+	Longitude float32   `json:"longitude"`
+	Advisory  *Advisory `json:"advisory,omitempty"`
 }
 
+// IndexedAirPollution is air pollution whose datapoints carry their air
+// quality index.
+type IndexedAirPollution struct {
+	Coord
+	List []IndexedAirPollutionDatapoint `json:"list"`
+}
+
+// IndexedAirPollutionDatapoint is a datapoint along with its air quality
+// index under the requested standard.
+type IndexedAirPollutionDatapoint struct {
+	Dt         int                    `json:"dt"`
+	AQI        AQI                    `json:"aqi"`
+	Components AirPollutionComponents `json:"components"`
+}
+
+// AirPollutionForecastResponse summarizes the forecast by local day.
 type AirPollutionForecastResponse struct {
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+	// TimezoneOffset is the current offset from UTC of the local time of the
+	// location, in seconds. Every day is bucketed with it, so the days past
+	// a daylight saving time change are shifted by that change.
+	TimezoneOffset int `json:"timezoneOffset"`
+	// TimezoneEstimated is set when the offset of the location could not be
+	// fetched, and TimezoneOffset is that of the nautical time zone of the
+	// longitude instead, or when the location may have been at another
+	// offset at some of the datapoints, such as across a daylight saving
+	// time change.
+	TimezoneEstimated bool                `json:"timezoneEstimated"`
+	Standard          string              `json:"standard"`
+	Days              []DailyAirPollution `json:"days"`
 }
 
+// HistoricalAirPollutionResponse summarizes the history from Start to End by
+// local day.
 type HistoricalAirPollutionResponse struct {
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+	Start     int64   `json:"start"`
+	End       int64   `json:"end"`
+	// TimezoneOffset and TimezoneEstimated are those of
+	// AirPollutionForecastResponse.
+	TimezoneOffset    int                 `json:"timezoneOffset"`
+	TimezoneEstimated bool                `json:"timezoneEstimated"`
+	Standard          string              `json:"standard"`
+	Days              []DailyAirPollution `json:"days"`
+}
+
+// DailyAirPollution summarizes the hourly datapoints of a local day. Hours
+// is how many of them the day has, fewer than 24 at the ends of a range.
+type DailyAirPollution struct {
+	Date       string                      `json:"date"`
+	Hours      int                         `json:"hours"`
+	Pollutants map[string]PollutantSummary `json:"pollutants"`
+	PeakAQI    PeakAQI                     `json:"peakAqi"`
+	// ExceedanceHours counts, by pollutant, the hours above its WHO
+	// guideline value.
+	ExceedanceHours map[string]int `json:"exceedanceHours"`
+}
+
+// PollutantSummary is the range and mean of the concentrations of a
+// pollutant, in µg/m³.
+type PollutantSummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	Max  float64 `json:"max"`
+}
+
+// PeakAQI is the hour of the day with the highest air quality index. Hour is
+// its local time.
+type PeakAQI struct {
+	Dt   int    `json:"dt"`
+	Hour string `json:"hour"`
+	AQI  AQI    `json:"aqi"`
 }
@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeWeatherRead = "weather:read"
+	ScopeGeocodeRead = "geocode:read"
+	ScopeAirRead     = "air:read"
+)
+
+var APIKeyScopes = []string{ScopeWeatherRead, ScopeGeocodeRead, ScopeAirRead}
+
+// Scopes is stored as a comma separated text column.
+type Scopes []string
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Scopes) Scan(value any) error {
+	var raw string
+
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+		*s = Scopes{}
+		return nil
+	default:
+		return errors.New("unsupported type for scopes")
+	}
+
+	if raw == "" {
+		*s = Scopes{}
+		return nil
+	}
+
+	*s = strings.Split(raw, ",")
+	return nil
+}
+
+func (Scopes) GormDataType() string {
+	return "text"
+}
+
+type APIKey struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid" example:"5f0c8a0e-6d1b-4f7e-9a53-0c2c3f0c8e11"`
+	OwnerUID   string     `json:"ownerUid" gorm:"index;not null" example:"0MhHcnVNBMeCIygoBHDDt0SvT053"`
+	Name       string     `json:"name" gorm:"not null" example:"nightly-sync"`
+	Prefix     string     `json:"prefix" gorm:"not null" example:"ww_3f9a1c"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     Scopes     `json:"scopes" gorm:"not null" swaggertype:"array,string" example:"weather:read,air:read"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+type CreateAPIKeyBody struct {
+	Name      string     `json:"name" validate:"required,max=64" example:"nightly-sync"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,scope" example:"weather:read,air:read"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" validate:"omitempty,future" example:"2030-01-01T00:00:00Z"`
+}
+
+// CreatedAPIKey is only returned when a key is created or rotated, since the
+// plaintext key is never stored.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"ww_3f9a1c..."`
+}
@@ -0,0 +1,58 @@
+package entities
+
+import "time"
+
+type CacheEntriesQuery struct {
+	Resource string   `query:"resource" validate:"omitempty,oneof=current_weather weather_forecast current_air_pollution air_pollution_forecast air_pollution_history geocode_direct geocode_reverse last_known"`
+	MinLat   *float64 `query:"minLat" validate:"required_with=MinLon MaxLat MaxLon,omitempty,gte=-90,lte=90"`
+	MinLon   *float64 `query:"minLong" validate:"required_with=MinLat MaxLat MaxLon,omitempty,gte=-180,lte=180"`
+	MaxLat   *float64 `query:"maxLat" validate:"required_with=MinLat MinLon MaxLon,omitempty,gte=-90,lte=90,gtefield=MinLat"`
+	MaxLon   *float64 `query:"maxLong" validate:"required_with=MinLat MinLon MaxLat,omitempty,gte=-180,lte=180,gtefield=MinLon"`
+	Lat      *float64 `query:"lat" validate:"required_with=Lon,excluded_with=MinLat,omitempty,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+	Limit    int      `query:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// CachePurgeQuery is CacheEntriesQuery without the limit; a resource or a
+// location is required, everything is removed with a flush instead.
+type CachePurgeQuery struct {
+	Resource string   `query:"resource" validate:"required_without_all=MinLat Lat,omitempty,oneof=current_weather weather_forecast current_air_pollution air_pollution_forecast air_pollution_history geocode_direct geocode_reverse last_known"`
+	MinLat   *float64 `query:"minLat" validate:"required_with=MinLon MaxLat MaxLon,omitempty,gte=-90,lte=90"`
+	MinLon   *float64 `query:"minLong" validate:"required_with=MinLat MaxLat MaxLon,omitempty,gte=-180,lte=180"`
+	MaxLat   *float64 `query:"maxLat" validate:"required_with=MinLat MinLon MaxLon,omitempty,gte=-90,lte=90,gtefield=MinLat"`
+	MaxLon   *float64 `query:"maxLong" validate:"required_with=MinLat MinLon MaxLat,omitempty,gte=-180,lte=180,gtefield=MinLon"`
+	Lat      *float64 `query:"lat" validate:"required_with=Lon,excluded_with=MinLat,omitempty,gte=-90,lte=90"`
+	Lon      *float64 `query:"long" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+}
+
+type CacheEntryQuery struct {
+	Key string `query:"key" validate:"required"`
+}
+
+type CacheEntryInfo struct {
+	Key      string `json:"key" example:"weather-wrapper:v1:current_weather_geohash:tdr1v9"`
+	Resource string `json:"resource,omitempty" example:"current_weather"`
+	// Cell is the snapped cell of the entry, Lat and Lon its center or the
+	// coordinates of a last known response.
+	Cell      string     `json:"cell,omitempty" example:"geohash:tdr1v9"`
+	Lat       *float64   `json:"lat,omitempty" example:"12.9716"`
+	Lon       *float64   `json:"lon,omitempty" example:"77.5946"`
+	FetchedAt *time.Time `json:"fetchedAt,omitempty"`
+	StaleAt   *time.Time `json:"staleAt,omitempty"`
+	// Age is the number of seconds since the value was fetched upstream.
+	Age int64 `json:"age" example:"120"`
+	// TTL is the number of seconds until the entry expires, -1 when it
+	// never does.
+	TTL   int64 `json:"ttl" example:"3480"`
+	Stale bool  `json:"stale"`
+}
+
+type CacheEntries struct {
+	Entries []CacheEntryInfo `json:"entries"`
+	// Truncated is set when more entries matched than the limit.
+	Truncated bool `json:"truncated"`
+}
+
+type CachePurge struct {
+	Deleted int `json:"deleted" example:"42"`
+}
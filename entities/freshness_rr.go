@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// CacheWarning flags a response whose data is not fresh.
+type CacheWarning struct {
+	// Code is "stale" or "last_known_good".
+	Code      string    `json:"code"`
+	Message   string    `json:"message"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
@@ -1,5 +1,10 @@
 package entities
 
+type GeocodeQuery struct {
+	City  string `query:"city" validate:"required"`
+	Limit int    `query:"limit" validate:"min=1,max=10"`
+}
+
 type Geocode struct {
 	Name       string `json:"name"`
 	LocalNames *struct {
@@ -0,0 +1,37 @@
+package entities
+
+import "time"
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+type Health struct {
+	// Status is degraded while Redis is down or any circuit breaker, of the
+	// cache or of an upstream endpoint, is not closed.
+	Status   string           `json:"status"`
+	Cache    CacheHealth      `json:"cache"`
+	Breakers []CircuitBreaker `json:"breakers"`
+}
+
+type CacheHealth struct {
+	Backend string `json:"backend"`
+	Redis   string `json:"redis"`
+	// Breaker is the state of the circuit breaker in front of Redis, empty
+	// for the memory backend.
+	Breaker string `json:"breaker,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type CircuitBreaker struct {
+	Endpoint            string     `json:"endpoint"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+}
@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Claims is stored as a JSON text column.
+type Claims map[string]interface{}
+
+func (c Claims) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+
+	claimsJSON, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(claimsJSON), nil
+}
+
+func (c *Claims) Scan(value any) error {
+	var raw []byte
+
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case nil:
+		*c = Claims{}
+		return nil
+	default:
+		return errors.New("unsupported type for claims")
+	}
+
+	return json.Unmarshal(raw, c)
+}
+
+func (Claims) GormDataType() string {
+	return "text"
+}
+
+// LocalUser is an account managed by the local identity provider.
+type LocalUser struct {
+	UID           string `gorm:"primaryKey;type:uuid"`
+	Email         string `gorm:"uniqueIndex;not null"`
+	EmailVerified bool   `gorm:"not null;default:false"`
+	PhoneNumber   string
+	PasswordHash  string `gorm:"not null"`
+	DisplayName   string
+	PhotoURL      *string
+	Disabled      bool   `gorm:"not null;default:false"`
+	CustomClaims  Claims `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,9 @@
+package entities
+
+// LatLonQuery is the location query of the weather, reverse geocoding and
+// air pollution endpoints. The coordinates are pointers so that 0 counts as
+// a value rather than a missing parameter.
+type LatLonQuery struct {
+	Lat *float64 `query:"lat" validate:"required,gte=-90,lte=90"`
+	Lon *float64 `query:"long" validate:"required,gte=-180,lte=180"`
+}
@@ -0,0 +1,37 @@
+package entities
+
+import "time"
+
+// UsageRollup aggregates metered requests per principal, route and day.
+type UsageRollup struct {
+	Principal      string    `gorm:"primaryKey"`
+	Route          string    `gorm:"primaryKey"`
+	Day            time.Time `gorm:"primaryKey;type:date"`
+	UID            string    `gorm:"index"`
+	Requests       int64     `gorm:"not null;default:0"`
+	CacheHits      int64     `gorm:"not null;default:0"`
+	CacheMisses    int64     `gorm:"not null;default:0"`
+	UpstreamCalls  int64     `gorm:"not null;default:0"`
+	TotalLatencyMs int64     `gorm:"not null;default:0"`
+}
+
+type UsageQuery struct {
+	Month string `query:"month" validate:"omitempty,datetime=2006-01"`
+	UID   string `query:"uid"`
+}
+
+type UsageTotals struct {
+	Period        string  `json:"period" example:"2024-10-18"`
+	Requests      int64   `json:"requests" example:"120"`
+	CacheHits     int64   `json:"cacheHits" example:"100"`
+	CacheMisses   int64   `json:"cacheMisses" example:"20"`
+	UpstreamCalls int64   `json:"upstreamCalls" example:"20"`
+	AvgLatencyMs  float64 `json:"avgLatencyMs" example:"12.5"`
+}
+
+type UsageReport struct {
+	UID     string        `json:"uid" example:"0MhHcnVNBMeCIygoBHDDt0SvT053"`
+	Month   string        `json:"month" example:"2024-10"`
+	Daily   []UsageTotals `json:"daily"`
+	Monthly []UsageTotals `json:"monthly"`
+}
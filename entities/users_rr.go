@@ -1,14 +1,24 @@
 package entities
 
+import "time"
+
+const (
+	RoleAdmin   = "admin"
+	RoleReader  = "reader"
+	RolePartner = "partner"
+)
+
+var Roles = []string{RoleAdmin, RoleReader, RolePartner}
+
 type UserDetails struct {
 	UID           *string `json:"uid,omitempty" example:"0MhHcnVNBMeCIygoBHDDt0SvT053"`
 	Email         string  `json:"email" validate:"required,email" example:"test@test.com"`
-	EmailVerified bool    `json:"emailVerified" validate:"required" example:"false"`
+	EmailVerified bool    `json:"emailVerified" example:"false"`
 	PhoneNumber   string  `json:"phoneNumber" validate:"required,e164" example:"+911234567890"`
 	Password      string  `json:"password" validate:"required,min=6" example:"testpassword"`
 	DisplayName   string  `json:"name" validate:"required,min=3,max=24" example:"TEST"`
-	PhotoURL      *string `json:"photoURL,omitempty" validate:"url" example:"https://example.com/photo.jpg"`
-	Disabled      bool    `json:"disabled" validate:"required" example:"false"`
+	PhotoURL      *string `json:"photoURL,omitempty" validate:"omitempty,url" example:"https://example.com/photo.jpg"`
+	Disabled      bool    `json:"disabled" example:"false"`
 }
 
 type UidBody struct {
@@ -18,3 +28,41 @@ type UidBody struct {
 type EmailBody struct {
 	Email string `json:"email" validate:"required,email" example:"test@test.com"`
 }
+
+type RolesBody struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,role" example:"reader,partner"`
+}
+
+type CredentialsBody struct {
+	Email    string `json:"email" validate:"required,email" example:"test@test.com"`
+	Password string `json:"password" validate:"required,min=6" example:"testpassword"`
+}
+
+type RefreshTokenBody struct {
+	RefreshToken string `json:"refreshToken" validate:"required" example:"AMf-vBx..."`
+}
+
+// ActionBody applies the action of a link sent by email. The links carry
+// the mode and oobCode in their query; resetting the password also takes
+// newPassword.
+type ActionBody struct {
+	Mode        string `json:"mode" query:"mode" validate:"required,oneof=verifyEmail resetPassword" example:"resetPassword"`
+	OOBCode     string `json:"oobCode" query:"oobCode" validate:"required" example:"eyJhbGciOi..."`
+	NewPassword string `json:"newPassword" validate:"required_if=Mode resetPassword,omitempty,min=6" example:"newpassword"`
+}
+
+// CustomToken is a signed token minted for a user, to be exchanged for an ID
+// token by a client SDK.
+type CustomToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Session is the result of signing in: an ID token to send as a bearer token
+// and a refresh token to get a new one once it expires.
+type Session struct {
+	UID          string    `json:"uid"`
+	IDToken      string    `json:"idToken"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
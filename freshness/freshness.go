@@ -0,0 +1,141 @@
+package freshness
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"sync"
+	"time"
+)
+
+type contextKey struct{}
+
+// ranks orders the states from the most to the least fresh.
+var ranks = map[string]int{
+	Fresh:         0,
+	Stale:         1,
+	LastKnownGood: 2,
+}
+
+const (
+	// Fresh responses come from upstream or a cache entry within its soft TTL.
+	Fresh = "fresh"
+	// Stale responses come from a cache entry past its soft TTL, while a
+	// background refresh replaces it.
+	Stale = "stale"
+	// LastKnownGood responses are the last known upstream response, served
+	// because upstream is down.
+	LastKnownGood = "last_known_good"
+)
+
+// Info collects how fresh the response of an in-flight request is, and for
+// which location its data was fetched. Services mark it on the info carried
+// by the request context.
+type Info struct {
+	mu        sync.Mutex
+	state     string
+	fetchedAt time.Time
+	location  *geo.Location
+}
+
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &Info{state: Fresh})
+}
+
+// FromContext returns the info of the current request, or nil if the request
+// does not track freshness.
+func FromContext(ctx context.Context) *Info {
+	info, _ := ctx.Value(contextKey{}).(*Info)
+	return info
+}
+
+// MarkCached records that the response was served from a cache entry
+// fetched at fetchedAt.
+func MarkCached(ctx context.Context, fetchedAt time.Time) {
+	mark(ctx, Fresh, fetchedAt)
+}
+
+// MarkStale records that the response was served from a cache entry past
+// its soft TTL.
+func MarkStale(ctx context.Context, fetchedAt time.Time) {
+	mark(ctx, Stale, fetchedAt)
+}
+
+// MarkLastKnownGood records that the response is the last known upstream
+// response, served because upstream is down.
+func MarkLastKnownGood(ctx context.Context, fetchedAt time.Time) {
+	mark(ctx, LastKnownGood, fetchedAt)
+}
+
+// MarkLocation records the snapped location whose data the response serves.
+func MarkLocation(ctx context.Context, location geo.Location) {
+	if info := FromContext(ctx); info != nil {
+		info.mu.Lock()
+		info.location = &location
+		info.mu.Unlock()
+	}
+}
+
+// Merge marks ctx with the freshness of from when it is worse than the one
+// marked already, so a response assembled from concurrent fetches, each
+// marking its own context, reports the least fresh of them. Of two responses
+// in the same state the one fetched earlier is worse; a zero fetchedAt was
+// fetched for this request and is the latest. The location of from is kept
+// when ctx has none marked yet.
+func Merge(ctx context.Context, from *Info) {
+	info := FromContext(ctx)
+	if info == nil || from == nil {
+		return
+	}
+
+	state, fetchedAt := from.State()
+	location := from.Location()
+
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	if info.location == nil {
+		info.location = location
+	}
+
+	if worse(state, fetchedAt, info.state, info.fetchedAt) {
+		info.state = state
+		info.fetchedAt = fetchedAt
+	}
+}
+
+// worse reports whether state, fetched at fetchedAt, is less fresh than
+// other, fetched at otherFetchedAt.
+func worse(state string, fetchedAt time.Time, other string, otherFetchedAt time.Time) bool {
+	if ranks[state] != ranks[other] {
+		return ranks[state] > ranks[other]
+	}
+
+	return !fetchedAt.IsZero() && (otherFetchedAt.IsZero() || fetchedAt.Before(otherFetchedAt))
+}
+
+func mark(ctx context.Context, state string, fetchedAt time.Time) {
+	if info := FromContext(ctx); info != nil {
+		info.mu.Lock()
+		info.state = state
+		info.fetchedAt = fetchedAt
+		info.mu.Unlock()
+	}
+}
+
+// State returns the freshness of the response and when its data was fetched
+// from upstream. fetchedAt is zero when it was fetched for this request.
+func (i *Info) State() (state string, fetchedAt time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.state, i.fetchedAt
+}
+
+// Location returns the snapped location whose data the response serves, or
+// nil when the request was not for a location.
+func (i *Info) Location() *geo.Location {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.location
+}
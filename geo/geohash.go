@@ -0,0 +1,115 @@
+package geo
+
+import (
+	"errors"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var ErrInvalidGeohash = errors.New("invalid geohash")
+
+// Box is a latitude and longitude range.
+type Box struct {
+	MinLat float64 `json:"minLat"`
+	MinLon float64 `json:"minLon"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLon float64 `json:"maxLon"`
+}
+
+// Center returns the middle of the box.
+func (b Box) Center() (lat, lon float64) {
+	return (b.MinLat + b.MaxLat) / 2, (b.MinLon + b.MaxLon) / 2
+}
+
+// Contains reports whether the point lies in the box, edges included.
+func (b Box) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Intersects reports whether the boxes overlap, edges included.
+func (b Box) Intersects(other Box) bool {
+	return b.MinLat <= other.MaxLat && other.MinLat <= b.MaxLat && b.MinLon <= other.MaxLon && other.MinLon <= b.MaxLon
+}
+
+// EncodeGeohash returns the geohash of the point with precision characters.
+func EncodeGeohash(lat, lon float64, precision int) string {
+	box := Box{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}
+
+	var hash strings.Builder
+	bits, char, even := 0, 0, true
+
+	for hash.Len() < precision {
+		// Bits alternate between longitude and latitude, longitude first.
+		if even {
+			mid := (box.MinLon + box.MaxLon) / 2
+			if lon >= mid {
+				char = char<<1 | 1
+				box.MinLon = mid
+			} else {
+				char <<= 1
+				box.MaxLon = mid
+			}
+		} else {
+			mid := (box.MinLat + box.MaxLat) / 2
+			if lat >= mid {
+				char = char<<1 | 1
+				box.MinLat = mid
+			} else {
+				char <<= 1
+				box.MaxLat = mid
+			}
+		}
+
+		even = !even
+		bits++
+
+		if bits == 5 {
+			hash.WriteByte(geohashAlphabet[char])
+			bits, char = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+// DecodeGeohash returns the cell of a geohash.
+func DecodeGeohash(hash string) (Box, error) {
+	box := Box{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}
+	even := true
+
+	if hash == "" {
+		return Box{}, ErrInvalidGeohash
+	}
+
+	for i := 0; i < len(hash); i++ {
+		char := strings.IndexByte(geohashAlphabet, hash[i])
+		if char < 0 {
+			return Box{}, ErrInvalidGeohash
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			set := char>>bit&1 == 1
+
+			if even {
+				mid := (box.MinLon + box.MaxLon) / 2
+				if set {
+					box.MinLon = mid
+				} else {
+					box.MaxLon = mid
+				}
+			} else {
+				mid := (box.MinLat + box.MaxLat) / 2
+				if set {
+					box.MinLat = mid
+				} else {
+					box.MaxLat = mid
+				}
+			}
+
+			even = !even
+		}
+	}
+
+	return box, nil
+}
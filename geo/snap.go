@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Location is a requested point snapped to a cell. Nearby points snap to the
+// same cell, share its cache entries and are served the data of its center.
+type Location struct {
+	// Lat and Lon are the center of the cell.
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// Cell identifies the cell, e.g. "geohash:tdr1wx" or "grid0.05:259:1552".
+	Cell         string  `json:"cell"`
+	RequestedLat float64 `json:"requestedLat"`
+	RequestedLon float64 `json:"requestedLon"`
+}
+
+// pointCellFormat names the cell of a point under no rule, as keys were named
+// before snapping.
+const pointCellFormat = "%f_%f"
+
+// Snap snaps the point to its cell under rule. Points under no rule are their
+// own cell.
+func Snap(rule config.SnapRule, lat, lon float64) Location {
+	location := Location{
+		Lat:          lat,
+		Lon:          lon,
+		RequestedLat: lat,
+		RequestedLon: lon,
+	}
+
+	switch rule.Scheme {
+	case config.SnapGeohash:
+		hash := EncodeGeohash(lat, lon, rule.Precision)
+		box, _ := DecodeGeohash(hash)
+		location.Lat, location.Lon = box.Center()
+		location.Cell = config.SnapGeohash + ":" + hash
+	case config.SnapGrid:
+		latIndex := int64(math.Round(lat / rule.Step))
+		lonIndex := int64(math.Round(lon / rule.Step))
+		location.Lat = roundTo(float64(latIndex)*rule.Step, rule.Step)
+		location.Lon = roundTo(float64(lonIndex)*rule.Step, rule.Step)
+		location.Cell = fmt.Sprintf("%s%s:%d:%d", config.SnapGrid, formatStep(rule.Step), latIndex, lonIndex)
+	default:
+		location.Cell = fmt.Sprintf(pointCellFormat, float32(lat), float32(lon))
+	}
+
+	return location
+}
+
+// CellBox returns the area covered by a cell. Cells of single points cover a
+// box without area.
+func CellBox(cell string) (Box, bool) {
+	scheme, rest, found := strings.Cut(cell, ":")
+	if !found {
+		return pointCellBox(cell)
+	}
+
+	if scheme == config.SnapGeohash {
+		box, err := DecodeGeohash(rest)
+		return box, err == nil
+	}
+
+	stepString, found := strings.CutPrefix(scheme, config.SnapGrid)
+	if !found {
+		return Box{}, false
+	}
+
+	latString, lonString, _ := strings.Cut(rest, ":")
+	step, stepErr := strconv.ParseFloat(stepString, 64)
+	latIndex, latErr := strconv.ParseInt(latString, 10, 64)
+	lonIndex, lonErr := strconv.ParseInt(lonString, 10, 64)
+	if stepErr != nil || latErr != nil || lonErr != nil || step <= 0 {
+		return Box{}, false
+	}
+
+	lat, lon := float64(latIndex)*step, float64(lonIndex)*step
+
+	return Box{
+		MinLat: lat - step/2,
+		MinLon: lon - step/2,
+		MaxLat: lat + step/2,
+		MaxLon: lon + step/2,
+	}, true
+}
+
+// pointCellBox parses a cell named with pointCellFormat.
+func pointCellBox(cell string) (Box, bool) {
+	latString, lonString, _ := strings.Cut(cell, "_")
+	lat, latErr := strconv.ParseFloat(latString, 32)
+	lon, lonErr := strconv.ParseFloat(lonString, 32)
+	if latErr != nil || lonErr != nil {
+		return Box{}, false
+	}
+
+	return Box{MinLat: lat, MinLon: lon, MaxLat: lat, MaxLon: lon}, true
+}
+
+// roundTo drops the floating point noise of multiples of step, so 259*0.05
+// reads 12.95 rather than 12.950000000000001.
+func roundTo(value, step float64) float64 {
+	decimals := 0
+	if _, fraction, found := strings.Cut(formatStep(step), "."); found {
+		decimals = len(fraction)
+	}
+
+	scale := math.Pow10(decimals)
+
+	return math.Round(value*scale) / scale
+}
+
+func formatStep(step float64) string {
+	return strconv.FormatFloat(step, 'f', -1, 64)
+}
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/advisory"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/services"
 	"github.com/SamPariatIL/weather-wrapper/utils"
 	"github.com/gofiber/fiber/v2"
@@ -11,121 +15,242 @@ type AirPollutionHandler interface {
 	GetCurrentAirPollution(ctx *fiber.Ctx) error
 	GetAirPollutionForecast(ctx *fiber.Ctx) error
 	GetHistoricalAirPollution(ctx *fiber.Ctx) error
+	GetAdvice(ctx *fiber.Ctx) error
 }
 
 type airPollutionHandler struct {
 	airPollutionService services.AirPollutionService
+	adviceService       services.AdviceService
+	summaryService      services.AirPollutionSummaryService
 	logger              *zap.Logger
 }
 
-func NewAirPollutionHandler(as services.AirPollutionService, zl *zap.Logger) AirPollutionHandler {
+func NewAirPollutionHandler(as services.AirPollutionService, ads services.AdviceService, ss services.AirPollutionSummaryService, zl *zap.Logger) AirPollutionHandler {
 	return &airPollutionHandler{
 		airPollutionService: as,
+		adviceService:       ads,
+		summaryService:      ss,
 		logger:              zl,
 	}
 }
 
 // GetCurrentAirPollution godoc
 // @Summary Get current air pollution
-// @Description Get current air pollution for a given city
+// @Description Get current air pollution for a given city, along with its air quality index under the requested standard (US EPA by default) and the health advisory of its risk level
 // @Tags air-pollution
 // @Accept json
 // @Produce json
 // @Param lat query string true "Latitude"
 // @Param long query string true "Longitude"
+// @Param standard query string false "Air quality index standard" Enums(us_epa, eu_caqi, in_naqi)
+// @Param lang query string false "Language of the advisory, overriding the Accept-Language header"
 // @Success 200
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /air-pollution/now [get]
 func (ah *airPollutionHandler) GetCurrentAirPollution(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
+	query := new(entities.AirPollutionQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, ah.logger, err)
+	}
+
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+
+	currentAirPollution, err := ah.airPollutionService.GetCurrentAirPollution(ctx.UserContext(), lat, lon)
 	if err != nil {
-		ah.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
 	}
 
-	currentAirPollution, err := ah.airPollutionService.GetCurrentAirPollution(lat, lon)
+	currentAirQuality, err := aqi.Current(currentAirPollution, standardOrDefault(query.Standard))
 	if err != nil {
-		ah.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, airPollutionFetchingError, err.Error()))
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
 	}
 
+	currentAdvisory := advisory.For(advisory.Risk(currentAirQuality.AQI), locale(ctx, query.Lang))
+	currentAirQuality.Advisory = &currentAdvisory
+
 	ah.logger.Info(successFetchingAirPollution)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(currentAirPollution, fiber.StatusOK, "", successFetchingAirPollution))
+	return fetched(ctx, currentAirQuality, successFetchingAirPollution)
 }
 
 // GetAirPollutionForecast godoc
 // @Summary Get air pollution forecast
-// @Description Get air pollution forecast for a given city
+// @Description Get air pollution forecast for a given city, summarized by day in the local time of the location: the min, mean and max of every pollutant, the hour of the peak air quality index and the hours above the WHO guideline values. In raw mode, the hourly datapoints instead, which with a standard carry their air quality index under it.
 // @Tags air-pollution
 // @Accept json
 // @Produce json
 // @Param lat query string true "Latitude"
 // @Param long query string true "Longitude"
-// @Success 200
+// @Param standard query string false "Air quality index standard" Enums(us_epa, eu_caqi, in_naqi)
+// @Param raw query bool false "Return the hourly datapoints instead of daily summaries"
+// @Success 200 {object} entities.AirPollutionForecastResponse
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /air-pollution/forecast [get]
 func (ah *airPollutionHandler) GetAirPollutionForecast(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
+	query := new(entities.AirPollutionForecastQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, ah.logger, err)
+	}
+
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+
+	if !query.Raw {
+		forecastSummary, err := ah.summaryService.GetForecastSummary(ctx.UserContext(), lat, lon, standardOrDefault(query.Standard))
+		if err != nil {
+			return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
+		}
+
+		ah.logger.Info(successFetchingAirPollution)
+		return fetched(ctx, forecastSummary, successFetchingAirPollution)
+	}
+
+	airPollutionForecast, err := ah.airPollutionService.GetAirPollutionForecast(ctx.UserContext(), lat, lon)
 	if err != nil {
-		ah.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
 	}
 
-	airPollutionForecast, err := ah.airPollutionService.GetAirPollutionForecast(lat, lon)
+	if query.Standard == "" {
+		ah.logger.Info(successFetchingAirPollution)
+		return fetched(ctx, airPollutionForecast, successFetchingAirPollution)
+	}
+
+	indexedForecast, err := aqi.Indexed(airPollutionForecast, query.Standard)
 	if err != nil {
-		ah.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, airPollutionFetchingError, err.Error()))
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
 	}
 
 	ah.logger.Info(successFetchingAirPollution)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(airPollutionForecast, fiber.StatusOK, "", successFetchingAirPollution))
+	return fetched(ctx, indexedForecast, successFetchingAirPollution)
 }
 
 // GetHistoricalAirPollution godoc
 // @Summary Get historical air pollution
-// @Description Get historical air pollution for a given city
+// @Description Get historical air pollution for a given city, summarized by day in the local time of the location like the forecast. Ranges longer than the configured maximum span are rejected, and long ranges are fetched in parallel chunks. In raw mode, the hourly datapoints are streamed back instead, and with a standard carry their air quality index under it.
 // @Tags air-pollution
 // @Accept json
 // @Produce json
 // @Param lat query string true "Latitude"
 // @Param long query string true "Longitude"
-// @Param start query string true "Start Date (Epoch)"
-// @Param end query string true "End Date (Epoch)"
-// @Success 200
+// @Param start query string true "Start Date (Epoch), not before 1606435200"
+// @Param end query string true "End Date (Epoch), not in the future"
+// @Param standard query string false "Air quality index standard" Enums(us_epa, eu_caqi, in_naqi)
+// @Param raw query bool false "Stream the hourly datapoints instead of daily summaries"
+// @Success 200 {object} entities.HistoricalAirPollutionResponse
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /air-pollution/history [get]
 func (ah *airPollutionHandler) GetHistoricalAirPollution(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
+	query := new(entities.HistoricalAirPollutionQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, ah.logger, err)
+	}
+
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+	startDate, endDate := *query.Start, *query.End
+
+	if !query.Raw {
+		historySummary, err := ah.summaryService.GetHistorySummary(ctx.UserContext(), lat, lon, startDate, endDate, standardOrDefault(query.Standard))
+		if err != nil {
+			return ah.historyFailed(ctx, err)
+		}
+
+		ah.logger.Info(successFetchingAirPollution)
+		return fetched(ctx, historySummary, successFetchingAirPollution)
+	}
+
+	airPollutionHistory, err := ah.airPollutionService.GetHistoricalAirPollution(ctx.UserContext(), lat, lon, startDate, endDate)
 	if err != nil {
-		ah.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+		return ah.historyFailed(ctx, err)
+	}
+
+	if query.Standard == "" {
+		ah.logger.Info(successFetchingAirPollution)
+		return streamFetched(ctx, ah.logger, airPollutionHistory.Coord, airPollutionHistory.List, successFetchingAirPollution)
 	}
 
-	startDate, endDate, err := utils.ValidateDateRange(ctx.Query("start"), ctx.Query("end"))
+	indexedHistory, err := aqi.Indexed(airPollutionHistory, query.Standard)
 	if err != nil {
-		ah.logger.Warn(invalidDate)
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
+	}
+
+	ah.logger.Info(successFetchingAirPollution)
+	return streamFetched(ctx, ah.logger, indexedHistory.Coord, indexedHistory.List, successFetchingAirPollution)
+}
+
+// historyFailed answers a failed history request, with a 400 for ranges
+// longer than the maximum span.
+func (ah *airPollutionHandler) historyFailed(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrHistoryRangeTooLong) {
+		ah.logger.Warn(err.Error())
 		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidDate, err.Error()))
+			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, historyRangeTooLong, err.Error()))
+	}
+
+	return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
+}
+
+// GetAdvice godoc
+// @Summary Get air quality advice
+// @Description Get the health advisory of the current air quality for a given city, the outlook of the forecast by risk level and the periods when outdoor activity is safest
+// @Tags air-pollution
+// @Accept json
+// @Produce json
+// @Param lat query string true "Latitude"
+// @Param long query string true "Longitude"
+// @Param standard query string false "Air quality index standard" Enums(us_epa, eu_caqi, in_naqi)
+// @Param lang query string false "Language of the messages, overriding the Accept-Language header"
+// @Success 200 {object} entities.AirQualityAdvice
+// @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
+// @Failure 500
+// @Failure 502
+// @Failure 503
+// @Router /air-pollution/advice [get]
+func (ah *airPollutionHandler) GetAdvice(ctx *fiber.Ctx) error {
+	query := new(entities.AdviceQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, ah.logger, err)
 	}
 
-	airPollutionHistory, err := ah.airPollutionService.GetHistoricalAirPollution(lat, lon, startDate, endDate)
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+
+	advice, err := ah.adviceService.GetAdvice(ctx.UserContext(), lat, lon, standardOrDefault(query.Standard), locale(ctx, query.Lang))
 	if err != nil {
-		ah.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, airPollutionFetchingError, err.Error()))
+		return fetchFailed(ctx, ah.logger, err, airPollutionFetchingError)
 	}
 
-	ah.logger.Info(successFetchingAirPollution)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(airPollutionHistory, fiber.StatusOK, "", successFetchingAirPollution))
+	ah.logger.Info(successFetchingAdvice)
+	return fetched(ctx, advice, successFetchingAdvice)
+}
+
+// locale picks the locale of the advisories from the lang query parameter,
+// then from the Accept-Language header.
+func locale(ctx *fiber.Ctx, lang string) string {
+	return advisory.Locale(lang, ctx.Get(fiber.HeaderAcceptLanguage))
+}
+
+func standardOrDefault(standard string) string {
+	if standard == "" {
+		return aqi.Default
+	}
+
+	return standard
 }
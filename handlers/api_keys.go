@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type APIKeyHandler interface {
+	CreateAPIKey(ctx *fiber.Ctx) error
+	ListAPIKeys(ctx *fiber.Ctx) error
+	RotateAPIKey(ctx *fiber.Ctx) error
+	RevokeAPIKey(ctx *fiber.Ctx) error
+}
+
+type apiKeyHandler struct {
+	apiKeyService services.APIKeyService
+	logger        *zap.Logger
+}
+
+func NewAPIKeyHandler(aks services.APIKeyService, zl *zap.Logger) APIKeyHandler {
+	return &apiKeyHandler{
+		apiKeyService: aks,
+		logger:        zl,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create an API key for a user. The key is only returned once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Param apiKey body entities.CreateAPIKeyBody true "API key details"
+// @Success 201
+// @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/api-keys [post]
+func (akh *apiKeyHandler) CreateAPIKey(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+	body := new(entities.CreateAPIKeyBody)
+
+	if err := parseBody(ctx, body); err != nil {
+		return invalidInput(ctx, akh.logger, err)
+	}
+
+	apiKey, err := akh.apiKeyService.CreateAPIKey(uid, body)
+	if err != nil {
+		akh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, apiKeyCreationError, err.Error()))
+	}
+
+	akh.logger.Info(successCreatingAPIKey)
+	return ctx.Status(fiber.StatusCreated).
+		JSON(utils.CustomResponse(apiKey, fiber.StatusCreated, "", successCreatingAPIKey))
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of a user
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Success 200
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/api-keys [get]
+func (akh *apiKeyHandler) ListAPIKeys(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+
+	apiKeys, err := akh.apiKeyService.ListAPIKeys(uid)
+	if err != nil {
+		akh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, apiKeyFetchingError, err.Error()))
+	}
+
+	akh.logger.Info(successFetchingAPIKeys)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(apiKeys, fiber.StatusOK, "", successFetchingAPIKeys))
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate API key
+// @Description Replace an API key's secret, keeping its name, scopes and expiry. Revoked and expired keys cannot be rotated.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Param id path string true "API key ID"
+// @Success 200
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/api-keys/{id}/rotate [post]
+func (akh *apiKeyHandler) RotateAPIKey(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+	id := ctx.Params("id")
+
+	apiKey, err := akh.apiKeyService.RotateAPIKey(uid, id)
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		akh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusNotFound).
+			JSON(utils.CustomResponse(nil, fiber.StatusNotFound, apiKeyNotFound, err.Error()))
+	} else if errors.Is(err, services.ErrAPIKeyRevoked) || errors.Is(err, services.ErrAPIKeyExpired) {
+		akh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusConflict).
+			JSON(utils.CustomResponse(nil, fiber.StatusConflict, apiKeyInactive, err.Error()))
+	} else if err != nil {
+		akh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, apiKeyRotationError, err.Error()))
+	}
+
+	akh.logger.Info(successRotatingAPIKey)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(apiKey, fiber.StatusOK, "", successRotatingAPIKey))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key so it can no longer be used
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Param id path string true "API key ID"
+// @Success 200
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/api-keys/{id} [delete]
+func (akh *apiKeyHandler) RevokeAPIKey(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+	id := ctx.Params("id")
+
+	apiKey, err := akh.apiKeyService.RevokeAPIKey(uid, id)
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		akh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusNotFound).
+			JSON(utils.CustomResponse(nil, fiber.StatusNotFound, apiKeyNotFound, err.Error()))
+	} else if err != nil {
+		akh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, apiKeyRevocationError, err.Error()))
+	}
+
+	akh.logger.Info(successRevokingAPIKey)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(apiKey, fiber.StatusOK, "", successRevokingAPIKey))
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type CacheAdminHandler interface {
+	ListEntries(ctx *fiber.Ctx) error
+	GetEntry(ctx *fiber.Ctx) error
+	PurgeEntries(ctx *fiber.Ctx) error
+	Flush(ctx *fiber.Ctx) error
+}
+
+type cacheAdminHandler struct {
+	cacheAdminService services.CacheAdminService
+	logger            *zap.Logger
+}
+
+func NewCacheAdminHandler(cas services.CacheAdminService, zl *zap.Logger) CacheAdminHandler {
+	return &cacheAdminHandler{
+		cacheAdminService: cas,
+		logger:            zl,
+	}
+}
+
+// ListEntries godoc
+// @Summary List cache entries
+// @Description List cached entries with their age and remaining TTL, by resource and by bounding box or the cell containing a point
+// @Tags admin
+// @Produce json
+// @Param resource query string false "Resource" Enums(current_weather, weather_forecast, current_air_pollution, air_pollution_forecast, air_pollution_history, geocode_direct, geocode_reverse, last_known)
+// @Param minLat query number false "Bounding box south edge"
+// @Param minLong query number false "Bounding box west edge"
+// @Param maxLat query number false "Bounding box north edge"
+// @Param maxLong query number false "Bounding box east edge"
+// @Param lat query number false "Latitude of a point, instead of a bounding box"
+// @Param long query number false "Longitude of a point, instead of a bounding box"
+// @Param limit query int false "Maximum number of entries, 100 by default"
+// @Success 200 {object} entities.CacheEntries
+// @Failure 401
+// @Failure 403
+// @Failure 422
+// @Failure 500
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/cache/entries [get]
+func (cah *cacheAdminHandler) ListEntries(ctx *fiber.Ctx) error {
+	query := new(entities.CacheEntriesQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, cah.logger, err)
+	}
+
+	entries, err := cah.cacheAdminService.ListEntries(ctx.UserContext(), query)
+	if err != nil {
+		cah.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, cacheFetchingError, err.Error()))
+	}
+
+	cah.logger.Info(successFetchingCacheEntries)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(entries, fiber.StatusOK, "", successFetchingCacheEntries))
+}
+
+// GetEntry godoc
+// @Summary Get cache entry
+// @Description Get the age and remaining TTL of a cached entry by its key
+// @Tags admin
+// @Produce json
+// @Param key query string true "Key"
+// @Success 200 {object} entities.CacheEntryInfo
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 422
+// @Failure 500
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/cache/entry [get]
+func (cah *cacheAdminHandler) GetEntry(ctx *fiber.Ctx) error {
+	query := new(entities.CacheEntryQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, cah.logger, err)
+	}
+
+	entry, err := cah.cacheAdminService.GetEntry(ctx.UserContext(), query.Key)
+	if errors.Is(err, services.ErrCacheEntryNotFound) {
+		cah.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusNotFound).
+			JSON(utils.CustomResponse(nil, fiber.StatusNotFound, cacheEntryNotFound, err.Error()))
+	} else if err != nil {
+		cah.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, cacheFetchingError, err.Error()))
+	}
+
+	cah.logger.Info(successFetchingCacheEntries)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(entry, fiber.StatusOK, "", successFetchingCacheEntries))
+}
+
+// PurgeEntries godoc
+// @Summary Purge cache entries
+// @Description Remove the cached entries of a resource, of a bounding box or of the cell containing a point
+// @Tags admin
+// @Produce json
+// @Param resource query string false "Resource, required without a location" Enums(current_weather, weather_forecast, current_air_pollution, air_pollution_forecast, air_pollution_history, geocode_direct, geocode_reverse, last_known)
+// @Param minLat query number false "Bounding box south edge"
+// @Param minLong query number false "Bounding box west edge"
+// @Param maxLat query number false "Bounding box north edge"
+// @Param maxLong query number false "Bounding box east edge"
+// @Param lat query number false "Latitude of a point, instead of a bounding box"
+// @Param long query number false "Longitude of a point, instead of a bounding box"
+// @Success 200 {object} entities.CachePurge
+// @Failure 401
+// @Failure 403
+// @Failure 422
+// @Failure 500
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/cache/entries [delete]
+func (cah *cacheAdminHandler) PurgeEntries(ctx *fiber.Ctx) error {
+	query := new(entities.CachePurgeQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, cah.logger, err)
+	}
+
+	purge, err := cah.cacheAdminService.Purge(ctx.UserContext(), query)
+	if err != nil {
+		cah.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, cachePurgeError, err.Error()))
+	}
+
+	cah.logger.Info(successPurgingCache)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(purge, fiber.StatusOK, "", successPurgingCache))
+}
+
+// Flush godoc
+// @Summary Flush cache
+// @Description Remove every key this service wrote to the cache, locks and rate limits included. Usage quotas and the keys of other applications sharing the Redis DB are kept
+// @Tags admin
+// @Produce json
+// @Success 200 {object} entities.CachePurge
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/cache [delete]
+func (cah *cacheAdminHandler) Flush(ctx *fiber.Ctx) error {
+	purge, err := cah.cacheAdminService.Flush(ctx.UserContext())
+	if err != nil {
+		cah.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, cachePurgeError, err.Error()))
+	}
+
+	cah.logger.Info(successPurgingCache)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(purge, fiber.StatusOK, "", successPurgingCache))
+}
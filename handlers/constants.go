@@ -1,9 +1,8 @@
 package handlers
 
 const (
-	invalidLatLon                   = "invalid latitude or longitude"
-	invalidLimit                    = "invalid limit"
-	invalidCity                     = "invalid city"
+	validationFailed                = "validation failed"
+	malformedRequest                = "malformed request"
 	weatherFetchingError            = "something went wrong fetching the weather"
 	geocodingFetchingError          = "something went wrong fetching the geocode"
 	reverseGeocodingFetchingError   = "something went wrong fetching the city"
@@ -18,9 +17,47 @@ const (
 	userDeletionError               = "something went wrong deleting the user"
 	successGeneratingToken          = "successfully generated the token"
 	tokenGenerationError            = "something went wrong generating the token"
-	emailSendingError               = "something went wrong sending the email"
-	successSendingEmail             = "successfully sent the email"
+	emailQueued                     = "if an account uses this address, an email is on its way"
+	invalidActionCode               = "invalid or expired action code"
+	actionError                     = "something went wrong applying the action"
+	successApplyingAction           = "successfully applied the action"
 	airPollutionFetchingError       = "something went wrong fetching the air pollution"
 	successFetchingAirPollution     = "successfully fetched the air pollution"
-	invalidDate                     = "invalid date"
+	upstreamNotFound                = "no data was found for the request"
+	upstreamInvalidInput            = "the weather provider rejected the request"
+	upstreamRateLimited             = "the weather provider is rate limiting requests, try again later"
+	upstreamBadGateway              = "the weather provider returned an invalid response"
+	staleData                       = "the data was fetched at %s and is being refreshed"
+	lastKnownGoodData               = "the weather provider is unavailable, the data is the last known good from %s"
+	successFetchingHealth           = "successfully retrieved the health"
+	upstreamUnavailable             = "the weather provider is unavailable, try again later"
+	successUpdatingRoles            = "successfully updated the roles"
+	rolesUpdationError              = "something went wrong updating the roles"
+	apiKeyNotFound                  = "api key not found"
+	apiKeyInactive                  = "the api key is revoked or expired"
+	apiKeyCreationError             = "something went wrong creating the api key"
+	apiKeyFetchingError             = "something went wrong fetching the api keys"
+	apiKeyRotationError             = "something went wrong rotating the api key"
+	apiKeyRevocationError           = "something went wrong revoking the api key"
+	successCreatingAPIKey           = "successfully created the api key"
+	successFetchingAPIKeys          = "successfully fetched the api keys"
+	successRotatingAPIKey           = "successfully rotated the api key"
+	successRevokingAPIKey           = "successfully revoked the api key"
+	usageFetchingError              = "something went wrong fetching the usage"
+	successFetchingUsage            = "successfully fetched the usage"
+	forbidden                       = "forbidden"
+	notOwnUsage                     = "only admins can view the usage of other users"
+	notOwnToken                     = "only admins can generate tokens for other users"
+	userNotFound                    = "user not found"
+	invalidCredentials              = "invalid credentials"
+	invalidRefreshToken             = "invalid or expired refresh token"
+	tokenExchangeError              = "something went wrong exchanging the token"
+	successExchangingToken          = "successfully exchanged the token"
+	cacheEntryNotFound              = "cache entry not found"
+	cacheFetchingError              = "something went wrong fetching the cache entries"
+	cachePurgeError                 = "something went wrong purging the cache"
+	successFetchingCacheEntries     = "successfully fetched the cache entries"
+	successPurgingCache             = "successfully purged the cache"
+	historyRangeTooLong             = "the history range is too long"
+	successFetchingAdvice           = "successfully fetched the air quality advice"
 )
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// fetchFailed responds to a failed data fetch. Upstream failures, including
+// a payload without datapoints, are mapped to the status that describes them, anything else is a 500 with errorConst.
+// The error itself is only logged, since it may carry upstream URLs and
+// internal details.
+func fetchFailed(ctx *fiber.Ctx, logger *zap.Logger, err error, errorConst string) error {
+	status, upstreamErrorConst := upstreamStatus(err)
+	if upstreamErrorConst != "" {
+		errorConst = upstreamErrorConst
+	}
+
+	if status >= fiber.StatusInternalServerError {
+		logger.Error(err.Error())
+	} else {
+		logger.Warn(err.Error())
+	}
+
+	return ctx.Status(status).
+		JSON(utils.CustomResponse(nil, status, errorConst, errorConst))
+}
+
+func upstreamStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, upstream.ErrNotFound):
+		return fiber.StatusNotFound, upstreamNotFound
+	case errors.Is(err, upstream.ErrInvalidInput):
+		return fiber.StatusBadRequest, upstreamInvalidInput
+	case errors.Is(err, upstream.ErrRateLimited):
+		return fiber.StatusTooManyRequests, upstreamRateLimited
+	case errors.Is(err, upstream.ErrUnauthorized), errors.Is(err, upstream.ErrBadPayload), errors.Is(err, aqi.ErrNoDatapoints):
+		return fiber.StatusBadGateway, upstreamBadGateway
+	case errors.Is(err, upstream.ErrUnavailable):
+		return fiber.StatusServiceUnavailable, upstreamUnavailable
+	default:
+		return fiber.StatusInternalServerError, ""
+	}
+}
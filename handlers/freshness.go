@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// warnings are the Warning headers of responses that are not fresh.
+var warnings = map[string]string{
+	freshness.Stale:         `110 - "Response is Stale"`,
+	freshness.LastKnownGood: `111 - "Revalidation Failed"`,
+}
+
+var warningMessages = map[string]string{
+	freshness.Stale:         staleData,
+	freshness.LastKnownGood: lastKnownGoodData,
+}
+
+// streamBatch is how many datapoints a streamed response writes between
+// flushes.
+const streamBatch = 500
+
+// fetched answers with data fetched through the cache, along with the snapped
+// location the data is for. Cached data gets an Age header, and data that is
+// not fresh a Warning header and a warning in the body.
+func fetched(ctx *fiber.Ctx, data any, message string) error {
+	return ctx.Status(fiber.StatusOK).JSON(fetchedResponse(ctx, data, message))
+}
+
+// streamFetched answers like fetched with the datapoints of the list at the
+// coordinates, but streams the datapoints in batches instead of encoding the
+// whole response up front. The response is encoded with an empty list, and
+// the datapoints are written into it.
+func streamFetched[T any](ctx *fiber.Ctx, logger *zap.Logger, coord entities.Coord, list []T, message string) error {
+	response := fetchedResponse(ctx, streamedList[T]{Coord: coord, List: []T{}}, message)
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	// The data comes first, as the keys are sorted, and its list is the
+	// first one in the response.
+	split := bytes.Index(encoded, []byte(`"list":[]`))
+	if split < 0 {
+		return fmt.Errorf("no list to stream in the response")
+	}
+
+	head, tail := encoded[:split+len(`"list":[`)], encoded[split+len(`"list":[`):]
+
+	ctx.Status(fiber.StatusOK).Type("json")
+	// Errors of the writer stick, so they are all seen by the flushes.
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		w.Write(head)
+
+		for i, datapoint := range list {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			encodedDatapoint, err := json.Marshal(datapoint)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to encode a streamed datapoint: %v", err))
+				return
+			}
+
+			w.Write(encodedDatapoint)
+
+			if (i+1)%streamBatch == 0 {
+				if err := w.Flush(); err != nil {
+					logger.Warn(fmt.Sprintf("Failed to stream the response: %v", err))
+					return
+				}
+			}
+		}
+
+		w.Write(tail)
+
+		if err := w.Flush(); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to stream the response: %v", err))
+		}
+	})
+
+	return nil
+}
+
+// streamedList is the data of a streamed response.
+type streamedList[T any] struct {
+	entities.Coord
+	List []T `json:"list"`
+}
+
+// fetchedResponse builds the response of fetched and sets its headers.
+func fetchedResponse(ctx *fiber.Ctx, data any, message string) fiber.Map {
+	response := utils.CustomResponse(data, fiber.StatusOK, "", message)
+
+	if info := freshness.FromContext(ctx.UserContext()); info != nil {
+		if location := info.Location(); location != nil {
+			response["location"] = location
+		}
+
+		state, fetchedAt := info.State()
+
+		if !fetchedAt.IsZero() {
+			ctx.Set(fiber.HeaderAge, strconv.Itoa(int(time.Since(fetchedAt).Seconds())))
+		}
+
+		if warning, ok := warnings[state]; ok {
+			ctx.Set(fiber.HeaderWarning, warning)
+			response["warning"] = entities.CacheWarning{
+				Code:      state,
+				Message:   fmt.Sprintf(warningMessages[state], fetchedAt.UTC().Format(time.RFC3339)),
+				FetchedAt: fetchedAt,
+			}
+		}
+	}
+
+	return response
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/services"
-	"github.com/SamPariatIL/weather-wrapper/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
@@ -33,34 +33,26 @@ func NewGeocodingHandler(gs services.GeocodingService, zl *zap.Logger) Geocoding
 // @Param city query string true "City"
 // @Success 200
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /geocode [get]
 func (gh *geocodingHandler) GetGeocodeForCity(ctx *fiber.Ctx) error {
-	city := ctx.Query("city")
-	err := utils.ValidateCity(city)
-	if err != nil {
-		gh.logger.Warn(invalidCity)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidCity, err.Error()))
-	}
-
-	limit, err := utils.ValidateLimit(ctx.Query("limit"))
-	if err != nil {
-		gh.logger.Warn(invalidLimit)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLimit, err.Error()))
+	query := &entities.GeocodeQuery{Limit: 5}
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, gh.logger, err)
 	}
 
-	coords, err := gh.geocodingService.GetGeocodeForCity(city, limit)
+	coords, err := gh.geocodingService.GetGeocodeForCity(ctx.UserContext(), query.City, query.Limit)
 	if err != nil {
-		gh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, geocodingFetchingError, err.Error()))
+		return fetchFailed(ctx, gh.logger, err, geocodingFetchingError)
 	}
 
 	gh.logger.Info(successFetchingGeocode)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(coords, fiber.StatusOK, "", successFetchingGeocode))
+	return fetched(ctx, coords, successFetchingGeocode)
 }
 
 // GetCityFromLatLon godoc
@@ -73,31 +65,26 @@ func (gh *geocodingHandler) GetGeocodeForCity(ctx *fiber.Ctx) error {
 // @Param long query string true "Longitude"
 // @Success 200
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /geocode/reverse [get]
 func (gh *geocodingHandler) GetCityFromLatLon(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
-	if err != nil {
-		gh.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+	query := new(entities.LatLonQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, gh.logger, err)
 	}
 
-	city, err := gh.geocodingService.GetCityFromLatLon(lat, lon)
-
-	if city == nil && err != nil {
-		gh.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusNotFound).
-			JSON(utils.CustomResponse(nil, fiber.StatusNotFound, invalidLatLon, err.Error()))
-	}
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
 
+	city, err := gh.geocodingService.GetCityFromLatLon(ctx.UserContext(), lat, lon)
 	if err != nil {
-		gh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, reverseGeocodingFetchingError, err.Error()))
+		return fetchFailed(ctx, gh.logger, err, reverseGeocodingFetchingError)
 	}
 
 	gh.logger.Info(successFetchingReverseGeocoding)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(city, fiber.StatusOK, "", successFetchingReverseGeocoding))
+	return fetched(ctx, city, successFetchingReverseGeocoding)
 }
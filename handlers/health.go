@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type HealthHandler interface {
+	GetHealth(ctx *fiber.Ctx) error
+}
+
+type healthHandler struct {
+	healthService services.HealthService
+	logger        *zap.Logger
+}
+
+func NewHealthHandler(hs services.HealthService, zl *zap.Logger) HealthHandler {
+	return &healthHandler{
+		healthService: hs,
+		logger:        zl,
+	}
+}
+
+// GetHealth godoc
+// @Summary Get health
+// @Description Get the health of the service, whether Redis is reachable and the state of the circuit breaker of every upstream endpoint. The service keeps serving while degraded
+// @Tags health
+// @Produce json
+// @Success 200 {object} entities.Health
+// @Router /health [get]
+func (hh *healthHandler) GetHealth(ctx *fiber.Ctx) error {
+	health := hh.healthService.GetHealth(ctx.UserContext())
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(health, fiber.StatusOK, "", successFetchingHealth))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type UsageHandler interface {
+	GetUsage(ctx *fiber.Ctx) error
+}
+
+type usageHandler struct {
+	usageService services.UsageService
+	logger       *zap.Logger
+}
+
+func NewUsageHandler(us services.UsageService, zl *zap.Logger) UsageHandler {
+	return &usageHandler{
+		usageService: us,
+		logger:       zl,
+	}
+}
+
+// GetUsage godoc
+// @Summary Get usage
+// @Description Get daily totals for a month and monthly totals for the year up to it. Admins can pass uid to view any user.
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Param uid query string false "User ID, admin only"
+// @Success 200
+// @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /usage [get]
+func (uh *usageHandler) GetUsage(ctx *fiber.Ctx) error {
+	query := new(entities.UsageQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	callerUID := middlewares.GetUID(ctx)
+	uid := query.UID
+	if uid == "" {
+		uid = callerUID
+	}
+
+	if uid != callerUID && !middlewares.IsAdmin(ctx) {
+		uh.logger.Warn(fmt.Sprintf("forbidden: user %s tried to view the usage of user %s", callerUID, uid))
+		return ctx.Status(fiber.StatusForbidden).
+			JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, notOwnUsage))
+	}
+
+	month, err := utils.ValidateMonth(query.Month)
+	if err != nil {
+		return invalidInput(ctx, uh.logger, invalidField("month", "datetime", "2006-01", err))
+	}
+
+	report, err := uh.usageService.GetUsageReport(uid, month)
+	if err != nil {
+		uh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, usageFetchingError, err.Error()))
+	}
+
+	uh.logger.Info(successFetchingUsage)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(report, fiber.StatusOK, "", successFetchingUsage))
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
 	"github.com/SamPariatIL/weather-wrapper/services"
 	"github.com/SamPariatIL/weather-wrapper/utils"
 	"github.com/gofiber/fiber/v2"
@@ -13,8 +17,13 @@ type UserHandler interface {
 	UpdateUser(ctx *fiber.Ctx) error
 	DeleteUser(ctx *fiber.Ctx) error
 	GenerateToken(ctx *fiber.Ctx) error
+	ExchangeToken(ctx *fiber.Ctx) error
+	RefreshToken(ctx *fiber.Ctx) error
 	SendVerificationEmail(ctx *fiber.Ctx) error
 	ResetPassword(ctx *fiber.Ctx) error
+	ApplyAction(ctx *fiber.Ctx) error
+	SetUserRoles(ctx *fiber.Ctx) error
+	ClearUserRoles(ctx *fiber.Ctx) error
 }
 
 type userHandler struct {
@@ -38,15 +47,14 @@ func NewUserHandler(us services.UserService, zl *zap.Logger) UserHandler {
 // @Param user body entities.UserDetails true "User details"
 // @Success 201
 // @Failure 400
+// @Failure 422
 // @Failure 500
 // @Router /users/signup [post]
 func (uh *userHandler) CreateUser(ctx *fiber.Ctx) error {
 	user := new(entities.UserDetails)
 
-	if err := ctx.BodyParser(user); err != nil {
-		uh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, "", err.Error()))
+	if err := parseBody(ctx, user); err != nil {
+		return invalidInput(ctx, uh.logger, err)
 	}
 
 	uid, err := uh.userService.CreateUser(user)
@@ -73,16 +81,18 @@ func (uh *userHandler) CreateUser(ctx *fiber.Ctx) error {
 // @Param user body entities.UserDetails true "User details"
 // @Success 200
 // @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 403
 // @Failure 500
+// @Security BearerAuth
 // @Router /users/{uid} [put]
 func (uh *userHandler) UpdateUser(ctx *fiber.Ctx) error {
 	uid := ctx.Params("uid")
 	user := new(entities.UserDetails)
 
-	if err := ctx.BodyParser(user); err != nil {
-		uh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, "", err.Error()))
+	if err := parseBody(ctx, user); err != nil {
+		return invalidInput(ctx, uh.logger, err)
 	}
 
 	updatedUserId, err := uh.userService.UpdateUser(uid, user)
@@ -105,7 +115,10 @@ func (uh *userHandler) UpdateUser(ctx *fiber.Ctx) error {
 // @Produce json
 // @Param uid path string true "User ID"
 // @Success 200
+// @Failure 401
+// @Failure 403
 // @Failure 500
+// @Security BearerAuth
 // @Router /users/{uid} [delete]
 func (uh *userHandler) DeleteUser(ctx *fiber.Ctx) error {
 	uid := ctx.Params("uid")
@@ -124,26 +137,40 @@ func (uh *userHandler) DeleteUser(ctx *fiber.Ctx) error {
 
 // GenerateToken godoc
 // @Summary Generate token
-// @Description Generate a token
+// @Description Mint a custom token for a user, to be exchanged for an ID token by a client SDK. Users can only mint tokens for themselves unless they are admins.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param token body entities.UidBody true "Token body"
-// @Success 200
+// @Success 200 {object} entities.CustomToken
 // @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 403
+// @Failure 404
 // @Failure 500
+// @Security BearerAuth
 // @Router /users/token [post]
 func (uh *userHandler) GenerateToken(ctx *fiber.Ctx) error {
 	user := new(entities.UidBody)
 
-	if err := ctx.BodyParser(user); err != nil {
-		uh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, "", err.Error()))
+	if err := parseBody(ctx, user); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	callerUID := middlewares.GetUID(ctx)
+	if user.UID != callerUID && !middlewares.IsAdmin(ctx) {
+		uh.logger.Warn(fmt.Sprintf("forbidden: user %s tried to generate a token for user %s", callerUID, user.UID))
+		return ctx.Status(fiber.StatusForbidden).
+			JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, notOwnToken))
 	}
 
 	token, err := uh.userService.GenerateToken(user.UID)
-	if err != nil {
+	if errors.Is(err, identity.ErrUserNotFound) {
+		uh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusNotFound).
+			JSON(utils.CustomResponse(nil, fiber.StatusNotFound, userNotFound, err.Error()))
+	} else if err != nil {
 		uh.logger.Error(err.Error())
 		return ctx.Status(fiber.StatusInternalServerError).
 			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, tokenGenerationError, err.Error()))
@@ -154,66 +181,229 @@ func (uh *userHandler) GenerateToken(ctx *fiber.Ctx) error {
 		JSON(utils.CustomResponse(token, fiber.StatusOK, "", successGeneratingToken))
 }
 
-// SendVerificationEmail godoc
-// @Summary Send verification email
-// @Description Send a verification email
+// ExchangeToken godoc
+// @Summary Exchange credentials for tokens
+// @Description Sign in with an email and password and get an ID token plus a refresh token
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param email body entities.EmailBody true "Email body"
-// @Success 200
+// @Param credentials body entities.CredentialsBody true "Credentials"
+// @Success 200 {object} entities.Session
 // @Failure 400
+// @Failure 422
+// @Failure 401
 // @Failure 500
-// @Router /users/verify [post]
-func (uh *userHandler) SendVerificationEmail(ctx *fiber.Ctx) error {
-	emailBody := new(entities.EmailBody)
+// @Router /users/token/exchange [post]
+func (uh *userHandler) ExchangeToken(ctx *fiber.Ctx) error {
+	credentials := new(entities.CredentialsBody)
+
+	if err := parseBody(ctx, credentials); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
 
-	if err := ctx.BodyParser(emailBody); err != nil {
+	session, err := uh.userService.ExchangeCredentials(credentials.Email, credentials.Password)
+	if errors.Is(err, identity.ErrInvalidCredentials) {
+		uh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, invalidCredentials, identity.ErrInvalidCredentials.Error()))
+	} else if err != nil {
 		uh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, "", err.Error()))
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, tokenExchangeError, err.Error()))
 	}
 
-	link, err := uh.userService.SendVerificationEmail(emailBody.Email)
-	if err != nil {
+	uh.logger.Info(successExchangingToken)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(session, fiber.StatusOK, "", successExchangingToken))
+}
+
+// RefreshToken godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new ID token and refresh token
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param refreshToken body entities.RefreshTokenBody true "Refresh token"
+// @Success 200 {object} entities.Session
+// @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 500
+// @Router /users/token/refresh [post]
+func (uh *userHandler) RefreshToken(ctx *fiber.Ctx) error {
+	body := new(entities.RefreshTokenBody)
+
+	if err := parseBody(ctx, body); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	session, err := uh.userService.RefreshSession(body.RefreshToken)
+	if errors.Is(err, identity.ErrInvalidToken) {
+		uh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, invalidRefreshToken, err.Error()))
+	} else if err != nil {
 		uh.logger.Error(err.Error())
 		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, emailSendingError, err.Error()))
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, tokenExchangeError, err.Error()))
 	}
 
-	uh.logger.Info(successSendingEmail)
+	uh.logger.Info(successExchangingToken)
 	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(map[string]string{"link": *link}, fiber.StatusOK, "", successSendingEmail))
+		JSON(utils.CustomResponse(session, fiber.StatusOK, "", successExchangingToken))
+}
+
+// SendVerificationEmail godoc
+// @Summary Send verification email
+// @Description Send a verification email. The response is the same whether or not the address belongs to an account.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param email body entities.EmailBody true "Email body"
+// @Success 202
+// @Failure 400
+// @Failure 422
+// @Router /users/verify [post]
+func (uh *userHandler) SendVerificationEmail(ctx *fiber.Ctx) error {
+	emailBody := new(entities.EmailBody)
+
+	if err := parseBody(ctx, emailBody); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	uh.userService.SendVerificationEmail(emailBody.Email)
+
+	uh.logger.Info(emailQueued)
+	return ctx.Status(fiber.StatusAccepted).
+		JSON(utils.CustomResponse(nil, fiber.StatusAccepted, "", emailQueued))
 }
 
 // ResetPassword godoc
 // @Summary Reset password
-// @Description Send a verification email to reset password
+// @Description Send an email with a link to reset the password. The response is the same whether or not the address belongs to an account.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param email body entities.EmailBody true "Email body"
-// @Success 200
+// @Success 202
 // @Failure 400
-// @Failure 500
+// @Failure 422
 // @Router /users/reset-password [post]
 func (uh *userHandler) ResetPassword(ctx *fiber.Ctx) error {
 	emailBody := new(entities.EmailBody)
 
-	if err := ctx.BodyParser(emailBody); err != nil {
+	if err := parseBody(ctx, emailBody); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	uh.userService.ResetPassword(emailBody.Email)
+
+	uh.logger.Info(emailQueued)
+	return ctx.Status(fiber.StatusAccepted).
+		JSON(utils.CustomResponse(nil, fiber.StatusAccepted, "", emailQueued))
+}
+
+// ApplyAction godoc
+// @Summary Apply an emailed action
+// @Description Verify the email or reset the password with the code of a link sent by email. Following a link verifies the email; resetting the password needs a POST with the new password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param action body entities.ActionBody true "Action"
+// @Success 200
+// @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 500
+// @Router /users/action [post]
+func (uh *userHandler) ApplyAction(ctx *fiber.Ctx) error {
+	action := new(entities.ActionBody)
+
+	parse := parseBody
+	if ctx.Method() == fiber.MethodGet {
+		parse = parseQuery
+	}
+
+	if err := parse(ctx, action); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	err := uh.userService.ApplyAction(action.Mode, action.OOBCode, action.NewPassword)
+	if errors.Is(err, identity.ErrInvalidToken) {
+		uh.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, invalidActionCode, invalidActionCode))
+	} else if err != nil {
 		uh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, "", err.Error()))
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, actionError, actionError))
 	}
 
-	link, err := uh.userService.ResetPassword(emailBody.Email)
+	uh.logger.Info(successApplyingAction)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(nil, fiber.StatusOK, "", successApplyingAction))
+}
+
+// SetUserRoles godoc
+// @Summary Set user roles
+// @Description Replace the roles custom claim of a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Param roles body entities.RolesBody true "Roles body"
+// @Success 200
+// @Failure 400
+// @Failure 422
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/roles [put]
+func (uh *userHandler) SetUserRoles(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+	rolesBody := new(entities.RolesBody)
+
+	if err := parseBody(ctx, rolesBody); err != nil {
+		return invalidInput(ctx, uh.logger, err)
+	}
+
+	updatedUserId, err := uh.userService.SetUserRoles(uid, rolesBody.Roles)
+	if err != nil {
+		uh.logger.Error(err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, rolesUpdationError, err.Error()))
+	}
+
+	uh.logger.Info(successUpdatingRoles)
+	return ctx.Status(fiber.StatusOK).
+		JSON(utils.CustomResponse(updatedUserId, fiber.StatusOK, "", successUpdatingRoles))
+}
+
+// ClearUserRoles godoc
+// @Summary Clear user roles
+// @Description Remove the roles custom claim from a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param uid path string true "User ID"
+// @Success 200
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Security BearerAuth
+// @Router /users/{uid}/roles [delete]
+func (uh *userHandler) ClearUserRoles(ctx *fiber.Ctx) error {
+	uid := ctx.Params("uid")
+
+	updatedUserId, err := uh.userService.ClearUserRoles(uid)
 	if err != nil {
 		uh.logger.Error(err.Error())
 		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, emailSendingError, err.Error()))
+			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, rolesUpdationError, err.Error()))
 	}
 
-	uh.logger.Info(successSendingEmail)
+	uh.logger.Info(successUpdatingRoles)
 	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(map[string]string{"link": *link}, fiber.StatusOK, "", successSendingEmail))
+		JSON(utils.CustomResponse(updatedUserId, fiber.StatusOK, "", successUpdatingRoles))
 }
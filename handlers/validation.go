@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+)
+
+// parseBody parses the request body into body and checks its validate tags.
+func parseBody(ctx *fiber.Ctx, body any) error {
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	return utils.ValidateStruct(body)
+}
+
+// parseQuery parses the query string into query and checks its validate
+// tags. Values that cannot be converted to the field type are reported as
+// validation errors too.
+func parseQuery(ctx *fiber.Ctx, query any) error {
+	if err := ctx.QueryParser(query); err != nil {
+		return conversionErrors(err)
+	}
+
+	return utils.ValidateStruct(query)
+}
+
+// invalidInput responds with 422 and the invalid fields for validation errors,
+// and with 400 for bodies that could not be parsed at all.
+func invalidInput(ctx *fiber.Ctx, logger *zap.Logger, err error) error {
+	var validationErr *utils.ValidationError
+	if errors.As(err, &validationErr) {
+		logger.Warn(validationErr.Error())
+		return ctx.Status(fiber.StatusUnprocessableEntity).
+			JSON(utils.CustomResponse(validationErr.Fields, fiber.StatusUnprocessableEntity, validationFailed, validationErr.Error()))
+	}
+
+	logger.Warn(err.Error())
+	return ctx.Status(fiber.StatusBadRequest).
+		JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, malformedRequest, err.Error()))
+}
+
+// invalidField reports a check made outside the validate tags as a
+// validation error of a single field, so it is answered like the others.
+func invalidField(field, rule, param string, err error) error {
+	return &utils.ValidationError{Fields: []utils.FieldError{{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: err.Error(),
+	}}}
+}
+
+func conversionErrors(err error) error {
+	var multiErr fiber.MultiError
+	if !errors.As(err, &multiErr) {
+		return err
+	}
+
+	fields := make([]utils.FieldError, 0, len(multiErr))
+	for key, keyErr := range multiErr {
+		var conversionErr fiber.ConversionError
+		if !errors.As(keyErr, &conversionErr) {
+			return err
+		}
+
+		expectedType := typeName(conversionErr.Type)
+
+		fields = append(fields, utils.FieldError{
+			Field:   key,
+			Rule:    "type",
+			Param:   expectedType,
+			Message: fmt.Sprintf("%s must be a valid %s", key, expectedType),
+		})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+
+	return &utils.ValidationError{Fields: fields}
+}
+
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return t.String()
+	}
+}
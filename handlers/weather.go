@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/services"
-	"github.com/SamPariatIL/weather-wrapper/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
@@ -34,26 +34,28 @@ func NewWeatherHandler(ws services.WeatherService, zl *zap.Logger) WeatherHandle
 // @Param long query string true "Longitude"
 // @Success 200
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /weather/now [get]
 func (wh *weatherHandler) GetCurrentWeather(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
-	if err != nil {
-		wh.logger.Warn(invalidLatLon)
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+	query := new(entities.LatLonQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, wh.logger, err)
 	}
 
-	currentWeather, err := wh.weatherService.GetCurrentWeather(lat, lon)
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+
+	currentWeather, err := wh.weatherService.GetCurrentWeather(ctx.UserContext(), lat, lon)
 	if err != nil {
-		wh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, weatherFetchingError, err.Error()))
+		return fetchFailed(ctx, wh.logger, err, weatherFetchingError)
 	}
 
 	wh.logger.Info(successFetchingWeather)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(currentWeather, fiber.StatusOK, "", successFetchingWeather))
+	return fetched(ctx, currentWeather, successFetchingWeather)
 }
 
 // GetFiveDayForecast godoc
@@ -66,23 +68,26 @@ func (wh *weatherHandler) GetCurrentWeather(ctx *fiber.Ctx) error {
 // @Param long query string true "Longitude"
 // @Success 200
 // @Failure 400
+// @Failure 404
+// @Failure 422
+// @Failure 429
 // @Failure 500
+// @Failure 502
+// @Failure 503
 // @Router /weather/forecast [get]
 func (wh *weatherHandler) GetFiveDayForecast(ctx *fiber.Ctx) error {
-	lat, lon, err := utils.ValidateLatLon(ctx.Query("lat"), ctx.Query("long"))
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(utils.CustomResponse(nil, fiber.StatusBadRequest, invalidLatLon, err.Error()))
+	query := new(entities.LatLonQuery)
+	if err := parseQuery(ctx, query); err != nil {
+		return invalidInput(ctx, wh.logger, err)
 	}
 
-	forecast, err := wh.weatherService.GetFiveDayForecast(lat, lon)
+	lat, lon := float32(*query.Lat), float32(*query.Lon)
+
+	forecast, err := wh.weatherService.GetFiveDayForecast(ctx.UserContext(), lat, lon)
 	if err != nil {
-		wh.logger.Error(err.Error())
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(utils.CustomResponse(nil, fiber.StatusInternalServerError, weatherFetchingError, err.Error()))
+		return fetchFailed(ctx, wh.logger, err, weatherFetchingError)
 	}
 
 	wh.logger.Info(successFetchingWeather)
-	return ctx.Status(fiber.StatusOK).
-		JSON(utils.CustomResponse(forecast, fiber.StatusOK, "", successFetchingWeather))
+	return fetched(ctx, forecast, successFetchingWeather)
 }
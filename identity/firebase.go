@@ -0,0 +1,282 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"firebase.google.com/go/v4/auth"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signInWithPasswordURL = "https://identitytoolkit.googleapis.com/v1/accounts:signInWithPassword"
+	updateAccountURL      = "https://identitytoolkit.googleapis.com/v1/accounts:update"
+	resetPasswordURL      = "https://identitytoolkit.googleapis.com/v1/accounts:resetPassword"
+	refreshTokenURL       = "https://securetoken.googleapis.com/v1/token"
+
+	// Firebase custom tokens are always valid for one hour.
+	firebaseCustomTokenTTL = time.Hour
+	firebaseRequestTimeout = time.Second * 10
+)
+
+type firebaseProvider struct {
+	firebaseAuth *auth.Client
+	apiKey       string
+	httpClient   *http.Client
+}
+
+// NewFirebaseProvider wraps the Firebase admin auth client. The web API key
+// is only needed to sign users in with a password.
+func NewFirebaseProvider(fa *auth.Client, apiKey string) Provider {
+	return &firebaseProvider{
+		firebaseAuth: fa,
+		apiKey:       apiKey,
+		httpClient:   &http.Client{Timeout: firebaseRequestTimeout},
+	}
+}
+
+type signInResponse struct {
+	LocalID      string `json:"localId"`
+	IDToken      string `json:"idToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    string `json:"expiresIn"`
+}
+
+type refreshTokenResponse struct {
+	UserID       string `json:"user_id"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    string `json:"expires_in"`
+}
+
+type firebaseErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (fp *firebaseProvider) CreateUser(ctx context.Context, user *entities.UserDetails) (string, error) {
+	params := (&auth.UserToCreate{}).
+		Email(user.Email).
+		EmailVerified(user.EmailVerified).
+		PhoneNumber(user.PhoneNumber).
+		Password(user.Password).
+		DisplayName(user.DisplayName).
+		Disabled(user.Disabled)
+
+	if user.PhotoURL != nil {
+		params.PhotoURL(*user.PhotoURL)
+	}
+
+	createdUser, err := fp.firebaseAuth.CreateUser(ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	return createdUser.UID, nil
+}
+
+func (fp *firebaseProvider) UpdateUser(ctx context.Context, uid string, user *entities.UserDetails) error {
+	params := (&auth.UserToUpdate{}).
+		Email(user.Email).
+		EmailVerified(user.EmailVerified).
+		PhoneNumber(user.PhoneNumber).
+		Password(user.Password).
+		DisplayName(user.DisplayName).
+		Disabled(user.Disabled)
+
+	if user.PhotoURL != nil {
+		params.PhotoURL(*user.PhotoURL)
+	}
+
+	_, err := fp.firebaseAuth.UpdateUser(ctx, uid, params)
+	return mapFirebaseError(err)
+}
+
+func (fp *firebaseProvider) DeleteUser(ctx context.Context, uid string) error {
+	return mapFirebaseError(fp.firebaseAuth.DeleteUser(ctx, uid))
+}
+
+func (fp *firebaseProvider) GetCustomClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	user, err := fp.firebaseAuth.GetUser(ctx, uid)
+	if err != nil {
+		return nil, mapFirebaseError(err)
+	}
+
+	return user.CustomClaims, nil
+}
+
+func (fp *firebaseProvider) GetActiveClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	user, err := fp.firebaseAuth.GetUser(ctx, uid)
+	if err != nil {
+		return nil, mapFirebaseError(err)
+	}
+
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return user.CustomClaims, nil
+}
+
+func (fp *firebaseProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	return mapFirebaseError(fp.firebaseAuth.SetCustomUserClaims(ctx, uid, claims))
+}
+
+func (fp *firebaseProvider) CustomToken(ctx context.Context, uid string) (*entities.CustomToken, error) {
+	expiresAt := time.Now().Add(firebaseCustomTokenTTL)
+
+	token, err := fp.firebaseAuth.CustomToken(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CustomToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// VerifyIDToken also checks the account, so the tokens of disabled users
+// and revoked sessions are rejected before they expire.
+func (fp *firebaseProvider) VerifyIDToken(ctx context.Context, idToken string) (*Token, error) {
+	token, err := fp.firebaseAuth.VerifyIDTokenAndCheckRevoked(ctx, idToken)
+	if auth.IsUserDisabled(err) || auth.IsIDTokenRevoked(err) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &Token{UID: token.UID, Claims: token.Claims}, nil
+}
+
+func (fp *firebaseProvider) EmailVerificationLink(ctx context.Context, email string) (string, error) {
+	link, err := fp.firebaseAuth.EmailVerificationLink(ctx, email)
+	return link, mapFirebaseError(err)
+}
+
+func (fp *firebaseProvider) PasswordResetLink(ctx context.Context, email string) (string, error) {
+	link, err := fp.firebaseAuth.PasswordResetLink(ctx, email)
+	return link, mapFirebaseError(err)
+}
+
+func (fp *firebaseProvider) SignInWithPassword(ctx context.Context, email, password string) (*entities.Session, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"email":             email,
+		"password":          password,
+		"returnSecureToken": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var signIn signInResponse
+
+	err = fp.post(ctx, signInWithPasswordURL, "application/json", string(body), ErrInvalidCredentials, &signIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFirebaseSession(signIn.LocalID, signIn.IDToken, signIn.RefreshToken, signIn.ExpiresIn), nil
+}
+
+func (fp *firebaseProvider) RefreshSession(ctx context.Context, refreshToken string) (*entities.Session, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+
+	var refreshed refreshTokenResponse
+
+	err := fp.post(ctx, refreshTokenURL, "application/x-www-form-urlencoded", form.Encode(), ErrInvalidToken, &refreshed)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFirebaseSession(refreshed.UserID, refreshed.IDToken, refreshed.RefreshToken, refreshed.ExpiresIn), nil
+}
+
+// ApplyAction applies the codes of links handled by this server rather than
+// by the Firebase action page.
+func (fp *firebaseProvider) ApplyAction(ctx context.Context, mode, oobCode, newPassword string) error {
+	var endpoint string
+	body := map[string]interface{}{"oobCode": oobCode}
+
+	switch mode {
+	case ActionVerifyEmail:
+		endpoint = updateAccountURL
+	case ActionResetPassword:
+		endpoint = resetPasswordURL
+		body["newPassword"] = newPassword
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidToken, mode)
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var applied map[string]interface{}
+
+	return fp.post(ctx, endpoint, "application/json", string(bodyJSON), ErrInvalidToken, &applied)
+}
+
+// post calls a Firebase REST endpoint. Client errors are reported as
+// rejected, since Firebase answers 400 for bad credentials and tokens.
+func (fp *firebaseProvider) post(ctx context.Context, endpoint, contentType, body string, rejected error, v any) error {
+	if fp.apiKey == "" {
+		return errors.New("the firebase web api key is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?key="+url.QueryEscape(fp.apiKey), strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := fp.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError {
+		var firebaseErr firebaseErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&firebaseErr)
+
+		return fmt.Errorf("%w: %s", rejected, firebaseErr.Error.Message)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("firebase responded with status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func newFirebaseSession(uid, idToken, refreshToken, expiresIn string) *entities.Session {
+	seconds, err := strconv.Atoi(expiresIn)
+	if err != nil {
+		seconds = int(firebaseCustomTokenTTL.Seconds())
+	}
+
+	return &entities.Session{
+		UID:          uid,
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(time.Second * time.Duration(seconds)),
+	}
+}
+
+func mapFirebaseError(err error) error {
+	if auth.IsUserNotFound(err) || auth.IsEmailNotFound(err) {
+		return ErrUserNotFound
+	}
+
+	return err
+}
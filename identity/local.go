@@ -0,0 +1,478 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+)
+
+const (
+	tokenUseID            = "id"
+	tokenUseRefresh       = "refresh"
+	tokenUseVerifyEmail   = "verify_email"
+	tokenUseResetPassword = "reset_password"
+
+	actionLinkTTL = time.Hour
+)
+
+// actionTokenUses maps the modes of action links to the use of their codes.
+var actionTokenUses = map[string]string{
+	ActionVerifyEmail:   tokenUseVerifyEmail,
+	ActionResetPassword: tokenUseResetPassword,
+}
+
+// dummyPasswordHash is compared against when signing in with an unknown email.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// reservedClaims cannot be overwritten by custom claims.
+var reservedClaims = []string{"iss", "sub", "aud", "exp", "iat", "nbf", "jti", "uid", "email", "email_verified", "token_use", "pwd"}
+
+type localProvider struct {
+	postgresDB      *gorm.DB
+	signingMethod   jwt.SigningMethod
+	signingKey      any
+	verifyingKey    any
+	issuer          string
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+	actionURL       string
+}
+
+// NewLocalProvider returns a provider that keeps users in Postgres with
+// bcrypt hashed passwords and signs its own JWTs, so the server can run
+// without Firebase.
+func NewLocalProvider(db *gorm.DB, conf config.IdentityConfig) (Provider, error) {
+	lp := &localProvider{
+		postgresDB:      db,
+		issuer:          conf.JWTIssuer,
+		tokenTTL:        conf.TokenTTL,
+		refreshTokenTTL: conf.RefreshTokenTTL,
+		actionURL:       conf.ActionURL,
+	}
+
+	switch conf.JWTAlgorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if len(conf.JWTSecret) < 32 {
+			return nil, errors.New("the HS256 secret must be at least 32 bytes long")
+		}
+
+		lp.signingMethod = jwt.SigningMethodHS256
+		lp.signingKey = []byte(conf.JWTSecret)
+		lp.verifyingKey = []byte(conf.JWTSecret)
+	case jwt.SigningMethodEdDSA.Alg():
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM([]byte(conf.JWTPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the Ed25519 private key: %w", err)
+		}
+
+		lp.signingMethod = jwt.SigningMethodEdDSA
+		lp.signingKey = privateKey
+		lp.verifyingKey = privateKey.(crypto.Signer).Public()
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", conf.JWTAlgorithm)
+	}
+
+	return lp, nil
+}
+
+func (lp *localProvider) CreateUser(ctx context.Context, user *entities.UserDetails) (string, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	localUser := entities.LocalUser{
+		UID:           uuid.NewString(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		PhoneNumber:   user.PhoneNumber,
+		PasswordHash:  string(passwordHash),
+		DisplayName:   user.DisplayName,
+		PhotoURL:      user.PhotoURL,
+		Disabled:      user.Disabled,
+		CustomClaims:  entities.Claims{},
+	}
+
+	err = lp.postgresDB.WithContext(ctx).Create(&localUser).Error
+	if err != nil {
+		return "", err
+	}
+
+	return localUser.UID, nil
+}
+
+func (lp *localProvider) UpdateUser(ctx context.Context, uid string, user *entities.UserDetails) error {
+	localUser, err := lp.getUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	localUser.Email = user.Email
+	localUser.EmailVerified = user.EmailVerified
+	localUser.PhoneNumber = user.PhoneNumber
+	localUser.DisplayName = user.DisplayName
+	localUser.Disabled = user.Disabled
+
+	if user.PhotoURL != nil {
+		localUser.PhotoURL = user.PhotoURL
+	}
+
+	if user.Password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		localUser.PasswordHash = string(passwordHash)
+	}
+
+	return lp.postgresDB.WithContext(ctx).Save(localUser).Error
+}
+
+func (lp *localProvider) DeleteUser(ctx context.Context, uid string) error {
+	result := lp.postgresDB.WithContext(ctx).Delete(&entities.LocalUser{}, "uid = ?", uid)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (lp *localProvider) GetCustomClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	localUser, err := lp.getUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return localUser.CustomClaims, nil
+}
+
+func (lp *localProvider) GetActiveClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	localUser, err := lp.getUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if localUser.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return localUser.CustomClaims, nil
+}
+
+func (lp *localProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	for _, reservedClaim := range reservedClaims {
+		if _, exists := claims[reservedClaim]; exists {
+			return fmt.Errorf("claim %q is reserved", reservedClaim)
+		}
+	}
+
+	result := lp.postgresDB.WithContext(ctx).
+		Model(&entities.LocalUser{}).
+		Where("uid = ?", uid).
+		Update("custom_claims", entities.Claims(claims))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CustomToken mints an ID token directly. There is no client SDK to exchange
+// custom tokens against when running locally.
+func (lp *localProvider) CustomToken(ctx context.Context, uid string) (*entities.CustomToken, error) {
+	localUser, err := lp.getUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := lp.signIDToken(localUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CustomToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// VerifyIDToken looks the user up again, so the tokens of deleted and
+// disabled accounts are rejected before they expire.
+func (lp *localProvider) VerifyIDToken(ctx context.Context, idToken string) (*Token, error) {
+	claims, err := lp.parseToken(idToken, tokenUseID)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, _ := claims["sub"].(string)
+
+	localUser, err := lp.getUser(ctx, uid)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: unknown user", ErrInvalidToken)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if localUser.Disabled {
+		return nil, fmt.Errorf("%w: user is disabled", ErrInvalidToken)
+	}
+
+	return &Token{UID: uid, Claims: claims}, nil
+}
+
+func (lp *localProvider) SignInWithPassword(ctx context.Context, email, password string) (*entities.Session, error) {
+	var localUser entities.LocalUser
+
+	err := lp.postgresDB.WithContext(ctx).Where("email = ?", email).First(&localUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Compare against a dummy hash anyway, so that unknown emails take
+		// as long as wrong passwords.
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(localUser.PasswordHash), []byte(password))
+	if err != nil || localUser.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	return lp.newSession(&localUser)
+}
+
+// RefreshSession issues a new ID token and refresh token. The user is looked
+// up again, so deleted and disabled accounts cannot refresh, and neither can
+// sessions started before the password last changed.
+func (lp *localProvider) RefreshSession(ctx context.Context, refreshToken string) (*entities.Session, error) {
+	claims, err := lp.parseToken(refreshToken, tokenUseRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, _ := claims["sub"].(string)
+
+	localUser, err := lp.getUser(ctx, uid)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: unknown user", ErrInvalidToken)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if localUser.Disabled {
+		return nil, fmt.Errorf("%w: user is disabled", ErrInvalidToken)
+	}
+
+	if claims["pwd"] != passwordVersion(localUser) {
+		return nil, fmt.Errorf("%w: the password has changed", ErrInvalidToken)
+	}
+
+	return lp.newSession(localUser)
+}
+
+func (lp *localProvider) EmailVerificationLink(ctx context.Context, email string) (string, error) {
+	return lp.actionLink(ctx, email, tokenUseVerifyEmail, ActionVerifyEmail)
+}
+
+func (lp *localProvider) PasswordResetLink(ctx context.Context, email string) (string, error) {
+	return lp.actionLink(ctx, email, tokenUseResetPassword, ActionResetPassword)
+}
+
+// ApplyAction checks that the code was issued for mode and for the current
+// email of the user before applying it. Password reset codes are also bound
+// to the password they replace, so each can only be used once.
+func (lp *localProvider) ApplyAction(ctx context.Context, mode, oobCode, newPassword string) error {
+	tokenUse, known := actionTokenUses[mode]
+	if !known {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidToken, mode)
+	}
+
+	claims, err := lp.parseToken(oobCode, tokenUse)
+	if err != nil {
+		return err
+	}
+
+	uid, _ := claims["sub"].(string)
+
+	localUser, err := lp.getUser(ctx, uid)
+	if errors.Is(err, ErrUserNotFound) {
+		return fmt.Errorf("%w: unknown user", ErrInvalidToken)
+	} else if err != nil {
+		return err
+	}
+
+	if claims["email"] != localUser.Email {
+		return fmt.Errorf("%w: the email has changed", ErrInvalidToken)
+	}
+
+	if tokenUse == tokenUseResetPassword && claims["pwd"] != passwordVersion(localUser) {
+		return fmt.Errorf("%w: the password has changed", ErrInvalidToken)
+	}
+
+	switch tokenUse {
+	case tokenUseVerifyEmail:
+		localUser.EmailVerified = true
+	case tokenUseResetPassword:
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		localUser.PasswordHash = string(passwordHash)
+	}
+
+	return lp.postgresDB.WithContext(ctx).Save(localUser).Error
+}
+
+func (lp *localProvider) getUser(ctx context.Context, uid string) (*entities.LocalUser, error) {
+	var localUser entities.LocalUser
+
+	err := lp.postgresDB.WithContext(ctx).Where("uid = ?", uid).First(&localUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &localUser, nil
+}
+
+// passwordVersion identifies the current password of the user without
+// revealing its hash. Tokens carry it in their pwd claim, so that changing
+// the password invalidates them.
+func passwordVersion(localUser *entities.LocalUser) string {
+	sum := sha256.Sum256([]byte(localUser.PasswordHash))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (lp *localProvider) newSession(localUser *entities.LocalUser) (*entities.Session, error) {
+	idToken, expiresAt, err := lp.signIDToken(localUser)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	refreshToken, err := jwt.NewWithClaims(lp.signingMethod, jwt.MapClaims{
+		"iss":       lp.issuer,
+		"aud":       lp.issuer,
+		"sub":       localUser.UID,
+		"jti":       uuid.NewString(),
+		"pwd":       passwordVersion(localUser),
+		"token_use": tokenUseRefresh,
+		"iat":       now.Unix(),
+		"exp":       now.Add(lp.refreshTokenTTL).Unix(),
+	}).SignedString(lp.signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.Session{
+		UID:          localUser.UID,
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func (lp *localProvider) signIDToken(localUser *entities.LocalUser) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(lp.tokenTTL)
+
+	claims := jwt.MapClaims{}
+	for key, value := range localUser.CustomClaims {
+		claims[key] = value
+	}
+
+	claims["iss"] = lp.issuer
+	claims["aud"] = lp.issuer
+	claims["sub"] = localUser.UID
+	claims["uid"] = localUser.UID
+	claims["email"] = localUser.Email
+	claims["email_verified"] = localUser.EmailVerified
+	claims["token_use"] = tokenUseID
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+
+	token, err := jwt.NewWithClaims(lp.signingMethod, claims).SignedString(lp.signingKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (lp *localProvider) actionLink(ctx context.Context, email, tokenUse, mode string) (string, error) {
+	var localUser entities.LocalUser
+
+	err := lp.postgresDB.WithContext(ctx).Where("email = ?", email).First(&localUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrUserNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	oobCode, err := jwt.NewWithClaims(lp.signingMethod, jwt.MapClaims{
+		"iss":       lp.issuer,
+		"aud":       lp.issuer,
+		"sub":       localUser.UID,
+		"email":     localUser.Email,
+		"pwd":       passwordVersion(&localUser),
+		"token_use": tokenUse,
+		"iat":       now.Unix(),
+		"exp":       now.Add(actionLinkTTL).Unix(),
+	}).SignedString(lp.signingKey)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("mode", mode)
+	query.Set("oobCode", oobCode)
+
+	return lp.actionURL + "?" + query.Encode(), nil
+}
+
+func (lp *localProvider) parseToken(tokenString, tokenUse string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != lp.signingMethod.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return lp.verifyingKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	if !claims.VerifyIssuer(lp.issuer, true) || !claims.VerifyAudience(lp.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer or audience", ErrInvalidToken)
+	}
+
+	if claims["token_use"] != tokenUse {
+		return nil, fmt.Errorf("%w: unexpected token use", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
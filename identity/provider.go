@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+)
+
+const (
+	ProviderFirebase = "firebase"
+	ProviderLocal    = "local"
+)
+
+// Modes of the action links sent by email.
+const (
+	ActionVerifyEmail   = "verifyEmail"
+	ActionResetPassword = "resetPassword"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserDisabled = errors.New("user is disabled")
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidCredentials is returned for a wrong password, an unknown
+	// email or a disabled account alike, so callers cannot tell them apart.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// Token is a verified ID token.
+type Token struct {
+	UID    string
+	Claims map[string]interface{}
+}
+
+// Provider manages accounts and the tokens that authenticate them.
+type Provider interface {
+	CreateUser(ctx context.Context, user *entities.UserDetails) (string, error)
+	UpdateUser(ctx context.Context, uid string, user *entities.UserDetails) error
+	DeleteUser(ctx context.Context, uid string) error
+	GetCustomClaims(ctx context.Context, uid string) (map[string]interface{}, error)
+	// GetActiveClaims returns the custom claims of uid like GetCustomClaims,
+	// failing with ErrUserDisabled when the user is disabled.
+	GetActiveClaims(ctx context.Context, uid string) (map[string]interface{}, error)
+	SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error
+	CustomToken(ctx context.Context, uid string) (*entities.CustomToken, error)
+	VerifyIDToken(ctx context.Context, idToken string) (*Token, error)
+	SignInWithPassword(ctx context.Context, email, password string) (*entities.Session, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*entities.Session, error)
+	EmailVerificationLink(ctx context.Context, email string) (string, error)
+	PasswordResetLink(ctx context.Context, email string) (string, error)
+	// ApplyAction applies the action of a link sent by email: mode
+	// ActionVerifyEmail verifies the email, mode ActionResetPassword sets
+	// newPassword. Codes issued for another mode are rejected with
+	// ErrInvalidToken.
+	ApplyAction(ctx context.Context, mode, oobCode, newPassword string) error
+}
@@ -0,0 +1,164 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"go.uber.org/zap"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, to string, template string, data any) error
+}
+
+type smtpMailer struct {
+	conf   config.MailerConfig
+	logger *zap.Logger
+}
+
+func NewSMTPMailer(conf config.MailerConfig, zl *zap.Logger) Mailer {
+	return &smtpMailer{
+		conf:   conf,
+		logger: zl,
+	}
+}
+
+// Send renders the template and delivers it over SMTP. STARTTLS is used when
+// the server offers it, and credentials are only sent when configured.
+func (sm *smtpMailer) Send(ctx context.Context, to string, template string, data any) error {
+	email, err := Render(template, data)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(sm.conf.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	message, err := BuildMessage(from, recipient, email)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sm.conf.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(sm.conf.Host, strconv.Itoa(sm.conf.Port))
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, sm.conf.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: sm.conf.Host}); err != nil {
+			return err
+		}
+	}
+
+	if sm.conf.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", sm.conf.Username, sm.conf.Password, sm.conf.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	sm.logger.Info(fmt.Sprintf("sent %s email", template))
+	return client.Quit()
+}
+
+// BuildMessage encodes email as a multipart/alternative MIME message.
+func BuildMessage(from, to *mail.Address, email *Email) ([]byte, error) {
+	var body bytes.Buffer
+
+	parts := multipart.NewWriter(&body)
+
+	for _, alternative := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(part)
+		if _, err = encoder.Write([]byte(alternative.content)); err != nil {
+			return nil, err
+		}
+
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to.String())
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
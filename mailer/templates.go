@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+)
+
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+var subjects = map[string]string{
+	TemplateVerifyEmail:   "Verify your email address",
+	TemplateResetPassword: "Reset your password",
+}
+
+//go:embed templates
+var templatesFS embed.FS
+
+// Email is a rendered email with a plain text and an HTML body.
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// LinkData is passed to the templates of emails carrying an action link.
+type LinkData struct {
+	Email string
+	Link  string
+}
+
+// Render renders the text and HTML versions of the named template.
+func Render(name string, data any) (*Email, error) {
+	subject, ok := subjects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	textTmpl, err := textTemplate.ParseFS(templatesFS, "templates/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmlTemplate.ParseFS(templatesFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return nil, err
+	}
+
+	var text, html bytes.Buffer
+
+	if err = textTmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	if err = htmlTmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -7,6 +7,13 @@ import "github.com/SamPariatIL/weather-wrapper/cmd"
 // @description This is a wrapper for the OpenWeatherMap API.
 // @host localhost:8181
 // @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Firebase ID token, prefixed with "Bearer "
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	cmd.RunServer()
 }
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Coalescing counters, per upstream endpoint.
+const (
+	// UpstreamFetches counts cache misses that called upstream.
+	UpstreamFetches = "upstream_fetches"
+	// CoalescedInProcess counts cache misses that waited for a fetch of the
+	// same request running in this process.
+	CoalescedInProcess = "coalesced_in_process"
+	// CoalescedDistributed counts cache misses that waited for a fetch of
+	// the same request holding the lock on another replica.
+	CoalescedDistributed = "coalesced_distributed"
+	// LockWaitTimeouts counts waits for another replica that gave up and
+	// called upstream anyway.
+	LockWaitTimeouts = "lock_wait_timeouts"
+	// LockErrors counts fetches made without the lock because Redis failed.
+	LockErrors = "lock_errors"
+)
+
+// Cache failures, per operation. Failed reads are served from upstream and
+// failed writes are skipped.
+const (
+	CacheRead  = "read"
+	CacheWrite = "write"
+)
+
+var (
+	mu          sync.Mutex
+	coalescing  = expvar.NewMap("coalescing")
+	cacheErrors = expvar.NewMap("cache_errors")
+)
+
+// CountCacheError adds one to the failures of operation.
+func CountCacheError(operation string) {
+	cacheErrors.Add(operation, 1)
+}
+
+// Count adds one to counter of endpoint.
+func Count(counter, endpoint string) {
+	endpointVar := coalescing.Get(endpoint)
+	if endpointVar == nil {
+		mu.Lock()
+		if endpointVar = coalescing.Get(endpoint); endpointVar == nil {
+			endpointVar = new(expvar.Map).Init()
+			coalescing.Set(endpoint, endpointVar)
+		}
+		mu.Unlock()
+	}
+
+	endpointVar.(*expvar.Map).Add(counter, 1)
+}
@@ -1 +1,150 @@
 package middlewares
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"slices"
+)
+
+type AuthMiddleware interface {
+	VerifyToken(ctx *fiber.Ctx) error
+	Authenticate(ctx *fiber.Ctx) error
+	RequireScope(scope string) fiber.Handler
+}
+
+type authMiddleware struct {
+	identityProvider identity.Provider
+	apiKeyService    services.APIKeyService
+	logger           *zap.Logger
+}
+
+func NewAuthMiddleware(ip identity.Provider, aks services.APIKeyService, zl *zap.Logger) AuthMiddleware {
+	return &authMiddleware{
+		identityProvider: ip,
+		apiKeyService:    aks,
+		logger:           zl,
+	}
+}
+
+// VerifyToken checks the ID token in the Authorization header and
+// stores the caller's uid and claims in the request locals. API keys are not
+// accepted, so it guards the endpoints that manage accounts.
+func (am *authMiddleware) VerifyToken(ctx *fiber.Ctx) error {
+	idToken, err := utils.ParseBearerToken(ctx.Get(fiber.HeaderAuthorization))
+	if err != nil {
+		am.logger.Warn(missingToken)
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, missingToken, err.Error()))
+	}
+
+	token, err := am.identityProvider.VerifyIDToken(ctx.UserContext(), idToken)
+	if err != nil {
+		am.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, invalidToken, err.Error()))
+	}
+
+	ctx.Locals(LocalsUID, token.UID)
+	ctx.Locals(LocalsClaims, token.Claims)
+
+	return ctx.Next()
+}
+
+// Authenticate accepts either an X-API-Key header or an ID token.
+// Requests made with an API key carry the key owner's uid, the key's scopes
+// and the owner's current plan, but no claims.
+func (am *authMiddleware) Authenticate(ctx *fiber.Ctx) error {
+	key := ctx.Get(HeaderAPIKey)
+	if key == "" {
+		return am.VerifyToken(ctx)
+	}
+
+	apiKey, ownerClaims, err := am.apiKeyService.AuthenticateAPIKey(ctx.UserContext(), key)
+	if err != nil {
+		am.logger.Warn(err.Error())
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, invalidAPIKey, err.Error()))
+	}
+
+	ctx.Locals(LocalsUID, apiKey.OwnerUID)
+	ctx.Locals(LocalsAPIKeyID, apiKey.ID)
+	ctx.Locals(LocalsScopes, []string(apiKey.Scopes))
+	if plan, ok := ownerClaims[ClaimPlan].(string); ok {
+		ctx.Locals(LocalsPlan, plan)
+	}
+
+	return ctx.Next()
+}
+
+// RequireScope restricts API key callers to keys holding scope. Callers
+// authenticated with an ID token are not limited by scopes.
+func (am *authMiddleware) RequireScope(scope string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if GetAPIKeyID(ctx) == "" {
+			return ctx.Next()
+		}
+
+		scopes, _ := ctx.Locals(LocalsScopes).([]string)
+		if slices.Contains(scopes, scope) {
+			return ctx.Next()
+		}
+
+		am.logger.Warn(fmt.Sprintf("forbidden: api key %s lacks scope %s", GetAPIKeyID(ctx), scope))
+		return ctx.Status(fiber.StatusForbidden).
+			JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, missingScope))
+	}
+}
+
+// GetUID returns the uid of the authenticated caller, or an empty string
+// if the request was not authenticated.
+func GetUID(ctx *fiber.Ctx) string {
+	uid, _ := ctx.Locals(LocalsUID).(string)
+	return uid
+}
+
+// GetClaims returns the token claims of the authenticated caller.
+func GetClaims(ctx *fiber.Ctx) map[string]interface{} {
+	claims, _ := ctx.Locals(LocalsClaims).(map[string]interface{})
+	return claims
+}
+
+// GetAPIKeyID returns the id of the API key used for the request, or an
+// empty string if the caller used an ID token.
+func GetAPIKeyID(ctx *fiber.Ctx) string {
+	apiKeyID, _ := ctx.Locals(LocalsAPIKeyID).(string)
+	return apiKeyID
+}
+
+// GetPlan returns the billing plan of the caller, taken from the plan claim
+// of the caller or of the owner of the API key, falling back to the default
+// plan.
+func GetPlan(ctx *fiber.Ctx) string {
+	if plan, ok := GetClaims(ctx)[ClaimPlan].(string); ok && plan != "" {
+		return plan
+	}
+
+	if plan, ok := ctx.Locals(LocalsPlan).(string); ok && plan != "" {
+		return plan
+	}
+
+	return config.GetConfig().PlanConfig.Default
+}
+
+// GetPrincipal identifies who is making the request: the API key if one was
+// used, otherwise the user, falling back to the client IP for anonymous calls.
+func GetPrincipal(ctx *fiber.Ctx) string {
+	if apiKeyID := GetAPIKeyID(ctx); apiKeyID != "" {
+		return "key:" + apiKeyID
+	}
+
+	if uid := GetUID(ctx); uid != "" {
+		return "uid:" + uid
+	}
+
+	return "ip:" + ctx.IP()
+}
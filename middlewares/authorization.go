@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type AuthorizationMiddleware interface {
+	RequireOwnerOrAdmin(ctx *fiber.Ctx) error
+}
+
+type authorizationMiddleware struct {
+	logger *zap.Logger
+}
+
+func NewAuthorizationMiddleware(zl *zap.Logger) AuthorizationMiddleware {
+	return &authorizationMiddleware{
+		logger: zl,
+	}
+}
+
+// RequireOwnerOrAdmin lets the request through only when the caller's uid
+// matches the :uid path parameter or the caller has the admin role.
+// It must run after AuthMiddleware.VerifyToken.
+func (azm *authorizationMiddleware) RequireOwnerOrAdmin(ctx *fiber.Ctx) error {
+	callerUID := GetUID(ctx)
+	targetUID := ctx.Params("uid")
+
+	if callerUID == "" {
+		azm.logger.Warn(missingToken)
+		return ctx.Status(fiber.StatusUnauthorized).
+			JSON(utils.CustomResponse(nil, fiber.StatusUnauthorized, missingToken, missingToken))
+	}
+
+	if callerUID == targetUID || IsAdmin(ctx) {
+		return ctx.Next()
+	}
+
+	azm.logger.Warn(fmt.Sprintf("forbidden: user %s tried to %s user %s", callerUID, ctx.Method(), targetUID))
+	return ctx.Status(fiber.StatusForbidden).
+		JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, notOwnerOrAdmin))
+}
+
+// IsAdmin reports whether the authenticated caller has the admin role.
+func IsAdmin(ctx *fiber.Ctx) bool {
+	return HasAnyRole(ctx, entities.RoleAdmin)
+}
@@ -0,0 +1,34 @@
+package middlewares
+
+const (
+	HeaderAPIKey = "X-API-Key"
+
+	LocalsUID      = "uid"
+	LocalsClaims   = "claims"
+	LocalsAPIKeyID = "apiKeyId"
+	LocalsScopes   = "scopes"
+	LocalsPlan     = "plan"
+
+	ClaimRoles = "roles"
+	ClaimPlan  = "plan"
+
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+const (
+	missingToken      = "missing or malformed bearer token"
+	invalidToken      = "invalid or expired token"
+	forbidden         = "forbidden"
+	notOwnerOrAdmin   = "only the account owner or an admin can perform this action"
+	missingRole       = "you do not have a role that can perform this action"
+	missingRolePolicy = "no access policy is declared for this route"
+	invalidAPIKey     = "invalid api key"
+	missingScope      = "the api key does not have the scope required by this route"
+	rateLimited       = "rate limit exceeded"
+	tryAgainLater     = "too many requests, try again later"
+
+	quotaExceeded        = "quota exceeded"
+	quotaExceededMessage = "the monthly quota of your plan is used up"
+)
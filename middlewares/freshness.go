@@ -0,0 +1,13 @@
+package middlewares
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrackFreshness lets the services of the route record whether the response
+// is served fresh, stale or as the last known good response.
+func TrackFreshness(ctx *fiber.Ctx) error {
+	ctx.SetUserContext(freshness.NewContext(ctx.UserContext()))
+	return ctx.Next()
+}
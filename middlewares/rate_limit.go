@@ -0,0 +1,129 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const rateLimitPrefix = "rate_limit"
+
+// slidingWindowScript keeps one sorted set member per request, scored by its
+// timestamp in milliseconds. It returns whether the request is allowed, how
+// many requests remain and, when rejected, how many milliseconds until the
+// oldest request leaves the window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", key, 0, now - window)
+local count = redis.call("ZCARD", key)
+
+if count < limit then
+	redis.call("ZADD", key, now, ARGV[4])
+	redis.call("PEXPIRE", key, window)
+	return {1, limit - count - 1, 0}
+end
+
+local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+type RateLimitMiddleware interface {
+	Limit(group string) fiber.Handler
+}
+
+type rateLimitMiddleware struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewRateLimitMiddleware(rc *redis.Client, zl *zap.Logger) RateLimitMiddleware {
+	return &rateLimitMiddleware{
+		redisClient: rc,
+		logger:      zl,
+	}
+}
+
+// Limit applies the sliding window limit configured for group and the
+// caller's plan. It should run after authentication so that callers are
+// keyed by uid or API key rather than by IP. Redis errors let the request
+// through rather than taking the API down.
+func (rlm *rateLimitMiddleware) Limit(group string) fiber.Handler {
+	conf := config.GetConfig().RateLimitConfig
+	defaultPlan := config.GetConfig().PlanConfig.Default
+
+	return func(ctx *fiber.Ctx) error {
+		if !conf.Enabled {
+			return ctx.Next()
+		}
+
+		limit, limited := rlm.limitFor(conf, group, GetPlan(ctx), defaultPlan)
+		if !limited {
+			return ctx.Next()
+		}
+
+		key := getRateLimitKey(group, GetPrincipal(ctx))
+		now := time.Now().UnixMilli()
+
+		result, err := slidingWindowScript.Run(
+			ctx.UserContext(),
+			rlm.redisClient,
+			[]string{key},
+			now,
+			conf.Window.Milliseconds(),
+			limit,
+			fmt.Sprintf("%d-%s", now, uuid.NewString()),
+		).Int64Slice()
+		if err != nil {
+			rlm.logger.Warn(fmt.Sprintf("rate limiter unavailable, allowing request: %s", err.Error()))
+			return ctx.Next()
+		}
+
+		allowed, remaining, retryAfterMs := result[0] == 1, result[1], result[2]
+
+		ctx.Set(HeaderRateLimitLimit, strconv.Itoa(limit))
+		ctx.Set(HeaderRateLimitRemaining, strconv.FormatInt(remaining, 10))
+
+		if allowed {
+			return ctx.Next()
+		}
+
+		retryAfter := strconv.FormatInt((retryAfterMs+999)/1000, 10)
+		ctx.Set(HeaderRateLimitReset, retryAfter)
+		ctx.Set(fiber.HeaderRetryAfter, retryAfter)
+
+		rlm.logger.Warn(fmt.Sprintf("rate limited %s on %s", GetPrincipal(ctx), group))
+		return ctx.Status(fiber.StatusTooManyRequests).
+			JSON(utils.CustomResponse(nil, fiber.StatusTooManyRequests, rateLimited, tryAgainLater))
+	}
+}
+
+// limitFor returns the limit of plan in group, falling back to the default
+// plan. The second value is false when the group has no limit at all.
+func (rlm *rateLimitMiddleware) limitFor(conf config.RateLimitConfig, group, plan, defaultPlan string) (int, bool) {
+	planLimits, exists := conf.Limits[group]
+	if !exists {
+		return 0, false
+	}
+
+	if limit, exists := planLimits[plan]; exists {
+		return limit, true
+	}
+
+	limit, exists := planLimits[defaultPlan]
+	return limit, exists
+}
+
+func getRateLimitKey(group, principal string) string {
+	return fmt.Sprintf("%s%s_%s_%s", repository.KeyNamespace, rateLimitPrefix, group, principal)
+}
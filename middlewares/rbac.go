@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
+)
+
+// RolePolicy maps a route, written as "METHOD /full/path", to the roles that
+// may call it. An empty role list allows any authenticated caller.
+type RolePolicy map[string][]string
+
+type RBACMiddleware interface {
+	Authorize(ctx *fiber.Ctx) error
+}
+
+type rbacMiddleware struct {
+	policy RolePolicy
+	logger *zap.Logger
+}
+
+func NewRBACMiddleware(policy RolePolicy, zl *zap.Logger) RBACMiddleware {
+	return &rbacMiddleware{
+		policy: policy,
+		logger: zl,
+	}
+}
+
+// Authorize looks up the matched route in the policy and checks the caller's
+// roles against it. Routes missing from the policy are denied, so every
+// protected endpoint has to be declared. It must run after
+// AuthMiddleware.VerifyToken and be registered on the route itself rather
+// than through Use, so that the matched route is known.
+func (rm *rbacMiddleware) Authorize(ctx *fiber.Ctx) error {
+	route := ctx.Route()
+	routePath := route.Path
+	if len(routePath) > 1 {
+		routePath = strings.TrimSuffix(routePath, "/")
+	}
+	routeKey := route.Method + " " + routePath
+
+	requiredRoles, declared := rm.policy[routeKey]
+	if !declared {
+		rm.logger.Error(fmt.Sprintf("no role policy declared for %s", routeKey))
+		return ctx.Status(fiber.StatusForbidden).
+			JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, missingRolePolicy))
+	}
+
+	if len(requiredRoles) == 0 || HasAnyRole(ctx, requiredRoles...) {
+		return ctx.Next()
+	}
+
+	rm.logger.Warn(fmt.Sprintf("forbidden: user %s lacks roles %v for %s", GetUID(ctx), requiredRoles, routeKey))
+	return ctx.Status(fiber.StatusForbidden).
+		JSON(utils.CustomResponse(nil, fiber.StatusForbidden, forbidden, missingRole))
+}
+
+// GetRoles returns the roles custom claim of the authenticated caller.
+func GetRoles(ctx *fiber.Ctx) []string {
+	rawRoles, _ := GetClaims(ctx)[ClaimRoles].([]interface{})
+
+	roles := make([]string, 0, len(rawRoles))
+	for _, rawRole := range rawRoles {
+		if role, ok := rawRole.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// HasAnyRole reports whether the authenticated caller has at least one of roles.
+func HasAnyRole(ctx *fiber.Ctx, roles ...string) bool {
+	callerRoles := GetRoles(ctx)
+
+	for _, role := range roles {
+		if slices.Contains(callerRoles, role) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/usage"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type UsageMiddleware interface {
+	Meter(ctx *fiber.Ctx) error
+}
+
+type usageMiddleware struct {
+	usageService services.UsageService
+	logger       *zap.Logger
+}
+
+func NewUsageMiddleware(us services.UsageService, zl *zap.Logger) UsageMiddleware {
+	return &usageMiddleware{
+		usageService: us,
+		logger:       zl,
+	}
+}
+
+// Meter enforces the monthly quota of the caller's plan and records a usage
+// event for the request. Quotas are charged to the user, so requests made
+// with an API key count against its owner.
+func (um *usageMiddleware) Meter(ctx *fiber.Ctx) error {
+	start := time.Now()
+	principal := GetPrincipal(ctx)
+	uid := GetUID(ctx)
+
+	subject := principal
+	if uid != "" {
+		subject = "uid:" + uid
+	}
+
+	allowed, err := um.usageService.ConsumeQuota(ctx.UserContext(), subject, GetPlan(ctx))
+	if err != nil {
+		um.logger.Warn(fmt.Sprintf("quota check unavailable, allowing request: %s", err.Error()))
+	}
+
+	if !allowed {
+		um.logger.Warn(fmt.Sprintf("monthly quota exceeded for %s", subject))
+		return ctx.Status(fiber.StatusTooManyRequests).
+			JSON(utils.CustomResponse(nil, fiber.StatusTooManyRequests, quotaExceeded, quotaExceededMessage))
+	}
+
+	event := usage.NewEvent(principal, uid, start)
+	ctx.SetUserContext(usage.NewContext(ctx.UserContext(), event))
+
+	err = ctx.Next()
+
+	statusCode := ctx.Response().StatusCode()
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		statusCode = fiberErr.Code
+	}
+
+	routePath := ctx.Route().Path
+	if len(routePath) > 1 {
+		routePath = strings.TrimSuffix(routePath, "/")
+	}
+
+	um.usageService.Record(event.Finish(routePath, statusCode, time.Since(start)))
+
+	return err
+}
@@ -2,158 +2,85 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
-	"github.com/redis/go-redis/v9"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
 	"go.uber.org/zap"
 	"time"
 )
 
+const (
+	currentAirPollutionPrefix    = "current_air_pollution"
+	airPollutionForecastPrefix   = "air_pollution_forecast"
+	historicalAirPollutionPrefix = "historical_air_pollution"
+)
+
 type AirPollutionRepository interface {
-	GetCurrentAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error)
-	GetAirPollutionForecast(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error)
-	GetHistoricalAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error)
-	SetCurrentAirPollution(ctx context.Context, latitude, longitude float32, airPollution *entities.AirPollution) error
-	SetAirPollutionForecast(ctx context.Context, latitude, longitude float32, airPollutionForecast *entities.AirPollution) error
-	SetHistoricalAirPollution(ctx context.Context, latitude, longitude float32, historicalAirPollution *entities.AirPollution) error
+	GetCurrentAirPollution(ctx context.Context, location geo.Location) (*CacheEntry[entities.AirPollution], error)
+	GetAirPollutionForecast(ctx context.Context, location geo.Location) (*CacheEntry[entities.AirPollution], error)
+	// GetHistory returns the stored datapoints of location from start to
+	// end, and the sub-ranges of it that have to be fetched from upstream.
+	GetHistory(ctx context.Context, location geo.Location, start, end int64) (*HistoryWindow, error)
+	SetCurrentAirPollution(ctx context.Context, location geo.Location, airPollution *entities.AirPollution) error
+	SetAirPollutionForecast(ctx context.Context, location geo.Location, airPollutionForecast *entities.AirPollution) error
+	// AddHistory stores the datapoints fetched from upstream for location
+	// from start to end, replacing stored ones with the same dt.
+	AddHistory(ctx context.Context, location geo.Location, start, end int64, datapoints []entities.AirPollutionDatapoint) error
 }
 
 type airPollutionRepository struct {
-	redisClient *redis.Client
-	logger      *zap.Logger
+	cache  cache.Cache
+	conf   config.CacheConfig
+	logger *zap.Logger
 }
 
-func NewAirPollutionRepository(rc *redis.Client, zl *zap.Logger) AirPollutionRepository {
+func NewAirPollutionRepository(c cache.Cache, conf config.CacheConfig, zl *zap.Logger) AirPollutionRepository {
 	return &airPollutionRepository{
-		redisClient: rc,
-		logger:      zl,
+		cache:  c,
+		conf:   conf,
+		logger: zl,
 	}
 }
 
-func (ar *airPollutionRepository) GetCurrentAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error) {
-	key := getCurrentAirPollutionKey(latitude, longitude)
-
-	airPollutionJSON, err := ar.redisClient.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	var airPoll entities.AirPollution
-
-	err = json.Unmarshal([]byte(airPollutionJSON), &airPoll)
-	if err != nil {
+func (ar *airPollutionRepository) GetCurrentAirPollution(ctx context.Context, location geo.Location) (*CacheEntry[entities.AirPollution], error) {
+	entry, err := getCellEntry[entities.AirPollution](ctx, ar.cache, currentAirPollutionPrefix, location, ar.conf.ReadLegacyKeys, ttlFor(ar.conf.TTLPolicies, upstream.CurrentAirPollution, time.Now(), time.Time{}))
+	if entry == nil || err != nil {
 		return nil, err
 	}
 
-	ar.logger.Info(fmt.Sprintf("fetched cached air pollution for %f, %f", latitude, longitude))
-	return &airPoll, nil
+	ar.logger.Info(fmt.Sprintf("fetched cached air pollution for %s", location.Cell))
+	return entry, nil
 }
 
-func (ar *airPollutionRepository) GetAirPollutionForecast(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error) {
-	key := getAirPollutionForecastKey(latitude, longitude)
-
-	airPollutionForecastJSON, err := ar.redisClient.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	var airPollForecast entities.AirPollution
-
-	err = json.Unmarshal([]byte(airPollutionForecastJSON), &airPollForecast)
-	if err != nil {
+func (ar *airPollutionRepository) GetAirPollutionForecast(ctx context.Context, location geo.Location) (*CacheEntry[entities.AirPollution], error) {
+	entry, err := getCellEntry[entities.AirPollution](ctx, ar.cache, airPollutionForecastPrefix, location, ar.conf.ReadLegacyKeys, ttlFor(ar.conf.TTLPolicies, upstream.AirPollutionForecast, time.Now(), time.Time{}))
+	if entry == nil || err != nil {
 		return nil, err
 	}
 
-	ar.logger.Info(fmt.Sprintf("fetched cached air pollution forecast for %f, %f", latitude, longitude))
-	return &airPollForecast, nil
+	ar.logger.Info(fmt.Sprintf("fetched cached air pollution forecast for %s", location.Cell))
+	return entry, nil
 }
 
-func (ar *airPollutionRepository) GetHistoricalAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error) {
-	key := getHistoricalAirPollutionKey(latitude, longitude)
-
-	historicalAirPollutionJSON, err := ar.redisClient.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	var historicalAirPollution entities.AirPollution
-
-	err = json.Unmarshal([]byte(historicalAirPollutionJSON), &historicalAirPollution)
-	if err != nil {
-		return nil, err
-	}
-
-	ar.logger.Info(fmt.Sprintf("fetched cached historical air pollution for %f, %f", latitude, longitude))
-	return &historicalAirPollution, nil
-}
-
-func (ar *airPollutionRepository) SetCurrentAirPollution(ctx context.Context, latitude, longitude float32, airPollution *entities.AirPollution) error {
-	key := getCurrentAirPollutionKey(latitude, longitude)
-
-	airPollutionJSON, err := json.Marshal(airPollution)
+func (ar *airPollutionRepository) SetCurrentAirPollution(ctx context.Context, location geo.Location, airPollution *entities.AirPollution) error {
+	err := setCellEntry(ctx, ar.cache, currentAirPollutionPrefix, location, airPollution, ttlFor(ar.conf.TTLPolicies, upstream.CurrentAirPollution, time.Now(), time.Time{}))
 	if err != nil {
 		return err
 	}
 
-	err = ar.redisClient.Set(ctx, key, airPollutionJSON, time.Minute*5).Err()
-	if err != nil {
-		return err
-	}
-
-	ar.logger.Info(fmt.Sprintf("saved current air pollution for %f, %f", latitude, longitude))
-	return nil
-}
-
-func (ar *airPollutionRepository) SetAirPollutionForecast(ctx context.Context, latitude, longitude float32, airPollutionForecast *entities.AirPollution) error {
-	key := getAirPollutionForecastKey(latitude, longitude)
-
-	airPollutionForecastJSON, err := json.Marshal(airPollutionForecast)
-	if err != nil {
-		return err
-	}
-
-	err = ar.redisClient.Set(ctx, key, airPollutionForecastJSON, time.Minute*5).Err()
-	if err != nil {
-		return err
-	}
-
-	ar.logger.Info(fmt.Sprintf("saved air pollution forecast for %f, %f", latitude, longitude))
+	ar.logger.Info(fmt.Sprintf("saved current air pollution for %s", location.Cell))
 	return nil
 }
 
-func (ar *airPollutionRepository) SetHistoricalAirPollution(ctx context.Context, latitude, longitude float32, historicalAirPollution *entities.AirPollution) error {
-	key := getHistoricalAirPollutionKey(latitude, longitude)
-
-	historicalAirPollutionJSON, err := json.Marshal(historicalAirPollution)
-	if err != nil {
-		return err
-	}
-
-	err = ar.redisClient.Set(ctx, key, historicalAirPollutionJSON, time.Minute*5).Err()
+func (ar *airPollutionRepository) SetAirPollutionForecast(ctx context.Context, location geo.Location, airPollutionForecast *entities.AirPollution) error {
+	err := setCellEntry(ctx, ar.cache, airPollutionForecastPrefix, location, airPollutionForecast, ttlFor(ar.conf.TTLPolicies, upstream.AirPollutionForecast, time.Now(), time.Time{}))
 	if err != nil {
 		return err
 	}
 
-	ar.logger.Info(fmt.Sprintf("saved historical air pollution for %f, %f", latitude, longitude))
+	ar.logger.Info(fmt.Sprintf("saved air pollution forecast for %s", location.Cell))
 	return nil
 }
-
-func getCurrentAirPollutionKey(lat, lon float32) string {
-	return fmt.Sprintf("current_air_pollution_%f_%f", lat, lon)
-}
-
-func getAirPollutionForecastKey(lat, lon float32) string {
-	return fmt.Sprintf("air_pollution_forecast_%f_%f", lat, lon)
-}
-
-func getHistoricalAirPollutionKey(lat, lon float32) string {
-	return fmt.Sprintf("historical_air_pollution_%f_%f", lat, lon)
-}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"slices"
+	"time"
+)
+
+// historyBucketSeconds is the span of the buckets the history of a location
+// is stored in, one UTC day.
+const historyBucketSeconds = 24 * 60 * 60
+
+// historyStepSeconds is the interval of the history datapoints, which
+// upstream reports on the hour.
+const historyStepSeconds = 60 * 60
+
+// TimeRange is a closed range of Unix seconds.
+type TimeRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// HistoryWindow is the stored history of a location within a time range.
+type HistoryWindow struct {
+	// Datapoints are sorted by dt.
+	Datapoints []entities.AirPollutionDatapoint
+	// Missing are the sorted sub-ranges that were never fetched, or whose
+	// fetch has expired.
+	Missing []TimeRange
+	// FetchedAt is when the oldest bucket read was last written, and
+	// StaleAt when the first of the fetched ranges read expires. Both are
+	// zero when nothing was stored.
+	FetchedAt time.Time
+	StaleAt   time.Time
+}
+
+// historyBucket holds the datapoints of a location within one UTC day and
+// the ranges of the day that were fetched from upstream.
+type historyBucket struct {
+	Fetched    []fetchedRange                   `json:"fetched"`
+	Datapoints []entities.AirPollutionDatapoint `json:"datapoints"`
+}
+
+// fetchedRange is a range fetched from upstream, to be fetched again after
+// ExpiresAt, in Unix seconds.
+type fetchedRange struct {
+	TimeRange
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func (ar *airPollutionRepository) GetHistory(ctx context.Context, location geo.Location, start, end int64) (*HistoryWindow, error) {
+	now := time.Now()
+	window := &HistoryWindow{}
+
+	var fetched []TimeRange
+
+	entries, err := getEntries[historyBucket](ctx, ar.cache, getHistoryBucketKeys(location, start, end))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		if window.FetchedAt.IsZero() || entry.FetchedAt.Before(window.FetchedAt) {
+			window.FetchedAt = entry.FetchedAt
+		}
+
+		for _, fetchedRange := range entry.Value.Fetched {
+			if fetchedRange.ExpiresAt <= now.Unix() || fetchedRange.End < start || fetchedRange.Start > end {
+				continue
+			}
+
+			fetched = append(fetched, fetchedRange.TimeRange)
+
+			expiresAt := time.Unix(fetchedRange.ExpiresAt, 0)
+			if window.StaleAt.IsZero() || expiresAt.Before(window.StaleAt) {
+				window.StaleAt = expiresAt
+			}
+		}
+
+		for _, datapoint := range entry.Value.Datapoints {
+			if int64(datapoint.Dt) >= start && int64(datapoint.Dt) <= end {
+				window.Datapoints = append(window.Datapoints, datapoint)
+			}
+		}
+	}
+
+	window.Missing = hourlyRanges(subtractRanges(TimeRange{Start: start, End: end}, fetched))
+
+	ar.logger.Info(fmt.Sprintf("fetched %d cached historical air pollution datapoints for %s from %d to %d, %d ranges missing",
+		len(window.Datapoints), location.Cell, start, end, len(window.Missing)))
+	return window, nil
+}
+
+// AddHistory merges the datapoints into the bucket of every day the range
+// spans, reading and then writing all the buckets at once. Concurrent writes
+// to a bucket may drop one of the fetched ranges, which is then fetched
+// again.
+func (ar *airPollutionRepository) AddHistory(ctx context.Context, location geo.Location, start, end int64, datapoints []entities.AirPollutionDatapoint) error {
+	now := time.Now()
+
+	keys := getHistoryBucketKeys(location, start, end)
+
+	entries, err := getEntries[historyBucket](ctx, ar.cache, keys)
+	if err != nil {
+		return err
+	}
+
+	items := make([]cache.Item, len(keys))
+
+	for i, entry := range entries {
+		day := start/historyBucketSeconds + int64(i)
+		dayRange := TimeRange{
+			Start: max(start, day*historyBucketSeconds),
+			End:   min(end, (day+1)*historyBucketSeconds-1),
+		}
+
+		var bucket historyBucket
+		if entry != nil {
+			bucket = entry.Value
+		}
+
+		// Ranges ending before the current hour are immutable and kept for
+		// the retention of the policy; later ones expire after its soft TTL.
+		rangeTTL := ttlFor(ar.conf.TTLPolicies, upstream.HistoricalAirPollution, now, time.Unix(dayRange.End, 0))
+
+		bucket.Fetched = slices.DeleteFunc(bucket.Fetched, func(fetchedRange fetchedRange) bool {
+			return fetchedRange.ExpiresAt <= now.Unix()
+		})
+		bucket.Fetched = append(bucket.Fetched, fetchedRange{TimeRange: dayRange, ExpiresAt: now.Add(rangeTTL.Soft).Unix()})
+
+		bucket.Datapoints = mergeDatapoints(bucket.Datapoints, datapoints, dayRange)
+
+		var lastFetched int64
+		for _, fetchedRange := range bucket.Fetched {
+			lastFetched = max(lastFetched, fetchedRange.End)
+		}
+
+		bucketTTL := ttlFor(ar.conf.TTLPolicies, upstream.HistoricalAirPollution, now, time.Unix(lastFetched, 0))
+
+		items[i], err = entryItem(keys[i], &bucket, bucketTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ar.cache.MSet(ctx, items...)
+	if err != nil {
+		return err
+	}
+
+	ar.logger.Info(fmt.Sprintf("saved %d historical air pollution datapoints for %s from %d to %d", len(datapoints), location.Cell, start, end))
+	return nil
+}
+
+// getHistoryBucketKeys returns the keys of the buckets of every day from
+// start to end.
+func getHistoryBucketKeys(location geo.Location, start, end int64) []string {
+	var keys []string
+
+	for day := start / historyBucketSeconds; day <= end/historyBucketSeconds; day++ {
+		date := time.Unix(day*historyBucketSeconds, 0).UTC().Format("20060102")
+		keys = append(keys, getCellKey(historicalAirPollutionPrefix+"_"+date, location))
+	}
+
+	return keys
+}
+
+// mergeDatapoints adds the datapoints of added within dayRange to stored,
+// replacing those with the same dt, and sorts them by dt.
+func mergeDatapoints(stored, added []entities.AirPollutionDatapoint, dayRange TimeRange) []entities.AirPollutionDatapoint {
+	byDt := make(map[int]entities.AirPollutionDatapoint, len(stored)+len(added))
+
+	for _, datapoint := range stored {
+		byDt[datapoint.Dt] = datapoint
+	}
+
+	for _, datapoint := range added {
+		if int64(datapoint.Dt) >= dayRange.Start && int64(datapoint.Dt) <= dayRange.End {
+			byDt[datapoint.Dt] = datapoint
+		}
+	}
+
+	merged := make([]entities.AirPollutionDatapoint, 0, len(byDt))
+	for _, datapoint := range byDt {
+		merged = append(merged, datapoint)
+	}
+
+	slices.SortFunc(merged, func(a, b entities.AirPollutionDatapoint) int {
+		return cmp.Compare(a.Dt, b.Dt)
+	})
+
+	return merged
+}
+
+// subtractRanges returns the sorted sub-ranges of whole covered by none of
+// ranges.
+func subtractRanges(whole TimeRange, ranges []TimeRange) []TimeRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b TimeRange) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+
+	var missing []TimeRange
+	next := whole.Start
+
+	for _, covered := range sorted {
+		if next > whole.End {
+			break
+		}
+
+		if covered.Start > next {
+			missing = append(missing, TimeRange{Start: next, End: min(covered.Start-1, whole.End)})
+		}
+
+		next = max(next, covered.End+1)
+	}
+
+	if next <= whole.End {
+		missing = append(missing, TimeRange{Start: next, End: whole.End})
+	}
+
+	return missing
+}
+
+// hourlyRanges shrinks the ranges to the hours they contain, dropping those
+// that contain none, since there can be no datapoints in between, and merges
+// those left with only an hour between them.
+func hourlyRanges(ranges []TimeRange) []TimeRange {
+	var hourly []TimeRange
+
+	for _, timeRange := range ranges {
+		start := (timeRange.Start + historyStepSeconds - 1) / historyStepSeconds * historyStepSeconds
+		end := timeRange.End / historyStepSeconds * historyStepSeconds
+
+		if start > end {
+			continue
+		}
+
+		if len(hourly) > 0 && start-hourly[len(hourly)-1].End <= historyStepSeconds {
+			hourly[len(hourly)-1].End = end
+			continue
+		}
+
+		hourly = append(hourly, TimeRange{Start: start, End: end})
+	}
+
+	return hourly
+}
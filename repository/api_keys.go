@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, apiKey *entities.APIKey) error
+	ListAPIKeys(ctx context.Context, ownerUID string) ([]entities.APIKey, error)
+	GetAPIKey(ctx context.Context, ownerUID, id string) (*entities.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*entities.APIKey, error)
+	RotateAPIKey(ctx context.Context, ownerUID, id, prefix, keyHash string) (*entities.APIKey, error)
+	RevokeAPIKey(ctx context.Context, ownerUID, id string) (*entities.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	postgresDB *gorm.DB
+	logger     *zap.Logger
+}
+
+func NewAPIKeyRepository(db *gorm.DB, zl *zap.Logger) APIKeyRepository {
+	return &apiKeyRepository{
+		postgresDB: db,
+		logger:     zl,
+	}
+}
+
+func (akr *apiKeyRepository) CreateAPIKey(ctx context.Context, apiKey *entities.APIKey) error {
+	err := akr.postgresDB.WithContext(ctx).Create(apiKey).Error
+	if err != nil {
+		return err
+	}
+
+	akr.logger.Info(fmt.Sprintf("created api key %s for user %s", apiKey.ID, apiKey.OwnerUID))
+	return nil
+}
+
+func (akr *apiKeyRepository) ListAPIKeys(ctx context.Context, ownerUID string) ([]entities.APIKey, error) {
+	var apiKeys []entities.APIKey
+
+	err := akr.postgresDB.WithContext(ctx).
+		Where("owner_uid = ?", ownerUID).
+		Order("created_at DESC").
+		Find(&apiKeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (akr *apiKeyRepository) GetAPIKey(ctx context.Context, ownerUID, id string) (*entities.APIKey, error) {
+	var apiKey entities.APIKey
+
+	err := akr.postgresDB.WithContext(ctx).
+		Where("id = ? AND owner_uid = ?", id, ownerUID).
+		First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (akr *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*entities.APIKey, error) {
+	var apiKey entities.APIKey
+
+	err := akr.postgresDB.WithContext(ctx).
+		Where("key_hash = ?", keyHash).
+		First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (akr *apiKeyRepository) RotateAPIKey(ctx context.Context, ownerUID, id, prefix, keyHash string) (*entities.APIKey, error) {
+	apiKey, err := akr.GetAPIKey(ctx, ownerUID, id)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = keyHash
+	apiKey.LastUsedAt = nil
+
+	err = akr.postgresDB.WithContext(ctx).Save(apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	akr.logger.Info(fmt.Sprintf("rotated api key %s for user %s", id, ownerUID))
+	return apiKey, nil
+}
+
+func (akr *apiKeyRepository) RevokeAPIKey(ctx context.Context, ownerUID, id string) (*entities.APIKey, error) {
+	apiKey, err := akr.GetAPIKey(ctx, ownerUID, id)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		apiKey.RevokedAt = &now
+
+		err = akr.postgresDB.WithContext(ctx).Save(apiKey).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	akr.logger.Info(fmt.Sprintf("revoked api key %s for user %s", id, ownerUID))
+	return apiKey, nil
+}
+
+func (akr *apiKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	return akr.postgresDB.WithContext(ctx).
+		Model(&entities.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"go.uber.org/zap"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyNamespace prefixes every key the service writes, rate limits and usage
+// quotas included, so purges never touch other applications sharing the
+// Redis DB. Its version is bumped when
+// the format of keys or entries changes, leaving the old entries to expire.
+const KeyNamespace = "weather-wrapper:v1:"
+
+// LastKnownResource names the last known good responses kept by the
+// FallbackRepository; the other resources are named after their endpoint.
+const LastKnownResource = "last_known"
+
+// deleteBatchSize bounds the keys removed by a single delete.
+const deleteBatchSize = 500
+
+// cacheResources maps the resources of the cache admin API to the prefix of
+// their keys.
+var cacheResources = map[string]string{
+	string(upstream.CurrentWeather):         currentWeatherPrefix,
+	string(upstream.WeatherForecast):        fiveDayWeatherPrefix,
+	string(upstream.CurrentAirPollution):    currentAirPollutionPrefix,
+	string(upstream.AirPollutionForecast):   airPollutionForecastPrefix,
+	string(upstream.HistoricalAirPollution): historicalAirPollutionPrefix,
+	string(upstream.GeocodeDirect):          geocodePrefix,
+	string(upstream.GeocodeReverse):         reverseGeocodePrefix,
+	LastKnownResource:                       lastKnownPrefix,
+}
+
+// CacheFilter selects cached entries. The zero value selects all of them.
+type CacheFilter struct {
+	// Resource selects the entries of one resource.
+	Resource string
+	// Box selects the entries whose cell, or the coordinates of a last
+	// known response, overlap it. Entries without a location never do.
+	Box *geo.Box
+}
+
+// CacheAdminRepository inspects and removes the entries in the key namespace
+// of the service. Entries removed from a tiered cache stay in the local tier
+// of other replicas for up to its TTL.
+type CacheAdminRepository interface {
+	// ListEntries returns up to limit entries, sorted by key, and reports
+	// whether more matched.
+	ListEntries(ctx context.Context, filter CacheFilter, limit int) ([]entities.CacheEntryInfo, bool, error)
+	// GetEntry returns the entry under key, or nil when there is none in
+	// the namespace.
+	GetEntry(ctx context.Context, key string) (*entities.CacheEntryInfo, error)
+	// Purge removes the entries and returns how many it found.
+	Purge(ctx context.Context, filter CacheFilter) (int, error)
+	// Flush removes every key in the namespace, locks and rate limits
+	// included, and returns how many it found. Usage quotas are kept, as
+	// they count what was billed rather than cache.
+	Flush(ctx context.Context) (int, error)
+}
+
+type cacheAdminRepository struct {
+	cache  cache.Cache
+	logger *zap.Logger
+}
+
+func NewCacheAdminRepository(c cache.Cache, zl *zap.Logger) CacheAdminRepository {
+	return &cacheAdminRepository{
+		cache:  c,
+		logger: zl,
+	}
+}
+
+func (car *cacheAdminRepository) ListEntries(ctx context.Context, filter CacheFilter, limit int) ([]entities.CacheEntryInfo, bool, error) {
+	keys, err := car.findKeys(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]entities.CacheEntryInfo, 0, min(len(keys), limit))
+
+	for i, key := range keys {
+		if len(entries) == limit {
+			return entries, i < len(keys), nil
+		}
+
+		entry, err := car.GetEntry(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The entry expired since the scan.
+		if entry == nil {
+			continue
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	return entries, false, nil
+}
+
+func (car *cacheAdminRepository) GetEntry(ctx context.Context, key string) (*entities.CacheEntryInfo, error) {
+	if !strings.HasPrefix(key, KeyNamespace) {
+		return nil, nil
+	}
+
+	value, err := car.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ttl, err := car.cache.TTL(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	info := &entities.CacheEntryInfo{Key: key, TTL: -1}
+	if ttl > 0 {
+		info.TTL = int64(ttl / time.Second)
+	}
+
+	info.Resource = keyResource(key)
+	if info.Resource != "" {
+		cell, box, ok := keyLocation(info.Resource, key)
+		if ok {
+			lat, lon := box.Center()
+			info.Cell, info.Lat, info.Lon = cell, &lat, &lon
+		}
+	}
+
+	// Locks are not entries and have no fetch time.
+	var entry CacheEntry[json.RawMessage]
+	if json.Unmarshal(value, &entry) == nil && !entry.FetchedAt.IsZero() {
+		info.FetchedAt, info.StaleAt = &entry.FetchedAt, &entry.StaleAt
+		info.Age = int64(time.Since(entry.FetchedAt) / time.Second)
+		info.Stale = entry.IsStale()
+	}
+
+	return info, nil
+}
+
+func (car *cacheAdminRepository) Purge(ctx context.Context, filter CacheFilter) (int, error) {
+	keys, err := car.findKeys(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	err = car.delete(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	car.logger.Info(fmt.Sprintf("purged %d cache entries", len(keys)))
+	return len(keys), nil
+}
+
+func (car *cacheAdminRepository) Flush(ctx context.Context) (int, error) {
+	keys, err := car.cache.Scan(ctx, KeyNamespace+"*")
+	if err != nil {
+		return 0, err
+	}
+
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return strings.HasPrefix(key, KeyNamespace+usageQuotaPrefix+"_")
+	})
+
+	err = car.delete(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	car.logger.Info(fmt.Sprintf("flushed %d cache keys", len(keys)))
+	return len(keys), nil
+}
+
+// findKeys returns the sorted keys of the entries selected by filter.
+func (car *cacheAdminRepository) findKeys(ctx context.Context, filter CacheFilter) ([]string, error) {
+	resources := []string{filter.Resource}
+	if filter.Resource == "" {
+		resources = resources[:0]
+		for resource := range cacheResources {
+			resources = append(resources, resource)
+		}
+	}
+
+	var keys []string
+
+	for _, resource := range resources {
+		matches, err := car.cache.Scan(ctx, KeyNamespace+cacheResources[resource]+"_*")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range matches {
+			if filter.Box != nil {
+				_, box, ok := keyLocation(resource, key)
+				if !ok || !box.Intersects(*filter.Box) {
+					continue
+				}
+			}
+
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (car *cacheAdminRepository) delete(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		err := car.cache.Delete(ctx, keys[start:min(start+deleteBatchSize, len(keys))]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyResource returns the resource of a namespaced key, or "" for keys that
+// are not entries, such as locks.
+func keyResource(key string) string {
+	rest := strings.TrimPrefix(key, KeyNamespace)
+
+	for resource, prefix := range cacheResources {
+		if strings.HasPrefix(rest, prefix+"_") {
+			return resource
+		}
+	}
+
+	return ""
+}
+
+// keyLocation returns the cell of the key of an entry of resource, or the
+// coordinates of a last known response as a box without area. Keys are read
+// in the layout they are written in: "<prefix>_<cell>" by getCellKey, with
+// the day of a history bucket before the cell.
+func keyLocation(resource, key string) (string, geo.Box, bool) {
+	cell := strings.TrimPrefix(key, KeyNamespace+cacheResources[resource]+"_")
+
+	switch resource {
+	case string(upstream.GeocodeDirect):
+		return "", geo.Box{}, false
+	case LastKnownResource:
+		return lastKnownLocation(cell)
+	case string(upstream.HistoricalAirPollution):
+		_, cell, _ = strings.Cut(cell, "_")
+	}
+
+	box, ok := geo.CellBox(cell)
+	return cell, box, ok
+}
+
+// lastKnownLocation reads the coordinates from the params of a last known
+// response, keyed "<endpoint>_<params>".
+func lastKnownLocation(rest string) (string, geo.Box, bool) {
+	for resource := range cacheResources {
+		paramsString, found := strings.CutPrefix(rest, resource+"_")
+		if !found {
+			continue
+		}
+
+		params, err := url.ParseQuery(paramsString)
+		if err != nil {
+			return "", geo.Box{}, false
+		}
+
+		lat, latErr := strconv.ParseFloat(params.Get("lat"), 64)
+		lon, lonErr := strconv.ParseFloat(params.Get("lon"), 64)
+		if latErr != nil || lonErr != nil {
+			return "", geo.Box{}, false
+		}
+
+		return "", geo.Box{MinLat: lat, MinLon: lon, MaxLat: lat, MaxLon: lon}, true
+	}
+
+	return "", geo.Box{}, false
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"time"
+)
+
+// CacheTTL is how long a cached value is served. Until Soft has passed the
+// value is fresh; after that it is stale, still served but due for a
+// refresh, until Hard has passed and the entry is gone.
+type CacheTTL struct {
+	Soft time.Duration
+	Hard time.Duration
+}
+
+// CacheEntry is a cached value with the time it was fetched from upstream.
+type CacheEntry[T any] struct {
+	Value     T         `json:"value"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	StaleAt   time.Time `json:"staleAt"`
+}
+
+// IsStale reports whether the soft TTL of the entry has passed.
+func (ce *CacheEntry[T]) IsStale() bool {
+	return !time.Now().Before(ce.StaleAt)
+}
+
+// getEntry returns the entry under key, or nil when there is none. Values
+// cached before entries carried their fetch time are treated as missing.
+func getEntry[T any](ctx context.Context, c cache.Cache, key string) (*CacheEntry[T], error) {
+	entryJSON, err := c.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry[T]
+
+	err = json.Unmarshal(entryJSON, &entry)
+	if err != nil || entry.FetchedAt.IsZero() {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// getEntries returns the entries of keys in one read, in their order, with
+// nil for the keys that are missing or hold no entry.
+func getEntries[T any](ctx context.Context, c cache.Cache, keys []string) ([]*CacheEntry[T], error) {
+	entriesJSON, err := c.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*CacheEntry[T], len(keys))
+	for i, entryJSON := range entriesJSON {
+		if entryJSON == nil {
+			continue
+		}
+
+		var entry CacheEntry[T]
+
+		err = json.Unmarshal(entryJSON, &entry)
+		if err == nil && !entry.FetchedAt.IsZero() {
+			entries[i] = &entry
+		}
+	}
+
+	return entries, nil
+}
+
+// setEntry caches value under key as fetched now, until the hard TTL.
+func setEntry[T any](ctx context.Context, c cache.Cache, key string, value *T, ttl CacheTTL) error {
+	item, err := entryItem(key, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	return c.Set(ctx, item.Key, item.Value, item.TTL)
+}
+
+// entryItem returns the item caching value under key as fetched now, until
+// the hard TTL, for writing along with others.
+func entryItem[T any](key string, value *T, ttl CacheTTL) (cache.Item, error) {
+	now := time.Now()
+
+	entryJSON, err := json.Marshal(CacheEntry[T]{
+		Value:     *value,
+		FetchedAt: now,
+		StaleAt:   now.Add(ttl.Soft),
+	})
+	if err != nil {
+		return cache.Item{}, err
+	}
+
+	return cache.Item{Key: key, Value: entryJSON, TTL: ttl.Hard}, nil
+}
+
+// getCellEntry returns the entry of the cell of location. With readLegacy, a
+// miss falls back to the key of the requested coordinates used before
+// snapping and namespacing, and an entry found there is copied to the cell
+// for the rest of its TTL. ttl is the TTL entries of the cell are set with.
+func getCellEntry[T any](ctx context.Context, c cache.Cache, prefix string, location geo.Location, readLegacy bool, ttl CacheTTL) (*CacheEntry[T], error) {
+	key := getCellKey(prefix, location)
+
+	entry, err := getEntry[T](ctx, c, key)
+	if entry != nil || err != nil || !readLegacy {
+		return entry, err
+	}
+
+	legacyKey := fmt.Sprintf("%s_%f_%f", prefix, float32(location.RequestedLat), float32(location.RequestedLon))
+
+	legacyJSON, err := c.Get(ctx, legacyKey)
+	if errors.Is(err, cache.ErrMiss) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	remaining, err := c.TTL(ctx, legacyKey)
+	if err != nil {
+		remaining = 0
+	}
+
+	entry = decodeLegacyEntry[T](legacyJSON, ttl, remaining)
+	if entry == nil || remaining <= 0 {
+		return entry, nil
+	}
+
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return entry, nil
+	}
+
+	// Several legacy keys may snap to the cell; the first one copied wins.
+	_, _ = c.SetNX(ctx, key, entryJSON, remaining)
+
+	return entry, nil
+}
+
+// decodeLegacyEntry decodes the value of a legacy key. Those hold an entry,
+// or the bare value when they were cached before entries carried their fetch
+// time, which is taken as fetched a hard TTL before it expires and is
+// already stale. It returns nil when the value cannot be decoded.
+func decodeLegacyEntry[T any](legacyJSON []byte, ttl CacheTTL, remaining time.Duration) *CacheEntry[T] {
+	var entry CacheEntry[T]
+	if err := json.Unmarshal(legacyJSON, &entry); err == nil && !entry.FetchedAt.IsZero() {
+		return &entry
+	}
+
+	var value T
+	if err := json.Unmarshal(legacyJSON, &value); err != nil {
+		// Cities were cached as plain strings rather than JSON.
+		text, ok := any(&value).(*string)
+		if !ok {
+			return nil
+		}
+
+		*text = string(legacyJSON)
+	}
+
+	now := time.Now()
+
+	return &CacheEntry[T]{
+		Value:     value,
+		FetchedAt: now.Add(min(max(remaining, 0)-ttl.Hard, 0)),
+		StaleAt:   now,
+	}
+}
+
+func setCellEntry[T any](ctx context.Context, c cache.Cache, prefix string, location geo.Location, value *T, ttl CacheTTL) error {
+	return setEntry(ctx, c, getCellKey(prefix, location), value, ttl)
+}
+
+func getCellKey(prefix string, location geo.Location) string {
+	return KeyNamespace + prefix + "_" + location.Cell
+}
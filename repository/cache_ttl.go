@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"time"
+)
+
+// defaultTTL applies to endpoints without a TTL policy.
+var defaultTTL = CacheTTL{Soft: time.Minute * 5, Hard: time.Hour}
+
+// ttlFor returns the TTL of a response of endpoint fetched at now. end is the
+// end of the time range the response covers, zero for responses without one.
+func ttlFor(policies map[string]config.TTLPolicy, endpoint upstream.Endpoint, now, end time.Time) CacheTTL {
+	policy, ok := policies[string(endpoint)]
+	if !ok {
+		return defaultTTL
+	}
+
+	switch policy.Policy {
+	case config.TTLForecastBoundary:
+		untilBoundary := policy.Interval - time.Duration(now.UnixNano()%int64(policy.Interval))
+		return CacheTTL{Soft: untilBoundary, Hard: untilBoundary + policy.Hard}
+	case config.TTLImmutablePast:
+		if !end.IsZero() && end.Before(now.Truncate(time.Hour)) {
+			return CacheTTL{Soft: policy.Retention, Hard: policy.Retention}
+		}
+	}
+
+	return CacheTTL{Soft: policy.Soft, Hard: policy.Hard}
+}
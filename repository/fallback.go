@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"go.uber.org/zap"
+	"net/url"
+	"time"
+)
+
+const lastKnownPrefix = "last_known"
+
+// FallbackRepository keeps the last known upstream response of every request
+// for much longer than the regular cache, so it can be served while the
+// endpoint is unavailable.
+type FallbackRepository interface {
+	// GetLastKnown decodes the last known response into v and returns when
+	// it was fetched, or nil when there is none.
+	GetLastKnown(ctx context.Context, endpoint string, params url.Values, v any) (*time.Time, error)
+	SetLastKnown(ctx context.Context, endpoint string, params url.Values, v any) error
+}
+
+type fallbackRepository struct {
+	cache     cache.Cache
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+func NewFallbackRepository(c cache.Cache, retention time.Duration, zl *zap.Logger) FallbackRepository {
+	return &fallbackRepository{
+		cache:     c,
+		retention: retention,
+		logger:    zl,
+	}
+}
+
+func (fr *fallbackRepository) GetLastKnown(ctx context.Context, endpoint string, params url.Values, v any) (*time.Time, error) {
+	entry, err := getEntry[json.RawMessage](ctx, fr.cache, getLastKnownKey(endpoint, params))
+	if entry == nil || err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(entry.Value, v)
+	if err != nil {
+		return nil, err
+	}
+
+	fr.logger.Info(fmt.Sprintf("fetched last known %s response", endpoint))
+	return &entry.FetchedAt, nil
+}
+
+func (fr *fallbackRepository) SetLastKnown(ctx context.Context, endpoint string, params url.Values, v any) error {
+	valueJSON, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	raw := json.RawMessage(valueJSON)
+
+	// The last known response is never fresh, so it is stale from the start.
+	return setEntry(ctx, fr.cache, getLastKnownKey(endpoint, params), &raw, CacheTTL{Hard: fr.retention})
+}
+
+// getLastKnownKey derives the key from the request itself; Encode sorts the
+// params, so equal requests share a key.
+func getLastKnownKey(endpoint string, params url.Values) string {
+	return fmt.Sprintf("%s%s_%s_%s", KeyNamespace, lastKnownPrefix, endpoint, params.Encode())
+}
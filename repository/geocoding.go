@@ -2,78 +2,64 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
-	"github.com/redis/go-redis/v9"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
 	"go.uber.org/zap"
 	"time"
 )
 
+const (
+	geocodePrefix        = "geocode"
+	reverseGeocodePrefix = "reverse_geocode"
+)
+
 type GeocodingRepository interface {
-	GetGeocodeForCity(ctx context.Context, city string, limit int) (*entities.Coord, error)
-	GetCityFromLatLon(ctx context.Context, lat, lon float32) (*string, error)
+	GetGeocodeForCity(ctx context.Context, city string, limit int) (*CacheEntry[entities.Coord], error)
+	GetCityFromLatLon(ctx context.Context, location geo.Location) (*CacheEntry[string], error)
 	SetGeocodeForCity(ctx context.Context, city string, limit int, coord *entities.Coord) error
-	SetCityFromLatLon(ctx context.Context, lat, lon float32, city string) error
+	SetCityFromLatLon(ctx context.Context, location geo.Location, city string) error
 }
 
 type geocodingRepository struct {
-	redisClient *redis.Client
-	logger      *zap.Logger
+	cache  cache.Cache
+	conf   config.CacheConfig
+	logger *zap.Logger
 }
 
-func NewGeocodingRepository(rc *redis.Client, zl *zap.Logger) GeocodingRepository {
+func NewGeocodingRepository(c cache.Cache, conf config.CacheConfig, zl *zap.Logger) GeocodingRepository {
 	return &geocodingRepository{
-		redisClient: rc,
-		logger:      zl,
+		cache:  c,
+		conf:   conf,
+		logger: zl,
 	}
 }
 
-func (gr *geocodingRepository) GetGeocodeForCity(ctx context.Context, city string, limit int) (*entities.Coord, error) {
-	key := getGeocodeKey(city, limit)
-
-	coordJSON, err := gr.redisClient.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	var coords entities.Coord
-
-	err = json.Unmarshal([]byte(coordJSON), &coords)
-	if err != nil {
+func (gr *geocodingRepository) GetGeocodeForCity(ctx context.Context, city string, limit int) (*CacheEntry[entities.Coord], error) {
+	entry, err := getEntry[entities.Coord](ctx, gr.cache, getGeocodeKey(city, limit))
+	if entry == nil || err != nil {
 		return nil, err
 	}
 
 	gr.logger.Info(fmt.Sprintf("fetched cached geocode for %s, %d", city, limit))
-	return &coords, nil
+	return entry, nil
 }
 
-func (gr *geocodingRepository) GetCityFromLatLon(ctx context.Context, lat, lon float32) (*string, error) {
-	key := getCityKey(lat, lon)
-
-	city, err := gr.redisClient.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, nil
-	} else if err != nil {
+func (gr *geocodingRepository) GetCityFromLatLon(ctx context.Context, location geo.Location) (*CacheEntry[string], error) {
+	entry, err := getCellEntry[string](ctx, gr.cache, reverseGeocodePrefix, location, gr.conf.ReadLegacyKeys, ttlFor(gr.conf.TTLPolicies, upstream.GeocodeReverse, time.Now(), time.Time{}))
+	if entry == nil || err != nil {
 		return nil, err
 	}
 
-	gr.logger.Info(fmt.Sprintf("fetched cached city for %f, %f", lat, lon))
-	return &city, nil
+	gr.logger.Info(fmt.Sprintf("fetched cached city for %s", location.Cell))
+	return entry, nil
 }
 
 func (gr *geocodingRepository) SetGeocodeForCity(ctx context.Context, city string, limit int, coord *entities.Coord) error {
-	key := getGeocodeKey(city, limit)
-
-	coordJSON, err := json.Marshal(coord)
-	if err != nil {
-		return err
-	}
-
-	err = gr.redisClient.Set(ctx, key, coordJSON, time.Hour*24).Err()
+	err := setEntry(ctx, gr.cache, getGeocodeKey(city, limit), coord, ttlFor(gr.conf.TTLPolicies, upstream.GeocodeDirect, time.Now(), time.Time{}))
 	if err != nil {
 		return err
 	}
@@ -82,22 +68,16 @@ func (gr *geocodingRepository) SetGeocodeForCity(ctx context.Context, city strin
 	return nil
 }
 
-func (gr *geocodingRepository) SetCityFromLatLon(ctx context.Context, lat, lon float32, city string) error {
-	key := getCityKey(lat, lon)
-
-	err := gr.redisClient.Set(ctx, key, city, time.Hour*24).Err()
+func (gr *geocodingRepository) SetCityFromLatLon(ctx context.Context, location geo.Location, city string) error {
+	err := setCellEntry(ctx, gr.cache, reverseGeocodePrefix, location, &city, ttlFor(gr.conf.TTLPolicies, upstream.GeocodeReverse, time.Now(), time.Time{}))
 	if err != nil {
 		return err
 	}
 
-	gr.logger.Info(fmt.Sprintf("saved city for %f, %f", lat, lon))
+	gr.logger.Info(fmt.Sprintf("saved city for %s", location.Cell))
 	return nil
 }
 
-func getCityKey(latitude, longitude float32) string {
-	return fmt.Sprintf("reverse_geocode_%f_%f", latitude, longitude)
-}
-
 func getGeocodeKey(city string, limit int) string {
-	return fmt.Sprintf("geocode_%s_%d", city, limit)
+	return fmt.Sprintf("%s%s_%s_%d", KeyNamespace, geocodePrefix, city, limit)
 }
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"go.uber.org/zap"
+	"time"
+)
+
+const lockPrefix = "lock"
+
+// LockRepository hands out locks shared by all replicas using the cache.
+// Locks expire after their TTL, so a replica that dies while holding one
+// blocks others for at most that long.
+type LockRepository interface {
+	// Acquire takes the lock named key and returns its token, or "" when
+	// another caller holds it.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Release frees the lock while it still holds token, so a lock that
+	// expired and was taken by another caller is kept.
+	Release(ctx context.Context, key, token string) error
+}
+
+type lockRepository struct {
+	cache  cache.Cache
+	logger *zap.Logger
+}
+
+func NewLockRepository(c cache.Cache, zl *zap.Logger) LockRepository {
+	return &lockRepository{
+		cache:  c,
+		logger: zl,
+	}
+}
+
+func (lr *lockRepository) Acquire(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+
+	acquired, err := lr.cache.SetNX(ctx, getLockKey(key), []byte(token), ttl)
+	if err != nil || !acquired {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (lr *lockRepository) Release(ctx context.Context, key, token string) error {
+	_, err := lr.cache.CompareAndDelete(ctx, getLockKey(key), []byte(token))
+	return err
+}
+
+func getLockKey(key string) string {
+	return KeyNamespace + lockPrefix + "_" + key
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const usageQuotaPrefix = "usage_quota"
+
+type UsageRepository interface {
+	UpsertUsageRollups(ctx context.Context, rollups []entities.UsageRollup) error
+	GetDailyUsage(ctx context.Context, uid string, from, to time.Time) ([]entities.UsageTotals, error)
+	GetMonthlyUsage(ctx context.Context, uid string, from, to time.Time) ([]entities.UsageTotals, error)
+	IncrementMonthlyRequests(ctx context.Context, subject, month string) (int64, error)
+}
+
+type usageRepository struct {
+	postgresDB  *gorm.DB
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewUsageRepository(db *gorm.DB, rc *redis.Client, zl *zap.Logger) UsageRepository {
+	return &usageRepository{
+		postgresDB:  db,
+		redisClient: rc,
+		logger:      zl,
+	}
+}
+
+func (ur *usageRepository) UpsertUsageRollups(ctx context.Context, rollups []entities.UsageRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	err := ur.postgresDB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "principal"}, {Name: "route"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"uid":              gorm.Expr("excluded.uid"),
+				"requests":         gorm.Expr("usage_rollups.requests + excluded.requests"),
+				"cache_hits":       gorm.Expr("usage_rollups.cache_hits + excluded.cache_hits"),
+				"cache_misses":     gorm.Expr("usage_rollups.cache_misses + excluded.cache_misses"),
+				"upstream_calls":   gorm.Expr("usage_rollups.upstream_calls + excluded.upstream_calls"),
+				"total_latency_ms": gorm.Expr("usage_rollups.total_latency_ms + excluded.total_latency_ms"),
+			}),
+		}).
+		Create(&rollups).Error
+	if err != nil {
+		return err
+	}
+
+	ur.logger.Info(fmt.Sprintf("flushed %d usage rollups", len(rollups)))
+	return nil
+}
+
+func (ur *usageRepository) GetDailyUsage(ctx context.Context, uid string, from, to time.Time) ([]entities.UsageTotals, error) {
+	return ur.getUsageTotals(ctx, "YYYY-MM-DD", uid, from, to)
+}
+
+func (ur *usageRepository) GetMonthlyUsage(ctx context.Context, uid string, from, to time.Time) ([]entities.UsageTotals, error) {
+	return ur.getUsageTotals(ctx, "YYYY-MM", uid, from, to)
+}
+
+// getUsageTotals sums the rollups of uid in [from, to), grouped by day or
+// month depending on periodFormat.
+func (ur *usageRepository) getUsageTotals(ctx context.Context, periodFormat, uid string, from, to time.Time) ([]entities.UsageTotals, error) {
+	var totals []entities.UsageTotals
+
+	err := ur.postgresDB.WithContext(ctx).
+		Model(&entities.UsageRollup{}).
+		Select(
+			"to_char(day, ?) AS period, "+
+				"SUM(requests) AS requests, "+
+				"SUM(cache_hits) AS cache_hits, "+
+				"SUM(cache_misses) AS cache_misses, "+
+				"SUM(upstream_calls) AS upstream_calls, "+
+				"COALESCE(SUM(total_latency_ms)::float / NULLIF(SUM(requests), 0), 0) AS avg_latency_ms",
+			periodFormat,
+		).
+		Where("uid = ? AND day >= ? AND day < ?", uid, from, to).
+		Group("period").
+		Order("period").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+func (ur *usageRepository) IncrementMonthlyRequests(ctx context.Context, subject, month string) (int64, error) {
+	key := getMonthlyRequestsKey(subject, month)
+
+	count, err := ur.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		err = ur.redisClient.Expire(ctx, key, time.Hour*24*32).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func getMonthlyRequestsKey(subject, month string) string {
+	return fmt.Sprintf("%s%s_%s_%s", KeyNamespace, usageQuotaPrefix, subject, month)
+}
@@ -2,79 +2,62 @@ package repository
 
 import (
 	"context"
-	"firebase.google.com/go/v4/auth"
 	"fmt"
 	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
 	"go.uber.org/zap"
 )
 
+const rolesClaim = "roles"
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *entities.UserDetails) (*string, error)
 	UpdateUser(ctx context.Context, uid string, user *entities.UserDetails) (*string, error)
 	DeleteUser(ctx context.Context, uid string) (*string, error)
-	GenerateToken(ctx context.Context, uid string) (*string, error)
-	SendVerificationEmail(ctx context.Context, email string) (*string, error)
-	ResetPassword(ctx context.Context, email string) (*string, error)
+	GenerateToken(ctx context.Context, uid string) (*entities.CustomToken, error)
+	ExchangeCredentials(ctx context.Context, email, password string) (*entities.Session, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*entities.Session, error)
+	EmailVerificationLink(ctx context.Context, email string) (*string, error)
+	PasswordResetLink(ctx context.Context, email string) (*string, error)
+	ApplyAction(ctx context.Context, mode, oobCode, newPassword string) error
+	SetUserRoles(ctx context.Context, uid string, roles []string) (*string, error)
+	ClearUserRoles(ctx context.Context, uid string) (*string, error)
 }
 
 type userRepository struct {
-	firebaseAuth *auth.Client
-	logger       *zap.Logger
+	identityProvider identity.Provider
+	logger           *zap.Logger
 }
 
-func NewUserRepository(fa *auth.Client, zl *zap.Logger) UserRepository {
+func NewUserRepository(ip identity.Provider, zl *zap.Logger) UserRepository {
 	return &userRepository{
-		firebaseAuth: fa,
-		logger:       zl,
+		identityProvider: ip,
+		logger:           zl,
 	}
 }
 
 func (ur *userRepository) CreateUser(ctx context.Context, user *entities.UserDetails) (*string, error) {
-	params := (&auth.UserToCreate{}).
-		Email(user.Email).
-		EmailVerified(user.EmailVerified).
-		PhoneNumber(user.PhoneNumber).
-		Password(user.Password).
-		DisplayName(user.DisplayName).
-		Disabled(user.Disabled)
-
-	if user.PhotoURL != nil {
-		params.PhotoURL(*user.PhotoURL)
-	}
-
-	createdUser, err := ur.firebaseAuth.CreateUser(ctx, params)
+	uid, err := ur.identityProvider.CreateUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	ur.logger.Info(fmt.Sprintf("created user %s", createdUser.UID))
-	return &createdUser.UID, nil
+	ur.logger.Info(fmt.Sprintf("created user %s", uid))
+	return &uid, nil
 }
 
 func (ur *userRepository) UpdateUser(ctx context.Context, uid string, user *entities.UserDetails) (*string, error) {
-	params := (&auth.UserToUpdate{}).
-		Email(user.Email).
-		EmailVerified(user.EmailVerified).
-		PhoneNumber(user.PhoneNumber).
-		Password(user.Password).
-		DisplayName(user.DisplayName).
-		Disabled(user.Disabled)
-
-	if user.PhotoURL != nil {
-		params.PhotoURL(*user.PhotoURL)
-	}
-
-	updatedUser, err := ur.firebaseAuth.UpdateUser(ctx, uid, params)
+	err := ur.identityProvider.UpdateUser(ctx, uid, user)
 	if err != nil {
 		return nil, err
 	}
 
-	ur.logger.Info(fmt.Sprintf("updated user %s", updatedUser.UID))
+	ur.logger.Info(fmt.Sprintf("updated user %s", uid))
 	return &uid, nil
 }
 
 func (ur *userRepository) DeleteUser(ctx context.Context, uid string) (*string, error) {
-	err := ur.firebaseAuth.DeleteUser(ctx, uid)
+	err := ur.identityProvider.DeleteUser(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -83,18 +66,38 @@ func (ur *userRepository) DeleteUser(ctx context.Context, uid string) (*string,
 	return &uid, nil
 }
 
-func (ur *userRepository) GenerateToken(ctx context.Context, uid string) (*string, error) {
-	token, err := ur.firebaseAuth.CustomToken(ctx, uid)
+func (ur *userRepository) GenerateToken(ctx context.Context, uid string) (*entities.CustomToken, error) {
+	token, err := ur.identityProvider.CustomToken(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
 
-	ur.logger.Info(fmt.Sprintf("logging in user %s - token %s", uid, token))
-	return &uid, nil
+	ur.logger.Info(fmt.Sprintf("generated a custom token for user %s", uid))
+	return token, nil
+}
+
+func (ur *userRepository) ExchangeCredentials(ctx context.Context, email, password string) (*entities.Session, error) {
+	session, err := ur.identityProvider.SignInWithPassword(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	ur.logger.Info(fmt.Sprintf("signed in user %s", session.UID))
+	return session, nil
 }
 
-func (ur *userRepository) SendVerificationEmail(ctx context.Context, email string) (*string, error) {
-	link, err := ur.firebaseAuth.EmailVerificationLink(ctx, email)
+func (ur *userRepository) RefreshSession(ctx context.Context, refreshToken string) (*entities.Session, error) {
+	session, err := ur.identityProvider.RefreshSession(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	ur.logger.Info(fmt.Sprintf("refreshed the session of user %s", session.UID))
+	return session, nil
+}
+
+func (ur *userRepository) EmailVerificationLink(ctx context.Context, email string) (*string, error) {
+	link, err := ur.identityProvider.EmailVerificationLink(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -102,11 +105,71 @@ func (ur *userRepository) SendVerificationEmail(ctx context.Context, email strin
 	return &link, nil
 }
 
-func (ur *userRepository) ResetPassword(ctx context.Context, email string) (*string, error) {
-	link, err := ur.firebaseAuth.PasswordResetLink(ctx, email)
+func (ur *userRepository) PasswordResetLink(ctx context.Context, email string) (*string, error) {
+	link, err := ur.identityProvider.PasswordResetLink(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
 	return &link, nil
 }
+
+func (ur *userRepository) ApplyAction(ctx context.Context, mode, oobCode, newPassword string) error {
+	err := ur.identityProvider.ApplyAction(ctx, mode, oobCode, newPassword)
+	if err != nil {
+		return err
+	}
+
+	ur.logger.Info(fmt.Sprintf("applied a %s action", mode))
+	return nil
+}
+
+func (ur *userRepository) SetUserRoles(ctx context.Context, uid string, roles []string) (*string, error) {
+	claims, err := ur.getCustomClaims(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	claims[rolesClaim] = roles
+
+	err = ur.identityProvider.SetCustomClaims(ctx, uid, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	ur.logger.Info(fmt.Sprintf("set roles %v for user %s", roles, uid))
+	return &uid, nil
+}
+
+func (ur *userRepository) ClearUserRoles(ctx context.Context, uid string) (*string, error) {
+	claims, err := ur.getCustomClaims(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(claims, rolesClaim)
+
+	err = ur.identityProvider.SetCustomClaims(ctx, uid, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	ur.logger.Info(fmt.Sprintf("cleared roles for user %s", uid))
+	return &uid, nil
+}
+
+// getCustomClaims returns a copy of the user's existing custom claims, so that
+// updating roles does not wipe out unrelated claims.
+func (ur *userRepository) getCustomClaims(ctx context.Context, uid string) (map[string]interface{}, error) {
+	customClaims, err := ur.identityProvider.GetCustomClaims(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{}, len(customClaims)+1)
+	for key, value := range customClaims {
+		claims[key] = value
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/advisory"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// safestWindowsLimit is how many of the safest forecast periods an advice
+// lists.
+const safestWindowsLimit = 3
+
+type AdviceService interface {
+	GetAdvice(ctx context.Context, latitude, longitude float32, standard, locale string) (*entities.AirQualityAdvice, error)
+}
+
+type adviceService struct {
+	airPollutionService AirPollutionService
+	logger              *zap.Logger
+}
+
+func NewAdviceService(as AirPollutionService, zl *zap.Logger) AdviceService {
+	return &adviceService{
+		airPollutionService: as,
+		logger:              zl,
+	}
+}
+
+// GetAdvice advises on the current air quality, and on when in the forecast
+// outdoor activity is safest.
+func (ads *adviceService) GetAdvice(ctx context.Context, latitude, longitude float32, standard, locale string) (*entities.AirQualityAdvice, error) {
+	var current, forecast *entities.AirPollution
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	// Both fetches mark their own freshness, and the advice is as fresh as
+	// the least fresh of them.
+	currentCtx, forecastCtx := freshness.NewContext(groupCtx), freshness.NewContext(groupCtx)
+
+	group.Go(func() error {
+		var err error
+		current, err = ads.airPollutionService.GetCurrentAirPollution(currentCtx, latitude, longitude)
+		return err
+	})
+
+	group.Go(func() error {
+		var err error
+		forecast, err = ads.airPollutionService.GetAirPollutionForecast(forecastCtx, latitude, longitude)
+		return err
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	freshness.Merge(ctx, freshness.FromContext(currentCtx))
+	freshness.Merge(ctx, freshness.FromContext(forecastCtx))
+
+	currentAirQuality, err := aqi.Current(current, standard)
+	if err != nil {
+		return nil, err
+	}
+
+	// The forecast may start before the current hour.
+	currentHour := currentAirQuality.Dt - currentAirQuality.Dt%3600
+	upcoming := &entities.AirPollution{Coord: forecast.Coord}
+	for _, datapoint := range forecast.List {
+		if datapoint.Dt >= currentHour {
+			upcoming.List = append(upcoming.List, datapoint)
+		}
+	}
+
+	indexedForecast, err := aqi.Indexed(upcoming, standard)
+	if err != nil {
+		return nil, err
+	}
+
+	outlook := advisory.Outlook(indexedForecast.List)
+
+	return &entities.AirQualityAdvice{
+		Locale: locale,
+		Current: entities.CurrentAirQualityAdvice{
+			Dt:       currentAirQuality.Dt,
+			AQI:      currentAirQuality.AQI,
+			Advisory: advisory.For(advisory.Risk(currentAirQuality.AQI), locale),
+		},
+		Outlook:       outlook,
+		SafestWindows: advisory.SafestWindows(outlook, safestWindowsLimit, locale),
+	}, nil
+}
@@ -1,173 +1,311 @@
 package services
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/metrics"
 	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/SamPariatIL/weather-wrapper/usage"
 	"go.uber.org/zap"
-	"log"
-	"net/http"
+	"golang.org/x/sync/errgroup"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
 )
 
+var ErrHistoryRangeTooLong = errors.New("the history range is too long")
+
+// historyDaySeconds is the size of the buckets the last known good history
+// is kept in.
+const historyDaySeconds = 24 * 60 * 60
+
 type AirPollutionService interface {
-	GetCurrentAirPollution(latitude, longitude float32) (*entities.AirPollution, error)
-	GetAirPollutionForecast(latitude, longitude float32) (*entities.AirPollution, error)
-	GetHistoricalAirPollution(latitude, longitude float32, start, end int64) (*entities.AirPollution, error)
+	GetCurrentAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error)
+	GetAirPollutionForecast(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error)
+	GetHistoricalAirPollution(ctx context.Context, latitude, longitude float32, start, end int64) (*entities.AirPollution, error)
 }
 
 type airPollutionService struct {
 	airPollutionRepo repository.AirPollutionRepository
+	fallbackRepo     repository.FallbackRepository
+	fetcher          *cachedFetcher
+	conf             config.AirPollutionConfig
 	logger           *zap.Logger
 }
 
-func NewAirPollutionService(ar repository.AirPollutionRepository, zl *zap.Logger) AirPollutionService {
+func NewAirPollutionService(ar repository.AirPollutionRepository, fr repository.FallbackRepository, lr repository.LockRepository, uc upstream.Client, rp RefreshPool, conf *config.Config, zl *zap.Logger) AirPollutionService {
 	return &airPollutionService{
 		airPollutionRepo: ar,
+		fallbackRepo:     fr,
+		fetcher:          newCachedFetcher(uc, fr, lr, rp, conf, zl),
+		conf:             conf.AirPollutionConfig,
 		logger:           zl,
 	}
 }
 
-func (as *airPollutionService) GetCurrentAirPollution(latitude, longitude float32) (*entities.AirPollution, error) {
-	conf := config.GetConfig()
+func (as *airPollutionService) GetCurrentAirPollution(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error) {
+	location := as.fetcher.snap(ctx, upstream.CurrentAirPollution, latitude, longitude)
+
+	return getCached(ctx, as.fetcher, cachedResource[entities.AirPollution, entities.AirPollution]{
+		endpoint: upstream.CurrentAirPollution,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon)),
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.AirPollution], error) {
+			return as.airPollutionRepo.GetCurrentAirPollution(ctx, location)
+		},
+		set: func(ctx context.Context, airPollution *entities.AirPollution) error {
+			return as.airPollutionRepo.SetCurrentAirPollution(ctx, location, airPollution)
+		},
+		convert: requireAirPollutionList,
+	})
+}
+
+func (as *airPollutionService) GetAirPollutionForecast(ctx context.Context, latitude, longitude float32) (*entities.AirPollution, error) {
+	location := as.fetcher.snap(ctx, upstream.AirPollutionForecast, latitude, longitude)
+
+	return getCached(ctx, as.fetcher, cachedResource[entities.AirPollution, entities.AirPollution]{
+		endpoint: upstream.AirPollutionForecast,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon)),
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.AirPollution], error) {
+			return as.airPollutionRepo.GetAirPollutionForecast(ctx, location)
+		},
+		set: func(ctx context.Context, airPollutionForecast *entities.AirPollution) error {
+			return as.airPollutionRepo.SetAirPollutionForecast(ctx, location, airPollutionForecast)
+		},
+		convert: requireAirPollutionList,
+	})
+}
+
+// GetHistoricalAirPollution serves the stored datapoints of the range and
+// fetches only the sub-ranges missing from them, which are then stored too.
+// Missing sub-ranges are split into chunks aligned to multiples of the chunk
+// size, so overlapping requests share their fetches, and fetched
+// concurrently.
+func (as *airPollutionService) GetHistoricalAirPollution(ctx context.Context, latitude, longitude float32, start, end int64) (*entities.AirPollution, error) {
+	// Compared in seconds, as the span of far apart times overflows a
+	// Duration.
+	if end-start > int64(as.conf.HistoryMaxSpan/time.Second) {
+		return nil, fmt.Errorf("%w: it may span at most %s", ErrHistoryRangeTooLong, as.conf.HistoryMaxSpan)
+	}
 
-	var err error
+	location := as.fetcher.snap(ctx, upstream.HistoricalAirPollution, latitude, longitude)
 
-	savedAirPollution, err := as.airPollutionRepo.GetCurrentAirPollution(context.Background(), latitude, longitude)
+	window, err := as.airPollutionRepo.GetHistory(ctx, location, start, end)
 	if err != nil {
-		return nil, err
+		as.logger.Warn(fmt.Sprintf("Cache read of the air pollution history of %s failed: %v", location.Cell, err))
+		metrics.CountCacheError(metrics.CacheRead)
+		window = &repository.HistoryWindow{Missing: []repository.TimeRange{{Start: start, End: end}}}
 	}
 
-	if savedAirPollution != nil {
-		return savedAirPollution, nil
+	if len(window.Missing) == 0 {
+		usage.MarkCacheHit(ctx)
+		freshness.MarkCached(ctx, window.FetchedAt)
 	}
 
-	url := fmt.Sprintf(
-		"https://%s?lat=%f&lon=%f&appid=%s",
-		conf.AirPollutionConfig.BaseURL,
-		latitude,
-		longitude,
-		conf.AirPollutionConfig.APIKey,
-	)
+	var chunks []repository.TimeRange
+	for _, missing := range window.Missing {
+		chunks = append(chunks, splitRange(missing, int64(as.conf.HistoryChunk/time.Second))...)
+	}
 
-	log.Println(url)
+	histories := make([]*entities.AirPollution, len(chunks))
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, nil
-	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(max(as.conf.HistoryWorkers, 1))
 
-	defer func() {
-		err = resp.Body.Close()
-	}()
+	// Every chunk marks its own freshness, and the response is as fresh as
+	// the least fresh of them.
+	chunkInfos := make([]*freshness.Info, len(chunks))
 
-	var airPollution entities.AirPollution
+	for i, chunk := range chunks {
+		chunkCtx := freshness.NewContext(groupCtx)
+		chunkInfos[i] = freshness.FromContext(chunkCtx)
 
-	err = json.NewDecoder(resp.Body).Decode(&airPollution)
-	if err != nil {
-		return nil, err
+		group.Go(func() error {
+			history, err := as.getHistoryRange(chunkCtx, location, chunk)
+			histories[i] = history
+			return err
+		})
 	}
 
-	err = as.airPollutionRepo.SetCurrentAirPollution(context.Background(), latitude, longitude, &airPollution)
+	err = group.Wait()
 	if err != nil {
 		return nil, err
 	}
 
-	return &airPollution, nil
-}
-
-func (as *airPollutionService) GetAirPollutionForecast(latitude, longitude float32) (*entities.AirPollution, error) {
-	conf := config.GetConfig()
-
-	var err error
-
-	savedAirPollutionForecast, err := as.airPollutionRepo.GetAirPollutionForecast(context.Background(), latitude, longitude)
-	if err != nil {
-		return nil, err
+	for _, chunkInfo := range chunkInfos {
+		freshness.Merge(ctx, chunkInfo)
 	}
 
-	if savedAirPollutionForecast != nil {
-		return savedAirPollutionForecast, nil
+	// Fetched datapoints come after the stored ones, so they win when both
+	// have the same dt.
+	datapoints := window.Datapoints
+	for _, history := range histories {
+		datapoints = append(datapoints, history.List...)
 	}
 
-	url := fmt.Sprintf(
-		"https://%s/forecast?lat=%f&lon=%f&appid=%s",
-		conf.AirPollutionConfig.BaseURL,
-		latitude,
-		longitude,
-		conf.AirPollutionConfig.APIKey,
-	)
+	return &entities.AirPollution{
+		Coord: entities.Coord{Lat: float32(location.Lat), Lon: float32(location.Lon)},
+		List:  dedupeDatapoints(datapoints),
+	}, nil
+}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, nil
+// getHistoryRange fetches a range missing from the stored history. Requests
+// missing the same range share the fetch, and those that find it stored in
+// the meantime are served from there.
+func (as *airPollutionService) getHistoryRange(ctx context.Context, location geo.Location, timeRange repository.TimeRange) (*entities.AirPollution, error) {
+	return getCached(ctx, as.fetcher, cachedResource[entities.AirPollution, entities.AirPollution]{
+		endpoint: upstream.HistoricalAirPollution,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon), "start", strconv.FormatInt(timeRange.Start, 10), "end", strconv.FormatInt(timeRange.End, 10)),
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.AirPollution], error) {
+			window, err := as.airPollutionRepo.GetHistory(ctx, location, timeRange.Start, timeRange.End)
+			if err != nil || len(window.Missing) > 0 {
+				return nil, err
+			}
+
+			return &repository.CacheEntry[entities.AirPollution]{
+				Value:     entities.AirPollution{List: window.Datapoints},
+				FetchedAt: window.FetchedAt,
+				StaleAt:   window.StaleAt,
+			}, nil
+		},
+		set: func(ctx context.Context, history *entities.AirPollution) error {
+			return as.airPollutionRepo.AddHistory(ctx, location, timeRange.Start, timeRange.End, history.List)
+		},
+		convert:   asIs[entities.AirPollution],
+		cacheable: hasAirPollutionList,
+		setLastKnown: func(ctx context.Context, history *entities.AirPollution) error {
+			return as.setLastKnownHistory(ctx, location, history)
+		},
+		getLastKnown: func(ctx context.Context, history *entities.AirPollution) (*time.Time, error) {
+			return as.getLastKnownHistory(ctx, location, timeRange, history)
+		},
+	})
+}
+
+// setLastKnownHistory keeps the fetched history as the last known good one
+// of every UTC day it covers, merged with what is kept of that day already,
+// so requests for other ranges over the same days can fall back to it.
+func (as *airPollutionService) setLastKnownHistory(ctx context.Context, location geo.Location, history *entities.AirPollution) error {
+	days := make(map[int64][]entities.AirPollutionDatapoint)
+	for _, datapoint := range history.List {
+		day := historyDay(int64(datapoint.Dt))
+		days[day] = append(days[day], datapoint)
 	}
 
-	defer func() {
-		err = resp.Body.Close()
-	}()
+	for day, datapoints := range days {
+		var kept entities.AirPollution
 
-	var airPollutionForecast entities.AirPollution
+		_, err := as.fallbackRepo.GetLastKnown(ctx, string(upstream.HistoricalAirPollution), historyDayParams(location, day), &kept)
+		if err != nil {
+			return err
+		}
 
-	err = json.NewDecoder(resp.Body).Decode(&airPollutionForecast)
-	if err != nil {
-		return nil, err
+		kept.List = dedupeDatapoints(append(kept.List, datapoints...))
+
+		err = as.fallbackRepo.SetLastKnown(ctx, string(upstream.HistoricalAirPollution), historyDayParams(location, day), &kept)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = as.airPollutionRepo.SetAirPollutionForecast(context.Background(), latitude, longitude, &airPollutionForecast)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// getLastKnownHistory reads the last known good history of the range from
+// the days it covers. It is only served when every day is known, and is as
+// old as the oldest of them.
+func (as *airPollutionService) getLastKnownHistory(ctx context.Context, location geo.Location, timeRange repository.TimeRange, history *entities.AirPollution) (*time.Time, error) {
+	var oldest *time.Time
+
+	for day := historyDay(timeRange.Start); day <= timeRange.End; day += historyDaySeconds {
+		var kept entities.AirPollution
+
+		fetchedAt, err := as.fallbackRepo.GetLastKnown(ctx, string(upstream.HistoricalAirPollution), historyDayParams(location, day), &kept)
+		if err != nil || fetchedAt == nil {
+			return nil, err
+		}
+
+		if oldest == nil || fetchedAt.Before(*oldest) {
+			oldest = fetchedAt
+		}
+
+		for _, datapoint := range kept.List {
+			if int64(datapoint.Dt) >= timeRange.Start && int64(datapoint.Dt) <= timeRange.End {
+				history.List = append(history.List, datapoint)
+			}
+		}
 	}
 
-	return &airPollutionForecast, nil
+	return oldest, nil
 }
 
-func (as *airPollutionService) GetHistoricalAirPollution(latitude, longitude float32, start, end int64) (*entities.AirPollution, error) {
-	conf := config.GetConfig()
+// historyDay returns the start of the UTC day of the unix time at.
+func historyDay(at int64) int64 {
+	return at - ((at%historyDaySeconds)+historyDaySeconds)%historyDaySeconds
+}
 
-	var err error
+func historyDayParams(location geo.Location, day int64) url.Values {
+	return upstream.LatLonParams(float32(location.Lat), float32(location.Lon), "day", strconv.FormatInt(day, 10))
+}
 
-	savedHistoricalAirPollution, err := as.airPollutionRepo.GetHistoricalAirPollution(context.Background(), latitude, longitude)
-	if err != nil {
-		return nil, err
-	}
+// hasAirPollutionList keeps empty history responses out of the cache, so a
+// range upstream has no data for yet is asked for again rather than stored
+// as covered.
+func hasAirPollutionList(airPollution *entities.AirPollution) bool {
+	return len(airPollution.List) > 0
+}
 
-	if savedHistoricalAirPollution != nil {
-		return savedHistoricalAirPollution, nil
+// requireAirPollutionList rejects current and forecast responses without
+// datapoints, so they are neither cached nor kept as last known good.
+func requireAirPollutionList(airPollution *entities.AirPollution) (*entities.AirPollution, error) {
+	if len(airPollution.List) == 0 {
+		return nil, fmt.Errorf("%w: the air pollution list is empty", upstream.ErrBadPayload)
 	}
 
-	url := fmt.Sprintf(
-		"https://%s/history?lat=%f&lon=%f&start=%d&end=%d&appid=%s",
-		conf.AirPollutionConfig.BaseURL,
-		latitude,
-		longitude,
-		start,
-		end,
-		conf.AirPollutionConfig.APIKey,
-	)
+	return airPollution, nil
+}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, nil
+// splitRange splits timeRange at the multiples of chunk seconds; a chunk of 0
+// leaves it whole.
+func splitRange(timeRange repository.TimeRange, chunk int64) []repository.TimeRange {
+	if chunk <= 0 {
+		return []repository.TimeRange{timeRange}
 	}
 
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	var historicalAirPollution entities.AirPollution
+	var chunks []repository.TimeRange
 
-	err = json.NewDecoder(resp.Body).Decode(&historicalAirPollution)
-	if err != nil {
-		return nil, err
+	for start := timeRange.Start; start <= timeRange.End; {
+		end := min((start/chunk+1)*chunk-1, timeRange.End)
+		chunks = append(chunks, repository.TimeRange{Start: start, End: end})
+		start = end + 1
 	}
 
-	err = as.airPollutionRepo.SetHistoricalAirPollution(context.Background(), latitude, longitude, &historicalAirPollution)
-	if err != nil {
-		return nil, err
+	return chunks
+}
+
+// dedupeDatapoints sorts the datapoints by dt, keeping the last of those
+// with the same dt.
+func dedupeDatapoints(datapoints []entities.AirPollutionDatapoint) []entities.AirPollutionDatapoint {
+	slices.SortStableFunc(datapoints, func(a, b entities.AirPollutionDatapoint) int {
+		return cmp.Compare(a.Dt, b.Dt)
+	})
+
+	deduped := datapoints[:0]
+	for _, datapoint := range datapoints {
+		if len(deduped) > 0 && deduped[len(deduped)-1].Dt == datapoint.Dt {
+			deduped[len(deduped)-1] = datapoint
+			continue
+		}
+
+		deduped = append(deduped, datapoint)
 	}
 
-	return &historicalAirPollution, nil
+	return deduped
 }
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/summary"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"math"
+	"time"
+)
+
+type AirPollutionSummaryService interface {
+	GetForecastSummary(ctx context.Context, latitude, longitude float32, standard string) (*entities.AirPollutionForecastResponse, error)
+	GetHistorySummary(ctx context.Context, latitude, longitude float32, start, end int64, standard string) (*entities.HistoricalAirPollutionResponse, error)
+}
+
+type airPollutionSummaryService struct {
+	airPollutionService AirPollutionService
+	weatherService      WeatherService
+	logger              *zap.Logger
+}
+
+func NewAirPollutionSummaryService(as AirPollutionService, ws WeatherService, zl *zap.Logger) AirPollutionSummaryService {
+	return &airPollutionSummaryService{
+		airPollutionService: as,
+		weatherService:      ws,
+		logger:              zl,
+	}
+}
+
+func (ss *airPollutionSummaryService) GetForecastSummary(ctx context.Context, latitude, longitude float32, standard string) (*entities.AirPollutionForecastResponse, error) {
+	var forecast *entities.AirPollution
+	var timezoneOffset int
+	var timezoneEstimated bool
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	group.Go(func() error {
+		timezoneOffset, timezoneEstimated = ss.timezoneOffset(groupCtx, latitude, longitude)
+		return nil
+	})
+
+	group.Go(func() error {
+		var err error
+		forecast, err = ss.airPollutionService.GetAirPollutionForecast(groupCtx, latitude, longitude)
+		return err
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	days, err := summary.Daily(forecast.List, timezoneOffset, standard)
+	if err != nil {
+		return nil, err
+	}
+
+	timezoneEstimated = timezoneEstimated || offsetMayChange(forecast.List, timezoneOffset)
+
+	return &entities.AirPollutionForecastResponse{
+		Latitude:          forecast.Lat,
+		Longitude:         forecast.Lon,
+		TimezoneOffset:    timezoneOffset,
+		TimezoneEstimated: timezoneEstimated,
+		Standard:          standard,
+		Days:              days,
+	}, nil
+}
+
+func (ss *airPollutionSummaryService) GetHistorySummary(ctx context.Context, latitude, longitude float32, start, end int64, standard string) (*entities.HistoricalAirPollutionResponse, error) {
+	var history *entities.AirPollution
+	var timezoneOffset int
+	var timezoneEstimated bool
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	group.Go(func() error {
+		timezoneOffset, timezoneEstimated = ss.timezoneOffset(groupCtx, latitude, longitude)
+		return nil
+	})
+
+	group.Go(func() error {
+		var err error
+		history, err = ss.airPollutionService.GetHistoricalAirPollution(groupCtx, latitude, longitude, start, end)
+		return err
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	days, err := summary.Daily(history.List, timezoneOffset, standard)
+	if err != nil {
+		return nil, err
+	}
+
+	timezoneEstimated = timezoneEstimated || offsetMayChange(history.List, timezoneOffset)
+
+	return &entities.HistoricalAirPollutionResponse{
+		Latitude:          history.Lat,
+		Longitude:         history.Lon,
+		Start:             start,
+		End:               end,
+		TimezoneOffset:    timezoneOffset,
+		TimezoneEstimated: timezoneEstimated,
+		Standard:          standard,
+		Days:              days,
+	}, nil
+}
+
+// timezoneOffset returns the offset from UTC of the local time of the
+// location, in seconds, which OpenWeatherMap reports with the current
+// weather. If the weather cannot be fetched, it falls back to the offset of
+// the nautical time zone of the longitude and reports it as estimated.
+//
+// The weather is fetched with freshness of its own, so that its cache state
+// and snapped location do not leak into those of the air pollution, which is
+// fetched alongside.
+func (ss *airPollutionSummaryService) timezoneOffset(ctx context.Context, latitude, longitude float32) (offset int, estimated bool) {
+	currentWeather, err := ss.weatherService.GetCurrentWeather(freshness.NewContext(ctx), latitude, longitude)
+	if err == nil {
+		return currentWeather.TimeZone, false
+	}
+
+	ss.logger.Warn(fmt.Sprintf("Falling back to the nautical time zone of %f, %f: %v", latitude, longitude, err))
+
+	return int(math.Round(float64(longitude)/15)) * 3600, true
+}
+
+// offsetMayChange reports whether the location may have been at another
+// offset than timezoneOffset at some of the datapoints, in which case the
+// days are bucketed with an estimated offset.
+func offsetMayChange(datapoints []entities.AirPollutionDatapoint, timezoneOffset int) bool {
+	if len(datapoints) == 0 {
+		return false
+	}
+
+	start, end := datapoints[0].Dt, datapoints[0].Dt
+	for _, datapoint := range datapoints[1:] {
+		start, end = min(start, datapoint.Dt), max(end, datapoint.Dt)
+	}
+
+	return summary.OffsetMayChange(timezoneOffset, time.Now(), int64(start), int64(end))
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyInvalid  = errors.New("api key is invalid")
+	ErrAPIKeyRevoked  = errors.New("api key has been revoked")
+	ErrAPIKeyExpired  = errors.New("api key has expired")
+	// ErrAPIKeyOwnerInactive is returned for the keys of deleted or disabled
+	// users.
+	ErrAPIKeyOwnerInactive = errors.New("api key owner is deleted or disabled")
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ownerUID string, body *entities.CreateAPIKeyBody) (*entities.CreatedAPIKey, error)
+	ListAPIKeys(ownerUID string) ([]entities.APIKey, error)
+	RotateAPIKey(ownerUID, id string) (*entities.CreatedAPIKey, error)
+	RevokeAPIKey(ownerUID, id string) (*entities.APIKey, error)
+	// AuthenticateAPIKey returns the key and the custom claims of its owner,
+	// which are looked up on every request so that the keys of deleted or
+	// disabled users stop working and plan changes apply right away.
+	AuthenticateAPIKey(ctx context.Context, key string) (*entities.APIKey, map[string]interface{}, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo       repository.APIKeyRepository
+	identityProvider identity.Provider
+	logger           *zap.Logger
+}
+
+func NewAPIKeyService(akr repository.APIKeyRepository, ip identity.Provider, zl *zap.Logger) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo:       akr,
+		identityProvider: ip,
+		logger:           zl,
+	}
+}
+
+func (aks *apiKeyService) CreateAPIKey(ownerUID string, body *entities.CreateAPIKeyBody) (*entities.CreatedAPIKey, error) {
+	key, prefix, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := entities.APIKey{
+		ID:        uuid.NewString(),
+		OwnerUID:  ownerUID,
+		Name:      body.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+	}
+
+	err = aks.apiKeyRepo.CreateAPIKey(context.Background(), &apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (aks *apiKeyService) ListAPIKeys(ownerUID string) ([]entities.APIKey, error) {
+	apiKeys, err := aks.apiKeyRepo.ListAPIKeys(context.Background(), ownerUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// RotateAPIKey replaces the secret of a key. Revoked and expired keys are not
+// rotated, since their new secret could never authenticate.
+func (aks *apiKeyService) RotateAPIKey(ownerUID, id string) (*entities.CreatedAPIKey, error) {
+	apiKey, err := aks.apiKeyRepo.GetAPIKey(context.Background(), ownerUID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err := checkActive(apiKey, time.Now()); err != nil {
+		return nil, err
+	}
+
+	key, prefix, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err = aks.apiKeyRepo.RotateAPIKey(context.Background(), ownerUID, id, prefix, keyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return &entities.CreatedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+func (aks *apiKeyService) RevokeAPIKey(ownerUID, id string) (*entities.APIKey, error) {
+	apiKey, err := aks.apiKeyRepo.RevokeAPIKey(context.Background(), ownerUID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return apiKey, nil
+}
+
+func (aks *apiKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*entities.APIKey, map[string]interface{}, error) {
+	apiKey, err := aks.apiKeyRepo.GetAPIKeyByHash(ctx, utils.HashAPIKey(key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if apiKey == nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+
+	if err := checkActive(apiKey, now); err != nil {
+		return nil, nil, err
+	}
+
+	ownerClaims, err := aks.identityProvider.GetActiveClaims(ctx, apiKey.OwnerUID)
+	if errors.Is(err, identity.ErrUserNotFound) || errors.Is(err, identity.ErrUserDisabled) {
+		return nil, nil, ErrAPIKeyOwnerInactive
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	err = aks.apiKeyRepo.TouchAPIKey(ctx, apiKey.ID, now)
+	if err != nil {
+		aks.logger.Warn(err.Error())
+	}
+
+	return apiKey, ownerClaims, nil
+}
+
+// checkActive returns why apiKey can no longer be used at now, or nil.
+func checkActive(apiKey *entities.APIKey, now time.Time) error {
+	if apiKey.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return ErrAPIKeyExpired
+	}
+
+	return nil
+}
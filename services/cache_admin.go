@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"go.uber.org/zap"
+)
+
+// defaultCacheEntriesLimit applies when a listing asks for no limit.
+const defaultCacheEntriesLimit = 100
+
+var ErrCacheEntryNotFound = errors.New("cache entry not found")
+
+type CacheAdminService interface {
+	ListEntries(ctx context.Context, query *entities.CacheEntriesQuery) (*entities.CacheEntries, error)
+	GetEntry(ctx context.Context, key string) (*entities.CacheEntryInfo, error)
+	Purge(ctx context.Context, query *entities.CachePurgeQuery) (*entities.CachePurge, error)
+	Flush(ctx context.Context) (*entities.CachePurge, error)
+}
+
+type cacheAdminService struct {
+	cacheAdminRepo repository.CacheAdminRepository
+	logger         *zap.Logger
+}
+
+func NewCacheAdminService(car repository.CacheAdminRepository, zl *zap.Logger) CacheAdminService {
+	return &cacheAdminService{
+		cacheAdminRepo: car,
+		logger:         zl,
+	}
+}
+
+func (cas *cacheAdminService) ListEntries(ctx context.Context, query *entities.CacheEntriesQuery) (*entities.CacheEntries, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultCacheEntriesLimit
+	}
+
+	filter := cacheFilter(query.Resource, query.MinLat, query.MinLon, query.MaxLat, query.MaxLon, query.Lat, query.Lon)
+
+	entries, truncated, err := cas.cacheAdminRepo.ListEntries(ctx, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CacheEntries{Entries: entries, Truncated: truncated}, nil
+}
+
+func (cas *cacheAdminService) GetEntry(ctx context.Context, key string) (*entities.CacheEntryInfo, error) {
+	entry, err := cas.cacheAdminRepo.GetEntry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, ErrCacheEntryNotFound
+	}
+
+	return entry, nil
+}
+
+func (cas *cacheAdminService) Purge(ctx context.Context, query *entities.CachePurgeQuery) (*entities.CachePurge, error) {
+	filter := cacheFilter(query.Resource, query.MinLat, query.MinLon, query.MaxLat, query.MaxLon, query.Lat, query.Lon)
+
+	deleted, err := cas.cacheAdminRepo.Purge(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CachePurge{Deleted: deleted}, nil
+}
+
+func (cas *cacheAdminService) Flush(ctx context.Context) (*entities.CachePurge, error) {
+	deleted, err := cas.cacheAdminRepo.Flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CachePurge{Deleted: deleted}, nil
+}
+
+// cacheFilter selects the entries of resource in the bounding box, or in the
+// cell containing the point; validation ensures at most one of them is set.
+func cacheFilter(resource string, minLat, minLon, maxLat, maxLon, lat, lon *float64) repository.CacheFilter {
+	filter := repository.CacheFilter{Resource: resource}
+
+	switch {
+	case minLat != nil:
+		filter.Box = &geo.Box{MinLat: *minLat, MinLon: *minLon, MaxLat: *maxLat, MaxLon: *maxLon}
+	case lat != nil:
+		filter.Box = &geo.Box{MinLat: *lat, MinLon: *lon, MaxLat: *lat, MaxLon: *lon}
+	}
+
+	return filter
+}
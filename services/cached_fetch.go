@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/metrics"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/SamPariatIL/weather-wrapper/usage"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"net/url"
+	"time"
+)
+
+// backgroundRefreshTimeout bounds refreshing a stale entry, retries included.
+const backgroundRefreshTimeout = time.Second * 30
+
+// cachedFetcher serves upstream resources through the cache of a service.
+// Concurrent misses of the same request share one upstream fetch: within
+// the process through singleflight, across replicas through a lock in the
+// shared cache.
+type cachedFetcher struct {
+	upstreamClient upstream.Client
+	fallbackRepo   repository.FallbackRepository
+	lockRepo       repository.LockRepository
+	conf           config.CacheConfig
+	upstreamConf   config.UpstreamConfig
+	refreshPool    RefreshPool
+	fetches        singleflight.Group
+	logger         *zap.Logger
+}
+
+func newCachedFetcher(uc upstream.Client, fr repository.FallbackRepository, lr repository.LockRepository, rp RefreshPool, conf *config.Config, zl *zap.Logger) *cachedFetcher {
+	return &cachedFetcher{
+		upstreamClient: uc,
+		fallbackRepo:   fr,
+		lockRepo:       lr,
+		refreshPool:    rp,
+		conf:           conf.CacheConfig,
+		upstreamConf:   conf.UpstreamConfig,
+		logger:         zl,
+	}
+}
+
+// cachedResource describes one upstream request and how its response of type
+// R is cached as a value of type T.
+type cachedResource[R, T any] struct {
+	endpoint upstream.Endpoint
+	params   url.Values
+	get      func(ctx context.Context) (*repository.CacheEntry[T], error)
+	set      func(ctx context.Context, value *T) error
+	// convert turns the upstream response into the cached value, or fails
+	// when the response is unusable.
+	convert func(response *R) (*T, error)
+	// cacheable reports whether a usable value is worth caching; without it
+	// every usable value is cached.
+	cacheable func(value *T) bool
+	// setLastKnown and getLastKnown keep and read the last known good
+	// response. Without them the response is kept whole under the endpoint
+	// and params of the request.
+	setLastKnown func(ctx context.Context, response *R) error
+	getLastKnown func(ctx context.Context, response *R) (*time.Time, error)
+}
+
+// key identifies the request of the resource. Encode sorts the params, so
+// equal requests share a key.
+func (cr cachedResource[R, T]) key() string {
+	return string(cr.endpoint) + "?" + cr.params.Encode()
+}
+
+func (cr cachedResource[R, T]) isCacheable(value *T) bool {
+	return cr.cacheable == nil || cr.cacheable(value)
+}
+
+func (cr cachedResource[R, T]) keepLastKnown(ctx context.Context, fr repository.FallbackRepository, response *R) error {
+	if cr.setLastKnown != nil {
+		return cr.setLastKnown(ctx, response)
+	}
+
+	return fr.SetLastKnown(ctx, string(cr.endpoint), cr.params, response)
+}
+
+func (cr cachedResource[R, T]) readLastKnown(ctx context.Context, fr repository.FallbackRepository, response *R) (*time.Time, error) {
+	if cr.getLastKnown != nil {
+		return cr.getLastKnown(ctx, response)
+	}
+
+	return fr.GetLastKnown(ctx, string(cr.endpoint), cr.params, response)
+}
+
+// fetchResult is a value loaded on a cache miss. lastKnownGoodAt is set when
+// upstream was down and the value is the last known good response.
+type fetchResult[T any] struct {
+	value           *T
+	lastKnownGoodAt time.Time
+}
+
+// snap snaps the requested coordinates to the cache cell of endpoint and
+// records the location served by the response.
+func (cf *cachedFetcher) snap(ctx context.Context, endpoint upstream.Endpoint, lat, lon float32) geo.Location {
+	location := geo.Snap(cf.conf.SnapRules[string(endpoint)], float64(lat), float64(lon))
+	freshness.MarkLocation(ctx, location)
+
+	return location
+}
+
+// lockTTL returns how long the lock of a request to endpoint is held: the
+// configured TTL, or as long as a fetch may take with every retry when that
+// is longer, so the lock does not expire while its holder is still fetching.
+func (cf *cachedFetcher) lockTTL(endpoint upstream.Endpoint) time.Duration {
+	timeout, ok := cf.upstreamConf.Timeouts[string(endpoint)]
+	if !ok {
+		timeout = cf.upstreamConf.Timeout
+	}
+
+	retries := time.Duration(max(cf.upstreamConf.Retries, 0))
+	fetch := timeout*(retries+1) + cf.upstreamConf.RetryMaxDelay*retries
+
+	return max(cf.conf.LockTTL, fetch)
+}
+
+func asIs[T any](response *T) (*T, error) {
+	return response, nil
+}
+
+// getCached serves the resource from the cache while its entry is fresh.
+// Stale entries are served right away while a background refresh replaces
+// them. Without an entry upstream is called, and when it is down the last
+// known good response is served instead. Cache failures are logged and
+// counted, never returned: reads fall through to upstream and writes are
+// skipped.
+func getCached[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (*T, error) {
+	entry, err := resource.get(ctx)
+	if err != nil {
+		cacheFailed(cf, metrics.CacheRead, resource, err)
+	}
+
+	if entry != nil {
+		usage.MarkCacheHit(ctx)
+
+		if entry.IsStale() {
+			freshness.MarkStale(ctx, entry.FetchedAt)
+			refreshInBackground(cf, resource)
+		} else {
+			freshness.MarkCached(ctx, entry.FetchedAt)
+		}
+
+		return &entry.Value, nil
+	}
+
+	result, err := loadCoalesced(ctx, cf, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.lastKnownGoodAt.IsZero() {
+		freshness.MarkLastKnownGood(ctx, result.lastKnownGoodAt)
+	}
+
+	return result.value, nil
+}
+
+// loadCoalesced loads the resource on a cache miss, sharing the load with
+// concurrent misses of the same request in this process. The load outlives
+// the caller that started it, so the others still get its result if that
+// caller goes away.
+func loadCoalesced[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (fetchResult[T], error) {
+	key := resource.key()
+	leader := false
+
+	results := cf.fetches.DoChan(key, func() (any, error) {
+		leader = true
+		return loadLocked(context.WithoutCancel(ctx), cf, resource)
+	})
+
+	select {
+	case <-ctx.Done():
+		return fetchResult[T]{}, ctx.Err()
+	case res := <-results:
+		if !leader {
+			// The followers did not call upstream themselves, so they count
+			// as served from the cache.
+			usage.MarkCacheHit(ctx)
+			metrics.Count(metrics.CoalescedInProcess, string(resource.endpoint))
+		}
+
+		if res.Err != nil {
+			return fetchResult[T]{}, res.Err
+		}
+
+		return res.Val.(fetchResult[T]), nil
+	}
+}
+
+// loadLocked loads the resource while holding its lock in Redis. When another
+// replica holds the lock, it waits for that replica to cache the value, and
+// only calls upstream itself when the wait times out or the lock is freed
+// without a value.
+func loadLocked[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (fetchResult[T], error) {
+	key := resource.key()
+	endpoint := string(resource.endpoint)
+
+	token, err := cf.lockRepo.Acquire(ctx, key, cf.lockTTL(resource.endpoint))
+	if err != nil {
+		cf.logger.Warn(fmt.Sprintf("Failed to lock %s, fetching without the lock: %v", key, err))
+		metrics.Count(metrics.LockErrors, endpoint)
+		return load(ctx, cf, resource)
+	}
+
+	if token == "" {
+		metrics.Count(metrics.CoalescedDistributed, endpoint)
+
+		var value *T
+		value, token, err = waitForLock(ctx, cf, resource)
+		if err != nil {
+			return fetchResult[T]{}, err
+		}
+
+		if value != nil {
+			usage.MarkCacheHit(ctx)
+			return fetchResult[T]{value: value}, nil
+		}
+	}
+
+	if token != "" {
+		defer func() {
+			if err := cf.lockRepo.Release(ctx, key, token); err != nil {
+				cf.logger.Warn(fmt.Sprintf("Failed to release the lock of %s: %v", key, err))
+			}
+		}()
+
+		// Another replica may have cached the value between the miss and
+		// taking the lock.
+		entry, err := resource.get(ctx)
+		if err != nil {
+			cacheFailed(cf, metrics.CacheRead, resource, err)
+		}
+
+		if entry != nil && !entry.IsStale() {
+			usage.MarkCacheHit(ctx)
+			return fetchResult[T]{value: &entry.Value}, nil
+		}
+	}
+
+	return load(ctx, cf, resource)
+}
+
+// waitForLock polls the cache until the replica holding the lock of the
+// resource has cached its value. When the lock is freed without a value it is
+// taken over and its token returned; when the wait times out or the cache
+// fails both are empty.
+func waitForLock[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (*T, string, error) {
+	key := resource.key()
+
+	ticker := time.NewTicker(cf.conf.LockPollInterval)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(cf.conf.LockWait)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-timeout.C:
+			cf.logger.Warn(fmt.Sprintf("Timed out waiting for the lock of %s", key))
+			metrics.Count(metrics.LockWaitTimeouts, string(resource.endpoint))
+			return nil, "", nil
+		case <-ticker.C:
+		}
+
+		entry, err := resource.get(ctx)
+		if err != nil {
+			cacheFailed(cf, metrics.CacheRead, resource, err)
+			return nil, "", nil
+		}
+
+		if entry != nil {
+			return &entry.Value, "", nil
+		}
+
+		token, err := cf.lockRepo.Acquire(ctx, key, cf.lockTTL(resource.endpoint))
+		if err != nil {
+			cf.logger.Warn(fmt.Sprintf("Failed to lock %s, fetching without the lock: %v", key, err))
+			metrics.Count(metrics.LockErrors, string(resource.endpoint))
+			return nil, "", nil
+		}
+
+		if token != "" {
+			return nil, token, nil
+		}
+	}
+}
+
+// load calls upstream and caches the response if it can and should. When upstream is
+// down the last known good response is returned instead, without caching it.
+func load[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (fetchResult[T], error) {
+	usage.MarkUpstreamCall(ctx)
+	metrics.Count(metrics.UpstreamFetches, string(resource.endpoint))
+
+	value, err := fetchUpstream(ctx, cf, resource)
+	if errors.Is(err, upstream.ErrUnavailable) {
+		lastKnown, fetchedAt := getLastKnownGood(ctx, cf, resource)
+		if lastKnown != nil {
+			cf.logger.Warn(fmt.Sprintf("Serving the last known good %s response: %v", resource.endpoint, err))
+			return fetchResult[T]{value: lastKnown, lastKnownGoodAt: fetchedAt}, nil
+		}
+	}
+
+	if err != nil {
+		return fetchResult[T]{}, err
+	}
+
+	if !resource.isCacheable(value) {
+		return fetchResult[T]{value: value}, nil
+	}
+
+	err = resource.set(ctx, value)
+	if err != nil {
+		cacheFailed(cf, metrics.CacheWrite, resource, err)
+	}
+
+	return fetchResult[T]{value: value}, nil
+}
+
+// fetchUpstream calls upstream and keeps usable, cacheable responses as the
+// last known good response of the request.
+func fetchUpstream[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (*T, error) {
+	var response R
+
+	err := cf.upstreamClient.Get(ctx, resource.endpoint, resource.params, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := resource.convert(&response)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resource.isCacheable(value) {
+		return value, nil
+	}
+
+	err = resource.keepLastKnown(ctx, cf.fallbackRepo, &response)
+	if err != nil {
+		cf.logger.Error(fmt.Sprintf("Failed to keep the last known %s response: %v", resource.endpoint, err))
+	}
+
+	return value, nil
+}
+
+func getLastKnownGood[R, T any](ctx context.Context, cf *cachedFetcher, resource cachedResource[R, T]) (*T, time.Time) {
+	var response R
+
+	fetchedAt, err := resource.readLastKnown(ctx, cf.fallbackRepo, &response)
+	if err != nil {
+		cf.logger.Error(fmt.Sprintf("Failed to read the last known %s response: %v", resource.endpoint, err))
+		return nil, time.Time{}
+	}
+
+	if fetchedAt == nil {
+		return nil, time.Time{}
+	}
+
+	value, err := resource.convert(&response)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	return value, *fetchedAt
+}
+
+// refreshInBackground replaces the cache entry of the resource in the refresh
+// pool, unless this or another replica is already refreshing it. Failures
+// keep the stale entry until its hard TTL.
+func refreshInBackground[R, T any](cf *cachedFetcher, resource cachedResource[R, T]) {
+	key := resource.key()
+
+	cf.refreshPool.run(key, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, backgroundRefreshTimeout)
+		defer cancel()
+
+		token, err := cf.lockRepo.Acquire(ctx, key, cf.lockTTL(resource.endpoint))
+		if err != nil {
+			cf.logger.Warn(fmt.Sprintf("Failed to lock %s, refreshing without the lock: %v", key, err))
+			metrics.Count(metrics.LockErrors, string(resource.endpoint))
+		} else if token == "" {
+			return
+		} else {
+			defer func() {
+				if err := cf.lockRepo.Release(context.WithoutCancel(ctx), key, token); err != nil {
+					cf.logger.Warn(fmt.Sprintf("Failed to release the lock of %s: %v", key, err))
+				}
+			}()
+		}
+
+		metrics.Count(metrics.UpstreamFetches, string(resource.endpoint))
+
+		value, err := fetchUpstream(ctx, cf, resource)
+		if err != nil {
+			cf.logger.Warn(fmt.Sprintf("Failed to refresh the stale %s response: %v", resource.endpoint, err))
+			return
+		}
+
+		if !resource.isCacheable(value) {
+			return
+		}
+
+		err = resource.set(ctx, value)
+		if err != nil {
+			cacheFailed(cf, metrics.CacheWrite, resource, err)
+		}
+	})
+}
+
+func cacheFailed[R, T any](cf *cachedFetcher, operation string, resource cachedResource[R, T], err error) {
+	cf.logger.Warn(fmt.Sprintf("Cache %s of %s failed: %v", operation, resource.key(), err))
+	metrics.CountCacheError(operation)
+}
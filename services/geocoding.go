@@ -2,132 +2,76 @@ package services
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
 	"go.uber.org/zap"
-	"net/http"
+	"net/url"
+	"strconv"
 )
 
 type GeocodingService interface {
-	GetGeocodeForCity(city string, limit int) (*entities.Coord, error)
-	GetCityFromLatLon(lat, lon float32) (*string, error)
+	GetGeocodeForCity(ctx context.Context, city string, limit int) (*entities.Coord, error)
+	GetCityFromLatLon(ctx context.Context, lat, lon float32) (*string, error)
 }
 
 type geocodingService struct {
 	geocodingRepo repository.GeocodingRepository
+	fetcher       *cachedFetcher
 	logger        *zap.Logger
 }
 
-func NewGeocodingService(gr repository.GeocodingRepository, zl *zap.Logger) GeocodingService {
+func NewGeocodingService(gr repository.GeocodingRepository, fr repository.FallbackRepository, lr repository.LockRepository, uc upstream.Client, rp RefreshPool, conf *config.Config, zl *zap.Logger) GeocodingService {
 	return &geocodingService{
 		geocodingRepo: gr,
+		fetcher:       newCachedFetcher(uc, fr, lr, rp, conf, zl),
 		logger:        zl,
 	}
 }
 
-func (gs *geocodingService) GetGeocodeForCity(city string, limit int) (*entities.Coord, error) {
-	conf := config.GetConfig()
-
-	var err error
-
-	savedGeocode, err := gs.geocodingRepo.GetGeocodeForCity(context.Background(), city, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	if savedGeocode != nil {
-		return savedGeocode, nil
-	}
-
-	url := fmt.Sprintf(
-		"https://%s/direct?q=%s&limit=%d&appid=%s",
-		conf.GeocodeConfig.BaseURL,
-		city,
-		limit,
-		conf.GeocodeConfig.APIKey,
-	)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, nil
-	}
-
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	var geocodes []entities.Geocode
-
-	err = json.NewDecoder(resp.Body).Decode(&geocodes)
-	if err != nil {
-		return nil, err
-	}
-
-	coords := entities.Coord{
-		Lat: geocodes[0].Lat,
-		Lon: geocodes[0].Lon,
-	}
-
-	err = gs.geocodingRepo.SetGeocodeForCity(context.Background(), city, limit, &coords)
-	if err != nil {
-		return nil, err
-	}
-
-	return &coords, nil
+func (gs *geocodingService) GetGeocodeForCity(ctx context.Context, city string, limit int) (*entities.Coord, error) {
+	return getCached(ctx, gs.fetcher, cachedResource[[]entities.Geocode, entities.Coord]{
+		endpoint: upstream.GeocodeDirect,
+		params:   url.Values{"q": {city}, "limit": {strconv.Itoa(limit)}},
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.Coord], error) {
+			return gs.geocodingRepo.GetGeocodeForCity(ctx, city, limit)
+		},
+		set: func(ctx context.Context, coords *entities.Coord) error {
+			return gs.geocodingRepo.SetGeocodeForCity(ctx, city, limit, coords)
+		},
+		convert: func(geocodes *[]entities.Geocode) (*entities.Coord, error) {
+			if len(*geocodes) == 0 {
+				return nil, fmt.Errorf("%w: no geocode found for %s", upstream.ErrNotFound, city)
+			}
+
+			return &entities.Coord{
+				Lat: (*geocodes)[0].Lat,
+				Lon: (*geocodes)[0].Lon,
+			}, nil
+		},
+	})
 }
 
-func (gs *geocodingService) GetCityFromLatLon(lat, lon float32) (*string, error) {
-	conf := config.GetConfig()
-
-	var err error
-
-	savedCity, err := gs.geocodingRepo.GetCityFromLatLon(context.Background(), lat, lon)
-	if err != nil {
-		return nil, err
-	}
-
-	if savedCity != nil {
-		return savedCity, nil
-	}
-
-	url := fmt.Sprintf(
-		"https://%s/reverse?lat=%f&lon=%f&limit=1&appid=%s",
-		conf.GeocodeConfig.BaseURL,
-		lat,
-		lon,
-		conf.GeocodeConfig.APIKey,
-	)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	var geocodes []entities.Geocode
-
-	err = json.NewDecoder(resp.Body).Decode(&geocodes)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(geocodes) == 0 {
-		return nil, errors.New("no city found")
-	}
-
-	city := geocodes[0].Name
-
-	err = gs.geocodingRepo.SetCityFromLatLon(context.Background(), lat, lon, city)
-	if err != nil {
-		return nil, err
-	}
-
-	return &city, nil
+func (gs *geocodingService) GetCityFromLatLon(ctx context.Context, lat, lon float32) (*string, error) {
+	location := gs.fetcher.snap(ctx, upstream.GeocodeReverse, lat, lon)
+
+	return getCached(ctx, gs.fetcher, cachedResource[[]entities.Geocode, string]{
+		endpoint: upstream.GeocodeReverse,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon), "limit", "1"),
+		get: func(ctx context.Context) (*repository.CacheEntry[string], error) {
+			return gs.geocodingRepo.GetCityFromLatLon(ctx, location)
+		},
+		set: func(ctx context.Context, city *string) error {
+			return gs.geocodingRepo.SetCityFromLatLon(ctx, location, *city)
+		},
+		convert: func(geocodes *[]entities.Geocode) (*string, error) {
+			if len(*geocodes) == 0 {
+				return nil, fmt.Errorf("%w: no city found", upstream.ErrNotFound)
+			}
+
+			return &(*geocodes)[0].Name, nil
+		},
+	})
 }
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type HealthService interface {
+	GetHealth(ctx context.Context) *entities.Health
+}
+
+type healthService struct {
+	upstreamClient upstream.Client
+	redisClient    *redis.Client
+	cacheBreaker   cache.BreakerCache
+	conf           *config.Config
+	logger         *zap.Logger
+}
+
+// NewHealthService reports on cb, the circuit breaker in front of Redis, unless
+// it is nil.
+func NewHealthService(uc upstream.Client, rc *redis.Client, cb cache.BreakerCache, zl *zap.Logger) HealthService {
+	return &healthService{
+		upstreamClient: uc,
+		redisClient:    rc,
+		cacheBreaker:   cb,
+		conf:           config.GetConfig(),
+		logger:         zl,
+	}
+}
+
+func (hs *healthService) GetHealth(ctx context.Context) *entities.Health {
+	health := &entities.Health{
+		Status: entities.HealthOK,
+		Cache:  hs.getCacheHealth(ctx),
+	}
+
+	breakerOpen := health.Cache.Breaker != "" && health.Cache.Breaker != cache.BreakerClosed
+	if health.Cache.Redis != entities.DependencyUp || breakerOpen {
+		health.Status = entities.HealthDegraded
+	}
+
+	for _, breaker := range hs.upstreamClient.Breakers() {
+		if breaker.State != upstream.BreakerClosed {
+			health.Status = entities.HealthDegraded
+		}
+
+		health.Breakers = append(health.Breakers, entities.CircuitBreaker{
+			Endpoint:            string(breaker.Endpoint),
+			State:               breaker.State,
+			ConsecutiveFailures: breaker.ConsecutiveFailures,
+			OpenedAt:            breaker.OpenedAt,
+		})
+	}
+
+	return health
+}
+
+// getCacheHealth pings Redis and reports the state of the circuit breaker in
+// front of it. While Redis is down or the breaker is not closed, responses are
+// served from upstream, or from the local tier of a tiered cache, and rate
+// limits and quotas let requests through.
+func (hs *healthService) getCacheHealth(ctx context.Context) entities.CacheHealth {
+	cacheHealth := entities.CacheHealth{
+		Backend: hs.conf.CacheConfig.Backend,
+		Redis:   entities.DependencyUp,
+	}
+
+	if hs.cacheBreaker != nil {
+		cacheHealth.Breaker = hs.cacheBreaker.State()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hs.conf.RedisConfig.Timeout)
+	defer cancel()
+
+	err := hs.redisClient.Ping(ctx).Err()
+	if err != nil {
+		cacheHealth.Redis = entities.DependencyDown
+		cacheHealth.Error = err.Error()
+	}
+
+	return cacheHealth
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// RefreshPool runs the background refreshes of stale cache entries for the
+// services sharing it. At most size refreshes run at once; stale entries
+// found while every slot is taken are served without one.
+type RefreshPool interface {
+	// Stop cancels the running refreshes and waits for them to return.
+	// Refreshes asked for after Stop are not run.
+	Stop()
+	// run runs refresh under key, unless a refresh of key is running already
+	// or every slot is taken.
+	run(key string, refresh func(ctx context.Context))
+}
+
+type refreshPool struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	slots      chan struct{}
+	running    sync.WaitGroup
+	refreshing sync.Map
+	logger     *zap.Logger
+}
+
+func NewRefreshPool(size int, zl *zap.Logger) RefreshPool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &refreshPool{
+		ctx:    ctx,
+		cancel: cancel,
+		slots:  make(chan struct{}, max(size, 1)),
+		logger: zl,
+	}
+}
+
+func (rp *refreshPool) run(key string, refresh func(ctx context.Context)) {
+	if rp.ctx.Err() != nil {
+		return
+	}
+
+	if _, running := rp.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	select {
+	case rp.slots <- struct{}{}:
+	default:
+		rp.refreshing.Delete(key)
+		rp.logger.Debug(fmt.Sprintf("Skipping the refresh of %s, every refresh slot is taken", key))
+		return
+	}
+
+	rp.running.Add(1)
+
+	go func() {
+		defer rp.running.Done()
+		defer func() { <-rp.slots }()
+		defer rp.refreshing.Delete(key)
+
+		refresh(rp.ctx)
+	}()
+}
+
+func (rp *refreshPool) Stop() {
+	rp.cancel()
+	rp.running.Wait()
+}
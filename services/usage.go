@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/usage"
+	"go.uber.org/zap"
+	"time"
+)
+
+const usageMonthFormat = "2006-01"
+
+type UsageService interface {
+	Start()
+	// Stop flushes the recorded requests not flushed yet and waits for the
+	// flush, so that shutting down loses none of them.
+	Stop()
+	Record(record usage.Record)
+	ConsumeQuota(ctx context.Context, subject, plan string) (bool, error)
+	GetUsageReport(uid string, month time.Time) (*entities.UsageReport, error)
+}
+
+type usageRollupKey struct {
+	principal string
+	route     string
+	day       time.Time
+}
+
+type usageService struct {
+	usageRepo repository.UsageRepository
+	records   chan usage.Record
+	stop      chan struct{}
+	stopped   chan struct{}
+	logger    *zap.Logger
+}
+
+func NewUsageService(ur repository.UsageRepository, zl *zap.Logger) UsageService {
+	conf := config.GetConfig()
+
+	return &usageService{
+		usageRepo: ur,
+		records:   make(chan usage.Record, conf.UsageConfig.BufferSize),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		logger:    zl,
+	}
+}
+
+// Start rolls recorded requests up in memory and flushes them to Postgres
+// every flush interval.
+func (us *usageService) Start() {
+	conf := config.GetConfig()
+
+	go func() {
+		ticker := time.NewTicker(conf.UsageConfig.FlushInterval)
+		defer ticker.Stop()
+
+		pending := make(map[usageRollupKey]*entities.UsageRollup)
+
+		for {
+			select {
+			case record := <-us.records:
+				addToRollup(pending, record)
+			case <-ticker.C:
+				us.flush(pending)
+				pending = make(map[usageRollupKey]*entities.UsageRollup)
+			case <-us.stop:
+				us.drain(pending)
+				close(us.stopped)
+				return
+			}
+		}
+	}()
+}
+
+func (us *usageService) Stop() {
+	close(us.stop)
+	<-us.stopped
+}
+
+// Record queues a finished request for the next flush. It never blocks the
+// request: when the buffer is full the record is dropped.
+func (us *usageService) Record(record usage.Record) {
+	select {
+	case us.records <- record:
+	default:
+		us.logger.Warn("usage buffer is full, dropping usage record")
+	}
+}
+
+// ConsumeQuota counts a request against the monthly quota of subject and
+// reports whether the request is still within the quota of plan.
+func (us *usageService) ConsumeQuota(ctx context.Context, subject, plan string) (bool, error) {
+	conf := config.GetConfig()
+
+	quota, limited := conf.UsageConfig.Quotas[plan]
+	if !limited {
+		return true, nil
+	}
+
+	count, err := us.usageRepo.IncrementMonthlyRequests(ctx, subject, time.Now().UTC().Format(usageMonthFormat))
+	if err != nil {
+		return true, err
+	}
+
+	return count <= quota, nil
+}
+
+// GetUsageReport returns the daily totals of uid within month and the
+// monthly totals of the twelve months up to and including it.
+func (us *usageService) GetUsageReport(uid string, month time.Time) (*entities.UsageReport, error) {
+	ctx := context.Background()
+
+	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	daily, err := us.usageRepo.GetDailyUsage(ctx, uid, monthStart, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	monthly, err := us.usageRepo.GetMonthlyUsage(ctx, uid, monthStart.AddDate(0, -11, 0), monthEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.UsageReport{
+		UID:     uid,
+		Month:   monthStart.Format(usageMonthFormat),
+		Daily:   daily,
+		Monthly: monthly,
+	}, nil
+}
+
+func (us *usageService) flush(pending map[usageRollupKey]*entities.UsageRollup) {
+	if len(pending) == 0 {
+		return
+	}
+
+	rollups := make([]entities.UsageRollup, 0, len(pending))
+	for _, rollup := range pending {
+		rollups = append(rollups, *rollup)
+	}
+
+	err := us.usageRepo.UpsertUsageRollups(context.Background(), rollups)
+	if err != nil {
+		us.logger.Error(err.Error())
+	}
+}
+
+// drain flushes pending along with the records still buffered.
+func (us *usageService) drain(pending map[usageRollupKey]*entities.UsageRollup) {
+	for {
+		select {
+		case record := <-us.records:
+			addToRollup(pending, record)
+		default:
+			us.flush(pending)
+			return
+		}
+	}
+}
+
+func addToRollup(pending map[usageRollupKey]*entities.UsageRollup, record usage.Record) {
+	timestamp := record.Timestamp.UTC()
+	key := usageRollupKey{
+		principal: record.Principal,
+		route:     record.Route,
+		day:       time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, time.UTC),
+	}
+
+	rollup, exists := pending[key]
+	if !exists {
+		rollup = &entities.UsageRollup{
+			Principal: key.principal,
+			Route:     key.route,
+			Day:       key.day,
+			UID:       record.UID,
+		}
+		pending[key] = rollup
+	}
+
+	rollup.Requests++
+	rollup.TotalLatencyMs += record.Latency.Milliseconds()
+
+	if record.CacheHit {
+		rollup.CacheHits++
+	} else {
+		rollup.CacheMisses++
+	}
+
+	if record.UpstreamCall {
+		rollup.UpstreamCalls++
+	}
+}
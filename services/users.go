@@ -2,32 +2,63 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/mailer"
 	"github.com/SamPariatIL/weather-wrapper/repository"
 	"go.uber.org/zap"
 )
 
 type UserService interface {
+	Start()
 	CreateUser(user *entities.UserDetails) (*string, error)
 	UpdateUser(uid string, user *entities.UserDetails) (*string, error)
 	DeleteUser(uid string) (*string, error)
-	GenerateToken(uid string) (*string, error)
-	SendVerificationEmail(email string) (*string, error)
-	ResetPassword(email string) (*string, error)
+	GenerateToken(uid string) (*entities.CustomToken, error)
+	ExchangeCredentials(email, password string) (*entities.Session, error)
+	RefreshSession(refreshToken string) (*entities.Session, error)
+	SendVerificationEmail(email string)
+	ResetPassword(email string)
+	ApplyAction(mode, oobCode, newPassword string) error
+	SetUserRoles(uid string, roles []string) (*string, error)
+	ClearUserRoles(uid string) (*string, error)
+}
+
+type actionEmail struct {
+	email    string
+	template string
 }
 
 type userService struct {
 	userRepo repository.UserRepository
+	mailer   mailer.Mailer
+	emails   chan actionEmail
 	logger   *zap.Logger
 }
 
-func NewUserService(ur repository.UserRepository, zl *zap.Logger) UserService {
+func NewUserService(ur repository.UserRepository, m mailer.Mailer, zl *zap.Logger) UserService {
+	conf := config.GetConfig()
+
 	return &userService{
 		userRepo: ur,
+		mailer:   m,
+		emails:   make(chan actionEmail, conf.MailerConfig.QueueSize),
 		logger:   zl,
 	}
 }
 
+// Start sends queued verification and password reset emails one at a time.
+func (us *userService) Start() {
+	go func() {
+		for email := range us.emails {
+			us.sendActionEmail(email)
+		}
+	}()
+}
+
 func (us *userService) CreateUser(user *entities.UserDetails) (*string, error) {
 	userId, err := us.userRepo.CreateUser(context.Background(), user)
 	if err != nil {
@@ -55,7 +86,7 @@ func (us *userService) DeleteUser(uid string) (*string, error) {
 	return userId, nil
 }
 
-func (us *userService) GenerateToken(uid string) (*string, error) {
+func (us *userService) GenerateToken(uid string) (*entities.CustomToken, error) {
 	token, err := us.userRepo.GenerateToken(context.Background(), uid)
 	if err != nil {
 		return nil, err
@@ -64,20 +95,89 @@ func (us *userService) GenerateToken(uid string) (*string, error) {
 	return token, nil
 }
 
-func (us *userService) SendVerificationEmail(email string) (*string, error) {
-	link, err := us.userRepo.SendVerificationEmail(context.Background(), email)
+func (us *userService) ExchangeCredentials(email, password string) (*entities.Session, error) {
+	session, err := us.userRepo.ExchangeCredentials(context.Background(), email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (us *userService) RefreshSession(refreshToken string) (*entities.Session, error) {
+	session, err := us.userRepo.RefreshSession(context.Background(), refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// SendVerificationEmail queues a verification email. Links are generated and
+// sent in the background, so callers cannot tell whether the address belongs
+// to an account.
+func (us *userService) SendVerificationEmail(email string) {
+	us.queueActionEmail(actionEmail{email: email, template: mailer.TemplateVerifyEmail})
+}
+
+// ResetPassword queues a password reset email, see SendVerificationEmail.
+func (us *userService) ResetPassword(email string) {
+	us.queueActionEmail(actionEmail{email: email, template: mailer.TemplateResetPassword})
+}
+
+func (us *userService) ApplyAction(mode, oobCode, newPassword string) error {
+	return us.userRepo.ApplyAction(context.Background(), mode, oobCode, newPassword)
+}
+
+func (us *userService) SetUserRoles(uid string, roles []string) (*string, error) {
+	userId, err := us.userRepo.SetUserRoles(context.Background(), uid, roles)
 	if err != nil {
 		return nil, err
 	}
 
-	return link, nil
+	return userId, nil
 }
 
-func (us *userService) ResetPassword(email string) (*string, error) {
-	link, err := us.userRepo.ResetPassword(context.Background(), email)
+func (us *userService) ClearUserRoles(uid string) (*string, error) {
+	userId, err := us.userRepo.ClearUserRoles(context.Background(), uid)
 	if err != nil {
 		return nil, err
 	}
 
-	return link, nil
+	return userId, nil
+}
+
+func (us *userService) queueActionEmail(email actionEmail) {
+	select {
+	case us.emails <- email:
+	default:
+		us.logger.Warn(fmt.Sprintf("email queue is full, dropping %s email", email.template))
+	}
+}
+
+func (us *userService) sendActionEmail(email actionEmail) {
+	ctx := context.Background()
+
+	var link *string
+	var err error
+
+	switch email.template {
+	case mailer.TemplateVerifyEmail:
+		link, err = us.userRepo.EmailVerificationLink(ctx, email.email)
+	case mailer.TemplateResetPassword:
+		link, err = us.userRepo.PasswordResetLink(ctx, email.email)
+	}
+
+	if errors.Is(err, identity.ErrUserNotFound) {
+		us.logger.Info(fmt.Sprintf("not sending %s email, no account uses the address", email.template))
+		return
+	} else if err != nil {
+		us.logger.Error(err.Error())
+		return
+	}
+
+	err = us.mailer.Send(ctx, email.email, email.template, mailer.LinkData{Email: email.email, Link: *link})
+	if err != nil {
+		us.logger.Error(fmt.Sprintf("failed to send %s email: %s", email.template, err.Error()))
+	}
 }
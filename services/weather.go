@@ -2,120 +2,60 @@ package services
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"github.com/SamPariatIL/weather-wrapper/config"
 	"github.com/SamPariatIL/weather-wrapper/entities"
 	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
 	"go.uber.org/zap"
-	"net/http"
 )
 
 type WeatherService interface {
-	GetCurrentWeather(latitude, longitude float32) (*entities.CurrentWeather, error)
-	GetFiveDayForecast(latitude, longitude float32) (*entities.Forecast, error)
+	GetCurrentWeather(ctx context.Context, latitude, longitude float32) (*entities.CurrentWeather, error)
+	GetFiveDayForecast(ctx context.Context, latitude, longitude float32) (*entities.Forecast, error)
 }
 
 type weatherService struct {
 	weatherRepo repository.WeatherRepository
+	fetcher     *cachedFetcher
 	logger      *zap.Logger
 }
 
-func NewWeatherService(wr repository.WeatherRepository, zl *zap.Logger) WeatherService {
+func NewWeatherService(wr repository.WeatherRepository, fr repository.FallbackRepository, lr repository.LockRepository, uc upstream.Client, rp RefreshPool, conf *config.Config, zl *zap.Logger) WeatherService {
 	return &weatherService{
 		weatherRepo: wr,
+		fetcher:     newCachedFetcher(uc, fr, lr, rp, conf, zl),
 		logger:      zl,
 	}
 }
 
-func (ws *weatherService) GetCurrentWeather(latitude, longitude float32) (*entities.CurrentWeather, error) {
-	conf := config.GetConfig()
-
-	var err error
-
-	savedWeather, err := ws.weatherRepo.GetCurrentWeather(context.Background(), latitude, longitude)
-	if err != nil {
-		return nil, err
-	}
-
-	if savedWeather != nil {
-		return savedWeather, nil
-	}
-
-	url := fmt.Sprintf(
-		"https://%s/weather?lat=%f&lon=%f&units=metric&appid=%s",
-		conf.WeatherConfig.BaseURL,
-		latitude,
-		longitude,
-		conf.WeatherConfig.APIKey,
-	)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	var currentWeather entities.CurrentWeather
-
-	err = json.NewDecoder(resp.Body).Decode(&currentWeather)
-	if err != nil {
-		return nil, err
-	}
-
-	err = ws.weatherRepo.SetCurrentWeather(context.Background(), latitude, longitude, &currentWeather)
-	if err != nil {
-		return nil, err
-	}
-
-	return &currentWeather, err
+func (ws *weatherService) GetCurrentWeather(ctx context.Context, latitude, longitude float32) (*entities.CurrentWeather, error) {
+	location := ws.fetcher.snap(ctx, upstream.CurrentWeather, latitude, longitude)
+
+	return getCached(ctx, ws.fetcher, cachedResource[entities.CurrentWeather, entities.CurrentWeather]{
+		endpoint: upstream.CurrentWeather,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon), "units", "metric"),
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.CurrentWeather], error) {
+			return ws.weatherRepo.GetCurrentWeather(ctx, location)
+		},
+		set: func(ctx context.Context, currentWeather *entities.CurrentWeather) error {
+			return ws.weatherRepo.SetCurrentWeather(ctx, location, currentWeather)
+		},
+		convert: asIs[entities.CurrentWeather],
+	})
 }
 
-func (ws *weatherService) GetFiveDayForecast(latitude, longitude float32) (*entities.Forecast, error) {
-	conf := config.GetConfig()
-
-	var err error
-
-	savedForecast, err := ws.weatherRepo.GetFiveDayForecast(context.Background(), latitude, longitude)
-	if err != nil {
-		return nil, err
-	}
-
-	if savedForecast != nil {
-		return savedForecast, nil
-	}
-
-	url := fmt.Sprintf(
-		"https://%s/forecast?lat=%f&lon=%f&units=metric&appid=%s",
-		conf.WeatherConfig.BaseURL,
-		latitude,
-		longitude,
-		conf.WeatherConfig.APIKey,
-	)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	var forecast entities.Forecast
-
-	err = json.NewDecoder(resp.Body).Decode(&forecast)
-	if err != nil {
-		return nil, err
-	}
-
-	err = ws.weatherRepo.SetFiveDayForecast(context.Background(), latitude, longitude, &forecast)
-	if err != nil {
-		return nil, err
-	}
-
-	return &forecast, err
+func (ws *weatherService) GetFiveDayForecast(ctx context.Context, latitude, longitude float32) (*entities.Forecast, error) {
+	location := ws.fetcher.snap(ctx, upstream.WeatherForecast, latitude, longitude)
+
+	return getCached(ctx, ws.fetcher, cachedResource[entities.Forecast, entities.Forecast]{
+		endpoint: upstream.WeatherForecast,
+		params:   upstream.LatLonParams(float32(location.Lat), float32(location.Lon), "units", "metric"),
+		get: func(ctx context.Context) (*repository.CacheEntry[entities.Forecast], error) {
+			return ws.weatherRepo.GetFiveDayForecast(ctx, location)
+		},
+		set: func(ctx context.Context, forecast *entities.Forecast) error {
+			return ws.weatherRepo.SetFiveDayForecast(ctx, location, forecast)
+		},
+		convert: asIs[entities.Forecast],
+	})
 }
@@ -0,0 +1,112 @@
+package summary
+
+import (
+	"cmp"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"math"
+	"slices"
+	"time"
+)
+
+// guidelines are the WHO 2021 air quality guideline levels, in µg/m³, of
+// the pollutants that have one. Those of PM, NO2, SO2 and CO are 24-hour
+// means and that of O3 an 8-hour mean; they are compared with each hour as
+// it is.
+var guidelines = map[string]float64{
+	aqi.PM25: 15,
+	aqi.PM10: 45,
+	aqi.O3:   100,
+	aqi.NO2:  25,
+	aqi.SO2:  40,
+	aqi.CO:   4000,
+}
+
+// Daily groups the hourly datapoints into the days of the local time at
+// timezoneOffset seconds from UTC, and summarizes every day with the index
+// of the standard.
+func Daily(datapoints []entities.AirPollutionDatapoint, timezoneOffset int, standard string) ([]entities.DailyAirPollution, error) {
+	datapoints = slices.Clone(datapoints)
+	slices.SortStableFunc(datapoints, func(a, b entities.AirPollutionDatapoint) int {
+		return cmp.Compare(a.Dt, b.Dt)
+	})
+
+	zone := time.FixedZone("", timezoneOffset)
+	days := []entities.DailyAirPollution{}
+	sums := map[string]float64{}
+
+	for _, datapoint := range datapoints {
+		index, err := aqi.Index(standard, datapoint.Components)
+		if err != nil {
+			return nil, err
+		}
+
+		local := time.Unix(int64(datapoint.Dt), 0).In(zone)
+		date := local.Format(time.DateOnly)
+
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			if len(days) > 0 {
+				finish(&days[len(days)-1], sums)
+			}
+
+			days = append(days, newDay(date))
+			clear(sums)
+		}
+
+		day := &days[len(days)-1]
+		day.Hours++
+
+		for pollutant, concentration := range aqi.Concentrations(datapoint.Components) {
+			summary := day.Pollutants[pollutant]
+			if day.Hours == 1 {
+				summary.Min, summary.Max = concentration, concentration
+			} else {
+				summary.Min, summary.Max = min(summary.Min, concentration), max(summary.Max, concentration)
+			}
+
+			day.Pollutants[pollutant] = summary
+			sums[pollutant] += concentration
+
+			if guideline, ok := guidelines[pollutant]; ok && concentration > guideline {
+				day.ExceedanceHours[pollutant]++
+			}
+		}
+
+		if day.Hours == 1 || index.Value > day.PeakAQI.AQI.Value {
+			day.PeakAQI = entities.PeakAQI{
+				Dt:   datapoint.Dt,
+				Hour: local.Format("15:04"),
+				AQI:  index,
+			}
+		}
+	}
+
+	if len(days) > 0 {
+		finish(&days[len(days)-1], sums)
+	}
+
+	return days, nil
+}
+
+func newDay(date string) entities.DailyAirPollution {
+	day := entities.DailyAirPollution{
+		Date:            date,
+		Pollutants:      map[string]entities.PollutantSummary{},
+		ExceedanceHours: make(map[string]int, len(guidelines)),
+	}
+
+	for pollutant := range guidelines {
+		day.ExceedanceHours[pollutant] = 0
+	}
+
+	return day
+}
+
+// finish sets the means of the pollutants of the day from the sums of their
+// concentrations.
+func finish(day *entities.DailyAirPollution, sums map[string]float64) {
+	for pollutant, summary := range day.Pollutants {
+		summary.Mean = math.Round(sums[pollutant]/float64(day.Hours)*100) / 100
+		day.Pollutants[pollutant] = summary
+	}
+}
@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"time"
+	_ "time/tzdata"
+)
+
+// daylightSavingZones are zones that observe daylight saving time, covering
+// the offsets at which it is observed.
+var daylightSavingZones = loadZones(
+	"America/St_Johns",
+	"America/Halifax",
+	"America/New_York",
+	"America/Havana",
+	"America/Chicago",
+	"America/Denver",
+	"America/Los_Angeles",
+	"America/Anchorage",
+	"America/Adak",
+	"America/Nuuk",
+	"America/Santiago",
+	"America/Asuncion",
+	"Pacific/Easter",
+	"Atlantic/Azores",
+	"Europe/London",
+	"Europe/Paris",
+	"Europe/Athens",
+	"Africa/Cairo",
+	"Asia/Beirut",
+	"Asia/Jerusalem",
+	"Australia/Adelaide",
+	"Australia/Sydney",
+	"Australia/Lord_Howe",
+	"Pacific/Auckland",
+	"Pacific/Chatham",
+)
+
+func loadZones(names ...string) []*time.Location {
+	zones := make([]*time.Location, 0, len(names))
+	for _, name := range names {
+		if zone, err := time.LoadLocation(name); err == nil {
+			zones = append(zones, zone)
+		}
+	}
+
+	return zones
+}
+
+// OffsetMayChange reports whether a location at timezoneOffset seconds from
+// UTC at now may have had another offset at some time between start and end.
+// The zone of the location is not known, so it does whenever a zone that
+// observes daylight saving time is at timezoneOffset at now and at another
+// offset within the range.
+func OffsetMayChange(timezoneOffset int, now time.Time, start, end int64) bool {
+	for _, zone := range daylightSavingZones {
+		if _, offset := now.In(zone).Zone(); offset != timezoneOffset {
+			continue
+		}
+
+		at := time.Unix(start, 0).In(zone)
+		for {
+			if _, offset := at.Zone(); offset != timezoneOffset {
+				return true
+			}
+
+			_, zoneEnd := at.ZoneBounds()
+			if zoneEnd.IsZero() || zoneEnd.Unix() > end {
+				break
+			}
+
+			at = zoneEnd
+		}
+	}
+
+	return false
+}
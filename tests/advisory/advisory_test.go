@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/advisory"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type AdvisorySuite struct {
+	suite.Suite
+}
+
+func indexedHour(dt, value, level int) entities.IndexedAirPollutionDatapoint {
+	return entities.IndexedAirPollutionDatapoint{
+		Dt:  dt,
+		AQI: entities.AQI{Standard: aqi.USEPA, Value: value, Level: level},
+	}
+}
+
+func (suite *AdvisorySuite) TestRisk() {
+	suite.Equal(advisory.RiskLow, advisory.Risk(entities.AQI{Standard: aqi.USEPA, Level: 1}))
+	suite.Equal(advisory.RiskElevated, advisory.Risk(entities.AQI{Standard: aqi.USEPA, Level: 3}))
+	suite.Equal(advisory.RiskSevere, advisory.Risk(entities.AQI{Standard: aqi.INNAQI, Level: 6}))
+	suite.Equal(advisory.RiskLow, advisory.Risk(entities.AQI{Standard: aqi.EUCAQI, Level: 2}))
+	suite.Equal(advisory.RiskHigh, advisory.Risk(entities.AQI{Standard: aqi.EUCAQI, Level: 5}))
+}
+
+func (suite *AdvisorySuite) TestFor() {
+	low := advisory.For(advisory.RiskLow, "en")
+	suite.Equal(advisory.RiskLow, low.Risk)
+	suite.NotEmpty(low.Summary)
+	suite.Equal(advisory.ActionEnjoyOutdoors, low.Actions[0].Code)
+	suite.Empty(low.AffectedGroups)
+
+	elevated := advisory.For(advisory.RiskElevated, "en")
+	suite.Len(elevated.AffectedGroups, 4)
+	suite.Equal(advisory.GroupChildren, elevated.AffectedGroups[0].Group)
+	suite.Equal("Children and teenagers", elevated.AffectedGroups[0].Name)
+	suite.Equal(advisory.ActionReduceExertion, elevated.AffectedGroups[0].Actions[0].Code)
+
+	suite.Equal("Niños y adolescentes", advisory.For(advisory.RiskElevated, "es").AffectedGroups[0].Name)
+	suite.Equal(elevated, advisory.For(advisory.RiskElevated, "xx"))
+}
+
+func (suite *AdvisorySuite) TestCatalogsAreComplete() {
+	english := advisory.For(advisory.RiskSevere, advisory.DefaultLocale)
+
+	for _, locale := range advisory.Locales() {
+		for _, risk := range []string{advisory.RiskLow, advisory.RiskModerate, advisory.RiskElevated, advisory.RiskHigh, advisory.RiskVeryHigh, advisory.RiskSevere} {
+			localized := advisory.For(risk, locale)
+			suite.NotEqual(risk, localized.Summary, locale)
+
+			for _, action := range localized.Actions {
+				suite.NotEqual(action.Code, action.Message, locale)
+			}
+
+			for _, group := range localized.AffectedGroups {
+				suite.NotEqual(group.Group, group.Name, locale)
+			}
+		}
+
+		if locale != advisory.DefaultLocale {
+			suite.NotEqual(english.Summary, advisory.For(advisory.RiskSevere, locale).Summary, locale)
+		}
+	}
+}
+
+func (suite *AdvisorySuite) TestLocale() {
+	suite.Equal("fr", advisory.Locale("", "fr-CH, fr;q=0.9, en;q=0.8"))
+	suite.Equal("hi", advisory.Locale("", "de;q=0.9, hi-IN;q=0.95, en;q=0.5"))
+	suite.Equal("es", advisory.Locale("es", "fr"))
+	suite.Equal("fr", advisory.Locale("xx", "fr"))
+	suite.Equal(advisory.DefaultLocale, advisory.Locale("", "de, fr;q=0"))
+	suite.Equal(advisory.DefaultLocale, advisory.Locale())
+}
+
+func (suite *AdvisorySuite) TestOutlook() {
+	outlook := advisory.Outlook([]entities.IndexedAirPollutionDatapoint{
+		indexedHour(0, 40, 1),
+		indexedHour(3600, 45, 1),
+		indexedHour(7200, 120, 3),
+		indexedHour(10800, 110, 3),
+		indexedHour(14400, 30, 1),
+		// A gap in the forecast starts a new period.
+		indexedHour(21600, 20, 1),
+	})
+
+	suite.Equal([]entities.AirQualityPeriod{
+		{Start: 0, End: 7200, Risk: advisory.RiskLow, PeakAQI: 45, MeanAQI: 43},
+		{Start: 7200, End: 14400, Risk: advisory.RiskElevated, PeakAQI: 120, MeanAQI: 115},
+		{Start: 14400, End: 18000, Risk: advisory.RiskLow, PeakAQI: 30, MeanAQI: 30},
+		{Start: 21600, End: 25200, Risk: advisory.RiskLow, PeakAQI: 20, MeanAQI: 20},
+	}, outlook)
+
+	windows := advisory.SafestWindows(outlook, 2, "en")
+	suite.Len(windows, 2)
+	suite.Equal(21600, windows[0].Start)
+	suite.Equal(14400, windows[1].Start)
+	suite.Equal(advisory.RiskLow, windows[0].Advisory.Risk)
+
+	suite.Empty(advisory.SafestWindows(nil, 3, "en"))
+}
+
+func TestAdvisorySuite(t *testing.T) {
+	suite.Run(t, &AdvisorySuite{})
+}
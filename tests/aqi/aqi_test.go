@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type AQISuite struct {
+	suite.Suite
+}
+
+func (suite *AQISuite) TestUSEPA() {
+	index, err := aqi.Index(aqi.USEPA, entities.AirPollutionComponents{PM25: 12, PM10: 20})
+	suite.NoError(err)
+	suite.Equal(56, index.Value)
+	suite.Equal(aqi.PM25, index.DominantPollutant)
+	suite.Equal("Moderate", index.Category)
+	suite.Equal(2, index.Level)
+	suite.Equal(19, index.SubIndices[aqi.PM10])
+	suite.NotContains(index.SubIndices, aqi.NH3)
+
+	// 140 µg/m³ of ozone is 0.0713 ppm, truncated to 0.071.
+	index, err = aqi.Index(aqi.USEPA, entities.AirPollutionComponents{O3: 140})
+	suite.NoError(err)
+	suite.Equal(101, index.Value)
+	suite.Equal(aqi.O3, index.DominantPollutant)
+	suite.Equal("Unhealthy for Sensitive Groups", index.Category)
+}
+
+func (suite *AQISuite) TestUSEPAOzoneAbove8HourBreakpoints() {
+	ozone := func(ppm float64) float64 { return ppm * 1000 * 48 / 24.45 }
+
+	// 0.2 ppm is the last 8-hour breakpoint.
+	index, err := aqi.Index(aqi.USEPA, entities.AirPollutionComponents{O3: ozone(0.2)})
+	suite.NoError(err)
+	suite.Equal(300, index.Value)
+
+	// Past it, the index continues into 301-500 and never goes down.
+	index, err = aqi.Index(aqi.USEPA, entities.AirPollutionComponents{O3: ozone(0.201)})
+	suite.NoError(err)
+	suite.Equal(301, index.Value)
+	suite.Equal("Hazardous", index.Category)
+
+	previous := 300
+	for ppb := 200; ppb <= 210; ppb++ {
+		index, err = aqi.Index(aqi.USEPA, entities.AirPollutionComponents{O3: ozone(float64(ppb) / 1000)})
+		suite.NoError(err)
+		suite.GreaterOrEqual(index.Value, previous, ppb)
+		previous = index.Value
+	}
+
+	index, err = aqi.Index(aqi.USEPA, entities.AirPollutionComponents{O3: ozone(0.604)})
+	suite.NoError(err)
+	suite.Equal(500, index.Value)
+}
+
+func (suite *AQISuite) TestEUCAQI() {
+	index, err := aqi.Index(aqi.EUCAQI, entities.AirPollutionComponents{NO2: 150, PM10: 20})
+	suite.NoError(err)
+	suite.Equal(63, index.Value)
+	suite.Equal(aqi.NO2, index.DominantPollutant)
+	suite.Equal("Medium", index.Category)
+
+	// Past the last breakpoint the index keeps rising.
+	index, err = aqi.Index(aqi.EUCAQI, entities.AirPollutionComponents{PM10: 270})
+	suite.NoError(err)
+	suite.Equal(125, index.Value)
+	suite.Equal("Very High", index.Category)
+	suite.Equal(5, index.Level)
+}
+
+func (suite *AQISuite) TestINNAQI() {
+	index, err := aqi.Index(aqi.INNAQI, entities.AirPollutionComponents{PM25: 45, NH3: 10})
+	suite.NoError(err)
+	suite.Equal(75, index.Value)
+	suite.Equal(aqi.PM25, index.DominantPollutant)
+	suite.Equal("Satisfactory", index.Category)
+	suite.Contains(index.SubIndices, aqi.NH3)
+
+	index, err = aqi.Index(aqi.INNAQI, entities.AirPollutionComponents{PM10: 500})
+	suite.NoError(err)
+	suite.Equal(488, index.Value)
+	suite.Equal("Severe", index.Category)
+	suite.Equal(6, index.Level)
+}
+
+func (suite *AQISuite) TestUnknownStandard() {
+	_, err := aqi.Index("owm", entities.AirPollutionComponents{})
+	suite.ErrorIs(err, aqi.ErrUnknownStandard)
+}
+
+func (suite *AQISuite) TestCurrent() {
+	current, err := aqi.Current(&entities.AirPollution{
+		Coord: entities.Coord{Lat: 12.97, Lon: 77.59},
+		List:  []entities.AirPollutionDatapoint{{Dt: 1700000000, Components: entities.AirPollutionComponents{PM25: 12}}},
+	}, aqi.USEPA)
+	suite.NoError(err)
+	suite.Equal(1700000000, current.Dt)
+	suite.Equal(56, current.AQI.Value)
+	suite.Equal(12.0, current.PM25)
+	suite.Equal(float32(12.97), current.Latitude)
+}
+
+func (suite *AQISuite) TestCurrentWithoutDatapoints() {
+	_, err := aqi.Current(&entities.AirPollution{Coord: entities.Coord{Lat: 12.97, Lon: 77.59}}, aqi.USEPA)
+	suite.ErrorIs(err, aqi.ErrNoDatapoints)
+}
+
+func TestAQISuite(t *testing.T) {
+	suite.Run(t, &AQISuite{})
+}
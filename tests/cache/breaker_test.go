@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type BreakerSuite struct {
+	suite.Suite
+	ctx     context.Context
+	backend *flakyCache
+	breaker cache.BreakerCache
+}
+
+func (suite *BreakerSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.backend = &flakyCache{Cache: cache.NewLRUCache(10)}
+	suite.breaker = cache.NewBreakerCache(suite.backend, 3, 50*time.Millisecond)
+}
+
+func (suite *BreakerSuite) TestMissesDoNotOpenTheBreaker() {
+	for i := 0; i < 5; i++ {
+		_, err := suite.breaker.Get(suite.ctx, "key")
+		suite.ErrorIs(err, cache.ErrMiss)
+	}
+
+	suite.Equal(cache.BreakerClosed, suite.breaker.State())
+	suite.Equal(5, suite.backend.calls)
+}
+
+func (suite *BreakerSuite) TestRepeatedFailuresOpenTheBreaker() {
+	suite.backend.down = true
+
+	for i := 0; i < 3; i++ {
+		_, err := suite.breaker.Get(suite.ctx, "key")
+		suite.ErrorIs(err, errDown)
+	}
+
+	suite.Equal(cache.BreakerOpen, suite.breaker.State())
+
+	// While it is open the backend is not called.
+	_, err := suite.breaker.Get(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrUnavailable)
+	suite.ErrorIs(suite.breaker.Set(suite.ctx, "key", []byte("value"), time.Minute), cache.ErrUnavailable)
+	suite.Equal(3, suite.backend.calls)
+}
+
+func (suite *BreakerSuite) TestSuccessesResetTheFailures() {
+	suite.backend.down = true
+	for i := 0; i < 2; i++ {
+		_, _ = suite.breaker.Get(suite.ctx, "key")
+	}
+
+	suite.backend.down = false
+	_, _ = suite.breaker.Get(suite.ctx, "key")
+
+	suite.backend.down = true
+	for i := 0; i < 2; i++ {
+		_, _ = suite.breaker.Get(suite.ctx, "key")
+	}
+
+	suite.Equal(cache.BreakerClosed, suite.breaker.State())
+}
+
+func (suite *BreakerSuite) TestProbesCloseOrReopenTheBreaker() {
+	suite.backend.down = true
+	for i := 0; i < 3; i++ {
+		_, _ = suite.breaker.Get(suite.ctx, "key")
+	}
+
+	// A failed probe opens the breaker again right away.
+	time.Sleep(60 * time.Millisecond)
+	_, err := suite.breaker.Get(suite.ctx, "key")
+	suite.ErrorIs(err, errDown)
+	suite.Equal(cache.BreakerOpen, suite.breaker.State())
+
+	_, err = suite.breaker.Get(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrUnavailable)
+
+	suite.backend.down = false
+	time.Sleep(60 * time.Millisecond)
+	suite.NoError(suite.breaker.Set(suite.ctx, "key", []byte("value"), time.Minute))
+	suite.Equal(cache.BreakerClosed, suite.breaker.State())
+
+	value, err := suite.breaker.Get(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Equal([]byte("value"), value)
+}
+
+func (suite *BreakerSuite) TestCancelledCallsDoNotOpenTheBreaker() {
+	suite.backend.down = true
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	for i := 0; i < 5; i++ {
+		_, _ = suite.breaker.Get(ctx, "key")
+	}
+
+	suite.Equal(cache.BreakerClosed, suite.breaker.State())
+}
+
+func TestBreakerSuite(t *testing.T) {
+	suite.Run(t, new(BreakerSuite))
+}
+
+// flakyCache counts the reads and writes it is given and fails them with
+// errDown while down.
+type flakyCache struct {
+	cache.Cache
+	down  bool
+	calls int
+}
+
+func (fc *flakyCache) Get(ctx context.Context, key string) ([]byte, error) {
+	fc.calls++
+	if fc.down {
+		return nil, errDown
+	}
+
+	return fc.Cache.Get(ctx, key)
+}
+
+func (fc *flakyCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	fc.calls++
+	if fc.down {
+		return errDown
+	}
+
+	return fc.Cache.Set(ctx, key, value, ttl)
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type LRUSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *LRUSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *LRUSuite) TestGetSet() {
+	lru := cache.NewLRUCache(10)
+
+	_, err := lru.Get(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrMiss)
+
+	suite.NoError(lru.Set(suite.ctx, "key", []byte("value"), time.Minute))
+
+	value, err := lru.Get(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Equal([]byte("value"), value)
+}
+
+func (suite *LRUSuite) TestEvictsLeastRecentlyUsed() {
+	lru := cache.NewLRUCache(2)
+
+	suite.NoError(lru.Set(suite.ctx, "a", []byte("a"), 0))
+	suite.NoError(lru.Set(suite.ctx, "b", []byte("b"), 0))
+
+	_, err := lru.Get(suite.ctx, "a")
+	suite.NoError(err)
+
+	suite.NoError(lru.Set(suite.ctx, "c", []byte("c"), 0))
+
+	_, err = lru.Get(suite.ctx, "b")
+	suite.ErrorIs(err, cache.ErrMiss)
+	_, err = lru.Get(suite.ctx, "a")
+	suite.NoError(err)
+}
+
+func (suite *LRUSuite) TestExpiry() {
+	lru := cache.NewLRUCache(10)
+
+	suite.NoError(lru.Set(suite.ctx, "key", []byte("value"), time.Millisecond*20))
+
+	ttl, err := lru.TTL(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Greater(ttl, time.Duration(0))
+
+	time.Sleep(time.Millisecond * 30)
+
+	_, err = lru.Get(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrMiss)
+	_, err = lru.TTL(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrMiss)
+}
+
+func (suite *LRUSuite) TestSetNXAndCompareAndDelete() {
+	lru := cache.NewLRUCache(10)
+
+	set, err := lru.SetNX(suite.ctx, "lock", []byte("first"), time.Minute)
+	suite.NoError(err)
+	suite.True(set)
+
+	set, err = lru.SetNX(suite.ctx, "lock", []byte("second"), time.Minute)
+	suite.NoError(err)
+	suite.False(set)
+
+	deleted, err := lru.CompareAndDelete(suite.ctx, "lock", []byte("second"))
+	suite.NoError(err)
+	suite.False(deleted)
+
+	deleted, err = lru.CompareAndDelete(suite.ctx, "lock", []byte("first"))
+	suite.NoError(err)
+	suite.True(deleted)
+}
+
+func TestLRUSuite(t *testing.T) {
+	suite.Run(t, new(LRUSuite))
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type RedisSuite struct {
+	suite.Suite
+	ctx   context.Context
+	redis *miniredis.Miniredis
+	cache cache.Cache
+}
+
+func (suite *RedisSuite) SetupTest() {
+	var err error
+
+	suite.ctx = context.Background()
+	suite.redis, err = miniredis.Run()
+	suite.Require().NoError(err)
+	suite.cache = cache.NewRedisCache(redis.NewClient(&redis.Options{Addr: suite.redis.Addr()}))
+}
+
+func (suite *RedisSuite) TearDownTest() {
+	suite.redis.Close()
+}
+
+func (suite *RedisSuite) TestMGetAnswersNilForMisses() {
+	suite.NoError(suite.cache.Set(suite.ctx, "first", []byte("1"), time.Minute))
+	suite.NoError(suite.cache.Set(suite.ctx, "third", []byte("3"), 0))
+
+	values, err := suite.cache.MGet(suite.ctx, "first", "second", "third")
+	suite.NoError(err)
+	suite.Equal([][]byte{[]byte("1"), nil, []byte("3")}, values)
+}
+
+func (suite *RedisSuite) TestMSetKeepsTheTTLOfEveryItem() {
+	suite.NoError(suite.cache.MSet(suite.ctx,
+		cache.Item{Key: "minute", Value: []byte("1"), TTL: time.Minute},
+		cache.Item{Key: "forever", Value: []byte("2")},
+	))
+
+	ttl, err := suite.cache.TTL(suite.ctx, "minute")
+	suite.NoError(err)
+	suite.Equal(time.Minute, ttl)
+
+	ttl, err = suite.cache.TTL(suite.ctx, "forever")
+	suite.NoError(err)
+	suite.Zero(ttl)
+
+	value, err := suite.cache.Get(suite.ctx, "forever")
+	suite.NoError(err)
+	suite.Equal([]byte("2"), value)
+}
+
+func TestRedisSuite(t *testing.T) {
+	suite.Run(t, new(RedisSuite))
+}
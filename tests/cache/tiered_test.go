@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type TieredSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *TieredSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+func (suite *TieredSuite) TestTieredReadsThroughAndWritesBoth() {
+	local := cache.NewLRUCache(10)
+	shared := cache.NewLRUCache(10)
+	tiered := cache.NewTieredCache(local, shared, time.Minute)
+
+	suite.NoError(shared.Set(suite.ctx, "key", []byte("shared"), time.Hour))
+
+	value, err := tiered.Get(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Equal([]byte("shared"), value)
+
+	value, err = local.Get(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Equal([]byte("shared"), value)
+
+	suite.NoError(tiered.Set(suite.ctx, "other", []byte("both"), time.Second))
+
+	ttl, err := local.TTL(suite.ctx, "other")
+	suite.NoError(err)
+	suite.LessOrEqual(ttl, time.Second)
+
+	_, err = shared.Get(suite.ctx, "other")
+	suite.NoError(err)
+
+	suite.NoError(tiered.Delete(suite.ctx, "key", "other"))
+	_, err = local.Get(suite.ctx, "key")
+	suite.ErrorIs(err, cache.ErrMiss)
+	_, err = shared.Get(suite.ctx, "other")
+	suite.ErrorIs(err, cache.ErrMiss)
+}
+
+func (suite *TieredSuite) TestMGetReadsLocalMissesFromTheSharedTier() {
+	local := cache.NewLRUCache(10)
+	shared := cache.NewLRUCache(10)
+	tiered := cache.NewTieredCache(local, shared, time.Minute)
+
+	suite.NoError(local.Set(suite.ctx, "local", []byte("local"), time.Hour))
+	suite.NoError(shared.Set(suite.ctx, "shared", []byte("shared"), time.Hour))
+
+	values, err := tiered.MGet(suite.ctx, "local", "missing", "shared")
+	suite.NoError(err)
+	suite.Equal([][]byte{[]byte("local"), nil, []byte("shared")}, values)
+
+	suite.NoError(tiered.MSet(suite.ctx, cache.Item{Key: "both", Value: []byte("both"), TTL: time.Hour}))
+
+	ttl, err := local.TTL(suite.ctx, "both")
+	suite.NoError(err)
+	suite.LessOrEqual(ttl, time.Minute)
+
+	ttl, err = shared.TTL(suite.ctx, "both")
+	suite.NoError(err)
+	suite.Greater(ttl, time.Minute)
+}
+
+func (suite *TieredSuite) TestReadThroughCopiesDoNotOutliveTheSharedEntry() {
+	local := cache.NewLRUCache(10)
+	shared := cache.NewLRUCache(10)
+	tiered := cache.NewTieredCache(local, shared, time.Minute)
+
+	suite.NoError(shared.Set(suite.ctx, "short", []byte("shared"), time.Second))
+	suite.NoError(shared.Set(suite.ctx, "long", []byte("shared"), time.Hour))
+	suite.NoError(shared.Set(suite.ctx, "forever", []byte("shared"), 0))
+
+	for _, key := range []string{"short", "long", "forever"} {
+		_, err := tiered.Get(suite.ctx, key)
+		suite.NoError(err)
+	}
+
+	ttl, err := local.TTL(suite.ctx, "short")
+	suite.NoError(err)
+	suite.LessOrEqual(ttl, time.Second)
+
+	for _, key := range []string{"long", "forever"} {
+		ttl, err = local.TTL(suite.ctx, key)
+		suite.NoError(err)
+		suite.Greater(ttl, 59*time.Second, key)
+		suite.LessOrEqual(ttl, time.Minute, key)
+	}
+}
+
+func (suite *TieredSuite) TestTieredServesLocallyWhileSharedFails() {
+	local := cache.NewLRUCache(10)
+	tiered := cache.NewTieredCache(local, downCache{}, time.Minute)
+
+	err := tiered.Set(suite.ctx, "key", []byte("local"), time.Hour)
+	suite.ErrorIs(err, errDown)
+
+	value, err := tiered.Get(suite.ctx, "key")
+	suite.NoError(err)
+	suite.Equal([]byte("local"), value)
+
+	_, err = tiered.Get(suite.ctx, "other")
+	suite.ErrorIs(err, errDown)
+}
+
+func TestTieredSuite(t *testing.T) {
+	suite.Run(t, new(TieredSuite))
+}
+
+var errDown = errors.New("connection refused")
+
+// downCache fails every call, like Redis while it is unreachable.
+type downCache struct{}
+
+func (downCache) Get(context.Context, string) ([]byte, error) {
+	return nil, errDown
+}
+
+func (downCache) MGet(context.Context, ...string) ([][]byte, error) {
+	return nil, errDown
+}
+
+func (downCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errDown
+}
+
+func (downCache) MSet(context.Context, ...cache.Item) error {
+	return errDown
+}
+
+func (downCache) SetNX(context.Context, string, []byte, time.Duration) (bool, error) {
+	return false, errDown
+}
+
+func (downCache) TTL(context.Context, string) (time.Duration, error) {
+	return 0, errDown
+}
+
+func (downCache) Delete(context.Context, ...string) error {
+	return errDown
+}
+
+func (downCache) CompareAndDelete(context.Context, string, []byte) (bool, error) {
+	return false, errDown
+}
+
+func (downCache) Scan(context.Context, string) ([]string, error) {
+	return nil, errDown
+}
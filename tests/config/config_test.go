@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -42,6 +43,9 @@ func (suite *ConfigSuite) SetupTest() {
 	envMap[config.RedisPassword] = "redis_password"
 	envMap[config.RedisDB] = "0"
 	envMap[config.RedisTimeout] = "10"
+	envMap[config.RedisDialTimeout] = "2000"
+	envMap[config.RedisReadTimeout] = "300"
+	envMap[config.RedisWriteTimeout] = "400"
 
 	envMap[config.PostgresDatabase] = "postgres_database"
 	envMap[config.PostgresPassword] = "postgres_password"
@@ -52,6 +56,67 @@ func (suite *ConfigSuite) SetupTest() {
 
 	envMap[config.AirPollutionApiKey] = "air_pollution_api_key"
 	envMap[config.AirPollutionBaseUrl] = "air_pollution_base_url"
+	envMap[config.AirPollutionHistoryChunk] = "86400"
+	envMap[config.AirPollutionHistoryWorkers] = "8"
+
+	envMap[config.AuthProtectWeather] = "true"
+	envMap[config.AuthProtectGeocode] = "false"
+	envMap[config.AuthProtectAirPollution] = "true"
+
+	envMap[config.RateLimitEnabled] = "true"
+	envMap[config.RateLimitWindow] = "30"
+	envMap[config.DefaultPlan] = "basic"
+	envMap[config.RateLimitRules] = "weather:basic=10, weather:pro=100,geocode:basic=5,broken,air:basic=x,air:pro=0"
+
+	envMap[config.UsageBufferSize] = "500"
+	envMap[config.UsageFlushInterval] = "5"
+	envMap[config.UsageQuotas] = "basic=1000,pro=0,broken"
+
+	envMap[config.IdentityProvider] = "local"
+	envMap[config.IdentityJWTAlgorithm] = "EdDSA"
+	envMap[config.IdentityJWTSecret] = "identity_jwt_secret"
+	envMap[config.IdentityJWTIssuer] = "identity_jwt_issuer"
+	envMap[config.IdentityTokenTTL] = "900"
+	envMap[config.IdentityRefreshTokenTTL] = "86400"
+	envMap[config.IdentityFirebaseAPIKey] = "identity_firebase_api_key"
+
+	envMap[config.UpstreamTimeout] = "7"
+	envMap[config.UpstreamTimeouts] = "current_weather=2, air_pollution_history=20,broken,geocode_direct=0"
+	envMap[config.UpstreamRetries] = "4"
+	envMap[config.CacheBackend] = "tiered"
+	envMap[config.CacheLRUSize] = "500"
+	envMap[config.CacheLocalTTL] = "5"
+	envMap[config.CacheLockTTL] = "20"
+	envMap[config.CacheSnapping] = "current_weather=geohash:7, current_air_pollution=grid:0.1,weather_forecast=geohash:13,broken,geocode_reverse=square:2"
+	envMap[config.CacheLegacyKeys] = "false"
+	envMap[config.CacheLockWait] = "1500"
+	envMap[config.CacheLockPollInterval] = "50"
+	envMap[config.CacheRefreshWorkers] = "4"
+	envMap[config.CacheBreakerThreshold] = "3"
+	envMap[config.CacheBreakerOpenDuration] = "20"
+	envMap[config.CacheTTLs] = "current_weather=fixed:120:600, air_pollution_history=immutable_past:60:600:86400,broken,current_air_pollution=fixed:10"
+	envMap[config.CacheTTLFile] = filepath.Join(suite.T().TempDir(), "config_test_cache_ttls.json")
+	envMap[config.UpstreamRetryBaseDelay] = "250"
+	envMap[config.UpstreamRetryMaxDelay] = "3000"
+	envMap[config.UpstreamBreakerThreshold] = "8"
+	envMap[config.UpstreamBreakerOpenDuration] = "45"
+	envMap[config.UpstreamFallbackRetention] = "86400"
+
+	envMap[config.SmtpHost] = "smtp_host"
+	envMap[config.SmtpPort] = "2525"
+	envMap[config.SmtpFrom] = "smtp_from"
+	envMap[config.SmtpTimeout] = "3"
+	envMap[config.SmtpQueueSize] = "20"
+
+	ttlFile := `{
+		"current_weather": {"policy": "fixed", "soft": "5m", "hard": "30m"},
+		"weather_forecast": {"policy": "boundary", "interval": "1h", "hard": "2h"},
+		"geocode_direct": {"policy": "fixed", "soft": "2h", "hard": "1h"}
+	}`
+	err := os.WriteFile(envMap[config.CacheTTLFile], []byte(ttlFile), 0o600)
+	if err != nil {
+		panic(err)
+	}
 
 	for key, value := range envMap {
 		err := os.Setenv(key, value)
@@ -94,6 +159,9 @@ func (suite *ConfigSuite) TestGetConfigSuccess() {
 	suite.Equal("redis_password", conf.RedisConfig.Password)
 	suite.Equal(0, conf.RedisConfig.DB)
 	suite.Equal(10*time.Second, conf.RedisConfig.Timeout)
+	suite.Equal(2*time.Second, conf.RedisConfig.DialTimeout)
+	suite.Equal(300*time.Millisecond, conf.RedisConfig.ReadTimeout)
+	suite.Equal(400*time.Millisecond, conf.RedisConfig.WriteTimeout)
 	suite.Equal("postgres_database", conf.PostgresConfig.Database)
 	suite.Equal("postgres_password", conf.PostgresConfig.Password)
 	suite.Equal("postgres_host", conf.PostgresConfig.Host)
@@ -102,6 +170,68 @@ func (suite *ConfigSuite) TestGetConfigSuccess() {
 	suite.Equal("postgres_time_zone", conf.PostgresConfig.TimeZone)
 	suite.Equal("air_pollution_api_key", conf.AirPollutionConfig.APIKey)
 	suite.Equal("air_pollution_base_url", conf.AirPollutionConfig.BaseURL)
+	suite.Equal(24*time.Hour, conf.AirPollutionConfig.HistoryChunk)
+	suite.Equal(8, conf.AirPollutionConfig.HistoryWorkers)
+	suite.Equal(365*24*time.Hour, conf.AirPollutionConfig.HistoryMaxSpan)
+	suite.True(conf.AuthConfig.ProtectWeather)
+	suite.False(conf.AuthConfig.ProtectGeocode)
+	suite.True(conf.AuthConfig.ProtectAirPollution)
+	suite.True(conf.RateLimitConfig.Enabled)
+	suite.Equal(30*time.Second, conf.RateLimitConfig.Window)
+	suite.Equal("basic", conf.PlanConfig.Default)
+	suite.Equal(map[string]map[string]int{
+		"weather": {"basic": 10, "pro": 100},
+		"geocode": {"basic": 5},
+	}, conf.RateLimitConfig.Limits)
+	suite.Equal(500, conf.UsageConfig.BufferSize)
+	suite.Equal(5*time.Second, conf.UsageConfig.FlushInterval)
+	suite.Equal(map[string]int64{"basic": 1000, "pro": 0}, conf.UsageConfig.Quotas)
+	suite.Equal("local", conf.IdentityConfig.Provider)
+	suite.Equal("EdDSA", conf.IdentityConfig.JWTAlgorithm)
+	suite.Equal("identity_jwt_secret", conf.IdentityConfig.JWTSecret)
+	suite.Equal("identity_jwt_issuer", conf.IdentityConfig.JWTIssuer)
+	suite.Equal(15*time.Minute, conf.IdentityConfig.TokenTTL)
+	suite.Equal(24*time.Hour, conf.IdentityConfig.RefreshTokenTTL)
+	suite.Equal("identity_firebase_api_key", conf.IdentityConfig.FirebaseAPIKey)
+	suite.Equal(7*time.Second, conf.UpstreamConfig.Timeout)
+	suite.Equal(map[string]time.Duration{
+		"current_weather":       2 * time.Second,
+		"air_pollution_history": 20 * time.Second,
+	}, conf.UpstreamConfig.Timeouts)
+	suite.Equal(4, conf.UpstreamConfig.Retries)
+	suite.Equal(config.CacheBackendTiered, conf.CacheConfig.Backend)
+	suite.Equal(500, conf.CacheConfig.LRUSize)
+	suite.Equal(5*time.Second, conf.CacheConfig.LocalTTL)
+	suite.Equal(20*time.Second, conf.CacheConfig.LockTTL)
+	suite.Equal(map[string]config.SnapRule{
+		"current_weather":       {Scheme: config.SnapGeohash, Precision: 7},
+		"current_air_pollution": {Scheme: config.SnapGrid, Step: 0.1},
+	}, conf.CacheConfig.SnapRules)
+	suite.False(conf.CacheConfig.ReadLegacyKeys)
+	suite.Equal(1500*time.Millisecond, conf.CacheConfig.LockWait)
+	suite.Equal(50*time.Millisecond, conf.CacheConfig.LockPollInterval)
+	suite.Equal(4, conf.CacheConfig.RefreshWorkers)
+	suite.Equal(3, conf.CacheConfig.BreakerThreshold)
+	suite.Equal(20*time.Second, conf.CacheConfig.BreakerOpenDuration)
+	suite.Equal(map[string]config.TTLPolicy{
+		"current_weather":        {Policy: config.TTLFixed, Soft: 2 * time.Minute, Hard: 10 * time.Minute},
+		"weather_forecast":       {Policy: config.TTLForecastBoundary, Interval: time.Hour, Hard: 2 * time.Hour},
+		"current_air_pollution":  {Policy: config.TTLFixed, Soft: 5 * time.Minute, Hard: time.Hour},
+		"air_pollution_forecast": {Policy: config.TTLForecastBoundary, Interval: time.Hour, Hard: time.Hour},
+		"air_pollution_history":  {Policy: config.TTLImmutablePast, Soft: time.Minute, Hard: 10 * time.Minute, Retention: 24 * time.Hour},
+		"geocode_direct":         {Policy: config.TTLFixed, Soft: 24 * time.Hour, Hard: 7 * 24 * time.Hour},
+		"geocode_reverse":        {Policy: config.TTLFixed, Soft: 24 * time.Hour, Hard: 7 * 24 * time.Hour},
+	}, conf.CacheConfig.TTLPolicies)
+	suite.Equal(250*time.Millisecond, conf.UpstreamConfig.RetryBaseDelay)
+	suite.Equal(3*time.Second, conf.UpstreamConfig.RetryMaxDelay)
+	suite.Equal(8, conf.UpstreamConfig.BreakerThreshold)
+	suite.Equal(45*time.Second, conf.UpstreamConfig.BreakerOpenDuration)
+	suite.Equal(24*time.Hour, conf.UpstreamConfig.FallbackRetention)
+	suite.Equal("smtp_host", conf.MailerConfig.Host)
+	suite.Equal(2525, conf.MailerConfig.Port)
+	suite.Equal("smtp_from", conf.MailerConfig.From)
+	suite.Equal(3*time.Second, conf.MailerConfig.Timeout)
+	suite.Equal(20, conf.MailerConfig.QueueSize)
 }
 
 func TestConfigSuite(t *testing.T) {
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SnapSuite struct {
+	suite.Suite
+}
+
+func (suite *SnapSuite) TestEncodeGeohash() {
+	suite.Equal("u4pruydqqvj", geo.EncodeGeohash(57.64911, 10.40744, 11))
+	suite.Equal("9q8yy", geo.EncodeGeohash(37.7749, -122.4194, 5))
+}
+
+func (suite *SnapSuite) TestDecodeGeohash() {
+	box, err := geo.DecodeGeohash("u4pruydqqvj")
+	suite.NoError(err)
+	suite.True(box.Contains(57.64911, 10.40744))
+
+	_, err = geo.DecodeGeohash("u4pa")
+	suite.ErrorIs(err, geo.ErrInvalidGeohash)
+}
+
+func (suite *SnapSuite) TestGeohashSnapSharesCells() {
+	rule := config.SnapRule{Scheme: config.SnapGeohash, Precision: 6}
+
+	first := geo.Snap(rule, 12.971599, 77.594566)
+	second := geo.Snap(rule, 12.9716, 77.5946)
+
+	suite.Equal(first.Cell, second.Cell)
+	suite.Equal(first.Lat, second.Lat)
+	suite.Equal(first.Lon, second.Lon)
+	suite.Equal(12.971599, first.RequestedLat)
+
+	box, ok := geo.CellBox(first.Cell)
+	suite.True(ok)
+	suite.True(box.Contains(12.971599, 77.594566))
+}
+
+func (suite *SnapSuite) TestGridSnap() {
+	location := geo.Snap(config.SnapRule{Scheme: config.SnapGrid, Step: 0.05}, 12.9716, -77.5946)
+
+	suite.Equal("grid0.05:259:-1552", location.Cell)
+	suite.Equal(12.95, location.Lat)
+	suite.Equal(-77.6, location.Lon)
+
+	box, ok := geo.CellBox(location.Cell)
+	suite.True(ok)
+	suite.True(box.Contains(12.9716, -77.5946))
+
+	quarter := geo.Snap(config.SnapRule{Scheme: config.SnapGrid, Step: 0.25}, 64.8, 0)
+	suite.Equal(64.75, quarter.Lat)
+}
+
+func (suite *SnapSuite) TestNoRuleKeepsCoordinates() {
+	location := geo.Snap(config.SnapRule{}, 12.9716, 77.5946)
+
+	suite.Equal(12.9716, location.Lat)
+	suite.Equal("12.971600_77.594597", location.Cell)
+
+	box, ok := geo.CellBox(location.Cell)
+	suite.True(ok)
+	suite.InDelta(12.9716, box.MinLat, 1e-5)
+	suite.InDelta(77.5946, box.MaxLon, 1e-5)
+	suite.Equal(box.MinLat, box.MaxLat)
+
+	_, ok = geo.CellBox("12.971600")
+	suite.False(ok)
+}
+
+func TestSnapSuite(t *testing.T) {
+	suite.Run(t, new(SnapSuite))
+}
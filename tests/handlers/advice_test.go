@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type AdviceHandlerSuite struct {
+	suite.Suite
+	airPollutionService *stubAirPollutionService
+	app                 *fiber.App
+}
+
+func (suite *AdviceHandlerSuite) SetupTest() {
+	suite.airPollutionService = &stubAirPollutionService{fetchedAt: time.Now().Add(-time.Hour)}
+
+	adviceService := services.NewAdviceService(suite.airPollutionService, zap.NewNop())
+	airPollutionHandler := handlers.NewAirPollutionHandler(suite.airPollutionService, adviceService, nil, zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Get("/air-pollution/advice", middlewares.TrackFreshness, airPollutionHandler.GetAdvice)
+}
+
+func (suite *AdviceHandlerSuite) TestAdviceIsServed() {
+	status, body, err := sendRaw(suite.app, fiber.MethodGet, "/air-pollution/advice?lat=12.97&long=77.59&lang=en")
+	suite.Require().NoError(err)
+	suite.Require().Equal(fiber.StatusOK, status)
+
+	var envelope struct {
+		Data     entities.AirQualityAdvice `json:"data"`
+		Location json.RawMessage           `json:"location"`
+		Warning  entities.CacheWarning     `json:"warning"`
+	}
+	suite.Require().NoError(json.Unmarshal(body, &envelope))
+
+	suite.Equal("en", envelope.Data.Locale)
+	suite.Equal(1700001800, envelope.Data.Current.Dt)
+	suite.Equal(56, envelope.Data.Current.AQI.Value)
+	suite.Require().NotEmpty(envelope.Data.Outlook)
+	suite.Equal(1700000000, envelope.Data.Outlook[0].Start)
+	suite.NotEmpty(envelope.Data.SafestWindows)
+
+	// The stale forecast makes the whole advice stale, and the location of
+	// the current air pollution is kept.
+	suite.Equal(freshness.Stale, envelope.Warning.Code)
+	suite.NotEmpty(envelope.Location)
+}
+
+func (suite *AdviceHandlerSuite) TestInvalidQueriesAreRejected() {
+	for _, query := range []string{"lat=91&long=77.59", "lat=12.97", "lat=12.97&long=77.59&standard=owm"} {
+		status, _, err := send(suite.app, fiber.MethodGet, "/air-pollution/advice?"+query, "")
+		suite.Require().NoError(err)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, query)
+	}
+}
+
+func (suite *AdviceHandlerSuite) TestFetchErrorsAreMappedToStatuses() {
+	suite.airPollutionService.err = upstream.ErrUnavailable
+
+	status, _, err := send(suite.app, fiber.MethodGet, "/air-pollution/advice?lat=12.97&long=77.59", "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusServiceUnavailable, status)
+
+	// Upstream answering the current air pollution without any datapoint is
+	// an invalid response.
+	suite.airPollutionService.err = nil
+	suite.airPollutionService.empty = true
+
+	status, res, err := send(suite.app, fiber.MethodGet, "/air-pollution/advice?lat=12.97&long=77.59", "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusBadGateway, status)
+	suite.NotEmpty(res.Error)
+}
+
+func TestAdviceHandlerSuite(t *testing.T) {
+	suite.Run(t, new(AdviceHandlerSuite))
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type HistoryStreamSuite struct {
+	suite.Suite
+	airPollutionService *stubAirPollutionService
+	app                 *fiber.App
+}
+
+func (suite *HistoryStreamSuite) SetupTest() {
+	suite.airPollutionService = &stubAirPollutionService{fetchedAt: time.Now().Add(-time.Hour)}
+
+	airPollutionHandler := handlers.NewAirPollutionHandler(suite.airPollutionService, nil, nil, zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Get("/air-pollution/history", middlewares.TrackFreshness, airPollutionHandler.GetHistoricalAirPollution)
+}
+
+// getHistory streams the raw history with query and decodes the response.
+func (suite *HistoryStreamSuite) getHistory(query string, history any) map[string]json.RawMessage {
+	start := time.Now().Add(-24 * time.Hour).Unix()
+	path := fmt.Sprintf("/air-pollution/history?lat=12.97&long=77.59&raw=true&start=%d&end=%d%s", start, start+3600, query)
+
+	status, body, err := sendRaw(suite.app, fiber.MethodGet, path)
+	suite.Require().NoError(err)
+	suite.Require().Equal(fiber.StatusOK, status)
+
+	var envelope map[string]json.RawMessage
+	suite.Require().NoError(json.Unmarshal(body, &envelope))
+	suite.Require().NoError(json.Unmarshal(envelope["data"], history))
+
+	return envelope
+}
+
+func (suite *HistoryStreamSuite) TestHistoryIsStreamedWhole() {
+	var history entities.AirPollution
+	envelope := suite.getHistory("", &history)
+
+	suite.Equal(float32(12.95), history.Lat)
+	suite.Equal(float32(77.6), history.Lon)
+	suite.Require().Len(history.List, 1200)
+
+	for i, datapoint := range history.List {
+		suite.Equal(1700000000+i*3600, datapoint.Dt)
+	}
+
+	// The envelope around the streamed data is complete.
+	suite.Contains(envelope, "location")
+	suite.Contains(envelope, "warning")
+	suite.Contains(envelope, "message")
+	suite.JSONEq(`200`, string(envelope["status"]))
+}
+
+func (suite *HistoryStreamSuite) TestIndexedHistoryIsStreamedWhole() {
+	var history entities.IndexedAirPollution
+	suite.getHistory("&standard=us_epa", &history)
+
+	suite.Require().Len(history.List, 1200)
+	suite.Equal(1700000000+1199*3600, history.List[1199].Dt)
+}
+
+func (suite *HistoryStreamSuite) TestEmptyHistoriesAreStreamed() {
+	suite.airPollutionService.empty = true
+
+	var history entities.AirPollution
+	suite.getHistory("", &history)
+
+	suite.NotNil(history.List)
+	suite.Empty(history.List)
+}
+
+func (suite *HistoryStreamSuite) TestTooLongRangesAreBadRequests() {
+	suite.airPollutionService.err = fmt.Errorf("%w: it may span at most %s", services.ErrHistoryRangeTooLong, 24*time.Hour)
+
+	start := time.Now().Add(-72 * time.Hour).Unix()
+	path := fmt.Sprintf("/air-pollution/history?lat=12.97&long=77.59&raw=true&start=%d&end=%d", start, start+48*3600)
+
+	status, res, err := send(suite.app, fiber.MethodGet, path, "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusBadRequest, status)
+	suite.Equal("the history range is too long", res.Error)
+}
+
+func TestHistoryStreamSuite(t *testing.T) {
+	suite.Run(t, new(HistoryStreamSuite))
+}
+
+// stubAirPollutionService answers history requests with 1200 hourly
+// datapoints, or none when empty, served stale from the cache. The current
+// air pollution is served fresh and its forecast stale. Every request fails
+// with err when it is set.
+type stubAirPollutionService struct {
+	services.AirPollutionService
+	empty     bool
+	err       error
+	fetchedAt time.Time
+}
+
+func (sas *stubAirPollutionService) GetCurrentAirPollution(ctx context.Context, _, _ float32) (*entities.AirPollution, error) {
+	if sas.err != nil {
+		return nil, sas.err
+	}
+
+	freshness.MarkLocation(ctx, geo.Location{Lat: 12.95, Lon: 77.6, Cell: "grid0.05:259:1552"})
+	freshness.MarkCached(ctx, time.Now())
+
+	current := &entities.AirPollution{Coord: entities.Coord{Lat: 12.95, Lon: 77.6}}
+	if !sas.empty {
+		current.List = []entities.AirPollutionDatapoint{{Dt: 1700001800, Components: entities.AirPollutionComponents{PM25: 12}}}
+	}
+
+	return current, nil
+}
+
+func (sas *stubAirPollutionService) GetAirPollutionForecast(ctx context.Context, _, _ float32) (*entities.AirPollution, error) {
+	if sas.err != nil {
+		return nil, sas.err
+	}
+
+	freshness.MarkStale(ctx, sas.fetchedAt)
+
+	forecast := &entities.AirPollution{Coord: entities.Coord{Lat: 12.95, Lon: 77.6}}
+	for i := 0; i < 24; i++ {
+		forecast.List = append(forecast.List, entities.AirPollutionDatapoint{
+			Dt:         1700000000 + i*3600,
+			Components: entities.AirPollutionComponents{PM25: float64(i * 5)},
+		})
+	}
+
+	return forecast, nil
+}
+
+func (sas *stubAirPollutionService) GetHistoricalAirPollution(ctx context.Context, _, _ float32, _, _ int64) (*entities.AirPollution, error) {
+	if sas.err != nil {
+		return nil, sas.err
+	}
+
+	freshness.MarkLocation(ctx, geo.Location{Lat: 12.95, Lon: 77.6, Cell: "grid0.05:259:1552"})
+	freshness.MarkStale(ctx, sas.fetchedAt)
+
+	history := &entities.AirPollution{Coord: entities.Coord{Lat: 12.95, Lon: 77.6}}
+	if sas.empty {
+		return history, nil
+	}
+
+	for i := 0; i < 1200; i++ {
+		history.List = append(history.List, entities.AirPollutionDatapoint{
+			Dt:         1700000000 + i*3600,
+			Components: entities.AirPollutionComponents{PM25: float64(i % 80), O3: 40},
+		})
+	}
+
+	return history, nil
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type APIKeyHandlerSuite struct {
+	suite.Suite
+	apiKeys *fakeAPIKeys
+	app     *fiber.App
+}
+
+func (suite *APIKeyHandlerSuite) SetupTest() {
+	suite.apiKeys = &fakeAPIKeys{keys: map[string]entities.APIKey{}}
+	apiKeyHandler := handlers.NewAPIKeyHandler(services.NewAPIKeyService(suite.apiKeys, nil, zap.NewNop()), zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Post("/users/:uid/api-keys/:id/rotate", apiKeyHandler.RotateAPIKey)
+}
+
+func (suite *APIKeyHandlerSuite) rotate(id string) int {
+	status, _, err := send(suite.app, fiber.MethodPost, "/users/owner/api-keys/"+id+"/rotate", "")
+	suite.Require().NoError(err)
+
+	return status
+}
+
+func (suite *APIKeyHandlerSuite) TestActiveKeysAreRotated() {
+	suite.apiKeys.keys["active"] = entities.APIKey{ID: "active", OwnerUID: "owner", KeyHash: "old"}
+
+	suite.Equal(fiber.StatusOK, suite.rotate("active"))
+	suite.NotEqual("old", suite.apiKeys.keys["active"].KeyHash)
+}
+
+func (suite *APIKeyHandlerSuite) TestRevokedAndExpiredKeysAreNotRotated() {
+	past := time.Now().Add(-time.Minute)
+	suite.apiKeys.keys["revoked"] = entities.APIKey{ID: "revoked", OwnerUID: "owner", KeyHash: "revoked", RevokedAt: &past}
+	suite.apiKeys.keys["expired"] = entities.APIKey{ID: "expired", OwnerUID: "owner", KeyHash: "expired", ExpiresAt: &past}
+
+	for _, id := range []string{"revoked", "expired"} {
+		suite.Equal(fiber.StatusConflict, suite.rotate(id), id)
+		suite.Equal(id, suite.apiKeys.keys[id].KeyHash)
+	}
+}
+
+func (suite *APIKeyHandlerSuite) TestUnknownKeysAreNotFound() {
+	suite.Equal(fiber.StatusNotFound, suite.rotate("unknown"))
+}
+
+func TestAPIKeyHandlerSuite(t *testing.T) {
+	suite.Run(t, new(APIKeyHandlerSuite))
+}
+
+// fakeAPIKeys keeps API keys in memory, by id.
+type fakeAPIKeys struct {
+	repository.APIKeyRepository
+	keys map[string]entities.APIKey
+}
+
+func (fak *fakeAPIKeys) GetAPIKey(_ context.Context, ownerUID, id string) (*entities.APIKey, error) {
+	apiKey, ok := fak.keys[id]
+	if !ok || apiKey.OwnerUID != ownerUID {
+		return nil, nil
+	}
+
+	return &apiKey, nil
+}
+
+func (fak *fakeAPIKeys) RotateAPIKey(ctx context.Context, ownerUID, id, prefix, keyHash string) (*entities.APIKey, error) {
+	apiKey, err := fak.GetAPIKey(ctx, ownerUID, id)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = keyHash
+	fak.keys[id] = *apiKey
+
+	return apiKey, nil
+}
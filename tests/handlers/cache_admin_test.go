@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"testing"
+)
+
+type CacheAdminHandlerSuite struct {
+	suite.Suite
+	ctx   context.Context
+	cache *scanFailingCache
+	// roles are the roles of the caller.
+	roles     []interface{}
+	bengaluru geo.Location
+	london    geo.Location
+	app       *fiber.App
+}
+
+func (suite *CacheAdminHandlerSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.cache = &scanFailingCache{Cache: cache.NewLRUCache(100)}
+	suite.roles = []interface{}{entities.RoleAdmin}
+
+	rule := config.SnapRule{Scheme: config.SnapGeohash, Precision: 6}
+	suite.bengaluru = geo.Snap(rule, 12.9716, 77.5946)
+	suite.london = geo.Snap(rule, 51.5074, -0.1278)
+
+	weatherRepo := repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop())
+	suite.Require().NoError(weatherRepo.SetCurrentWeather(suite.ctx, suite.bengaluru, &entities.CurrentWeather{Name: "Bengaluru"}))
+	suite.Require().NoError(weatherRepo.SetCurrentWeather(suite.ctx, suite.london, &entities.CurrentWeather{Name: "London"}))
+	suite.Require().NoError(weatherRepo.SetFiveDayForecast(suite.ctx, suite.bengaluru, &entities.Forecast{}))
+
+	cacheAdminService := services.NewCacheAdminService(repository.NewCacheAdminRepository(suite.cache, zap.NewNop()), zap.NewNop())
+	cacheAdminHandler := handlers.NewCacheAdminHandler(cacheAdminService, zap.NewNop())
+
+	rbacMiddleware := middlewares.NewRBACMiddleware(middlewares.RolePolicy{
+		"GET /api/v1/admin/cache/entries":    {entities.RoleAdmin},
+		"GET /api/v1/admin/cache/entry":      {entities.RoleAdmin},
+		"DELETE /api/v1/admin/cache/entries": {entities.RoleAdmin},
+		"DELETE /api/v1/admin/cache":         {entities.RoleAdmin},
+	}, zap.NewNop())
+
+	suite.app = fiber.New()
+	cacheAdmin := suite.app.Group("/api/v1/admin/cache", func(ctx *fiber.Ctx) error {
+		ctx.Locals(middlewares.LocalsUID, "uid")
+		ctx.Locals(middlewares.LocalsClaims, map[string]interface{}{middlewares.ClaimRoles: suite.roles})
+		return ctx.Next()
+	})
+	cacheAdmin.Get("/entries", rbacMiddleware.Authorize, cacheAdminHandler.ListEntries)
+	cacheAdmin.Get("/entry", rbacMiddleware.Authorize, cacheAdminHandler.GetEntry)
+	cacheAdmin.Delete("/entries", rbacMiddleware.Authorize, cacheAdminHandler.PurgeEntries)
+	cacheAdmin.Delete("/", rbacMiddleware.Authorize, cacheAdminHandler.Flush)
+}
+
+// request sends a request to the cache admin API and decodes the data of a
+// successful response into data.
+func (suite *CacheAdminHandlerSuite) request(method, path string, data any) int {
+	status, res, err := send(suite.app, method, "/api/v1/admin/cache"+path, "")
+	suite.Require().NoError(err)
+
+	if status == fiber.StatusOK && data != nil {
+		suite.Require().NoError(json.Unmarshal(res.Data, data))
+	}
+
+	return status
+}
+
+func (suite *CacheAdminHandlerSuite) TestListEntries() {
+	var entries entities.CacheEntries
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodGet, "/entries", &entries))
+	suite.Len(entries.Entries, 3)
+	suite.False(entries.Truncated)
+
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodGet, "/entries?limit=1", &entries))
+	suite.Len(entries.Entries, 1)
+	suite.True(entries.Truncated)
+
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodGet, "/entries?resource=current_weather&lat=12.9716&long=77.5946", &entries))
+	suite.Require().Len(entries.Entries, 1)
+	suite.Equal(suite.bengaluru.Cell, entries.Entries[0].Cell)
+	suite.Greater(entries.Entries[0].TTL, int64(0))
+}
+
+func (suite *CacheAdminHandlerSuite) TestListEntriesValidatesTheFilter() {
+	for _, query := range []string{
+		"resource=unknown",
+		"minLat=12&minLong=77",
+		"minLat=13&minLong=77&maxLat=12&maxLong=78",
+		"lat=12",
+		"limit=1001",
+	} {
+		suite.Equal(fiber.StatusUnprocessableEntity, suite.request(fiber.MethodGet, "/entries?"+query, nil), query)
+	}
+}
+
+func (suite *CacheAdminHandlerSuite) TestGetEntry() {
+	key := "weather-wrapper:v1:current_weather_" + suite.london.Cell
+
+	var entry entities.CacheEntryInfo
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodGet, "/entry?key="+url.QueryEscape(key), &entry))
+	suite.Equal(key, entry.Key)
+	suite.Equal("current_weather", entry.Resource)
+	suite.NotNil(entry.FetchedAt)
+
+	suite.Equal(fiber.StatusNotFound, suite.request(fiber.MethodGet, "/entry?key=other_app:session", nil))
+	suite.Equal(fiber.StatusUnprocessableEntity, suite.request(fiber.MethodGet, "/entry", nil))
+}
+
+func (suite *CacheAdminHandlerSuite) TestPurgeEntries() {
+	// Everything is removed with a flush, not a purge without a filter.
+	suite.Equal(fiber.StatusUnprocessableEntity, suite.request(fiber.MethodDelete, "/entries", nil))
+
+	var purge entities.CachePurge
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodDelete, "/entries?lat=12.9716&long=77.5946", &purge))
+	suite.Equal(2, purge.Deleted)
+
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodDelete, "/entries?resource=current_weather", &purge))
+	suite.Equal(1, purge.Deleted)
+
+	keys, err := suite.cache.Scan(suite.ctx, "*")
+	suite.NoError(err)
+	suite.Empty(keys)
+}
+
+func (suite *CacheAdminHandlerSuite) TestFlush() {
+	var purge entities.CachePurge
+	suite.Equal(fiber.StatusOK, suite.request(fiber.MethodDelete, "/", &purge))
+	suite.Equal(3, purge.Deleted)
+}
+
+func (suite *CacheAdminHandlerSuite) TestCacheFailures() {
+	suite.cache.scanErr = errors.New("connection refused")
+
+	suite.Equal(fiber.StatusInternalServerError, suite.request(fiber.MethodGet, "/entries", nil))
+	suite.Equal(fiber.StatusInternalServerError, suite.request(fiber.MethodDelete, "/entries?resource=current_weather", nil))
+	suite.Equal(fiber.StatusInternalServerError, suite.request(fiber.MethodDelete, "/", nil))
+}
+
+func (suite *CacheAdminHandlerSuite) TestOnlyAdminsAreAllowed() {
+	suite.roles = []interface{}{"viewer"}
+
+	suite.Equal(fiber.StatusForbidden, suite.request(fiber.MethodGet, "/entries", nil))
+	suite.Equal(fiber.StatusForbidden, suite.request(fiber.MethodGet, "/entry?key=key", nil))
+	suite.Equal(fiber.StatusForbidden, suite.request(fiber.MethodDelete, "/entries?resource=current_weather", nil))
+	suite.Equal(fiber.StatusForbidden, suite.request(fiber.MethodDelete, "/", nil))
+
+	keys, err := suite.cache.Scan(suite.ctx, "*")
+	suite.NoError(err)
+	suite.Len(keys, 3)
+}
+
+func TestCacheAdminHandlerSuite(t *testing.T) {
+	suite.Run(t, new(CacheAdminHandlerSuite))
+}
+
+// scanFailingCache fails every scan with scanErr when it is set.
+type scanFailingCache struct {
+	cache.Cache
+	scanErr error
+}
+
+func (sfc *scanFailingCache) Scan(ctx context.Context, pattern string) ([]string, error) {
+	if sfc.scanErr != nil {
+		return nil, sfc.scanErr
+	}
+
+	return sfc.Cache.Scan(ctx, pattern)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type FetchErrorSuite struct {
+	suite.Suite
+	weatherService *stubWeatherService
+	app            *fiber.App
+}
+
+func (suite *FetchErrorSuite) SetupTest() {
+	suite.weatherService = &stubWeatherService{}
+	weatherHandler := handlers.NewWeatherHandler(suite.weatherService, zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Get("/weather/now", weatherHandler.GetCurrentWeather)
+}
+
+func (suite *FetchErrorSuite) TestErrorsAreMappedToStatuses() {
+	errorStatuses := []struct {
+		err    error
+		status int
+	}{
+		{upstream.ErrNotFound, fiber.StatusNotFound},
+		{upstream.ErrInvalidInput, fiber.StatusBadRequest},
+		{upstream.ErrRateLimited, fiber.StatusTooManyRequests},
+		{upstream.ErrUnauthorized, fiber.StatusBadGateway},
+		{upstream.ErrBadPayload, fiber.StatusBadGateway},
+		{upstream.ErrUnavailable, fiber.StatusServiceUnavailable},
+		{upstream.ErrCircuitOpen, fiber.StatusServiceUnavailable},
+		{errors.New("connection refused"), fiber.StatusInternalServerError},
+	}
+
+	for _, errorStatus := range errorStatuses {
+		suite.weatherService.err = fmt.Errorf("get https://api.openweathermap.org/data/2.5/weather?appid=secret: %w", errorStatus.err)
+
+		status, res, err := send(suite.app, fiber.MethodGet, "/weather/now?lat=0&long=0", "")
+		suite.Require().NoError(err)
+		suite.Equal(errorStatus.status, status, errorStatus.err.Error())
+		suite.Equal(errorStatus.status, res.Status)
+		suite.NotEmpty(res.Error)
+	}
+}
+
+func (suite *FetchErrorSuite) TestErrorsAreNotEchoed() {
+	suite.weatherService.err = fmt.Errorf("get https://api.openweathermap.org/data/2.5/weather?appid=secret: %w", upstream.ErrUnauthorized)
+
+	_, res, err := send(suite.app, fiber.MethodGet, "/weather/now?lat=0&long=0", "")
+	suite.Require().NoError(err)
+	suite.NotContains(res.Error, "appid")
+	suite.NotContains(res.Message, "appid")
+	suite.NotContains(res.Message, upstream.ErrUnauthorized.Error())
+}
+
+func TestFetchErrorSuite(t *testing.T) {
+	suite.Run(t, new(FetchErrorSuite))
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http/httptest"
+	"strings"
+)
+
+// response is the decoded body of utils.CustomResponse.
+type response struct {
+	Data    json.RawMessage `json:"data"`
+	Status  int             `json:"status"`
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+}
+
+// sendRaw sends a request without a body to app and returns the status and
+// the body of the response.
+func sendRaw(app *fiber.App, method, path string) (int, []byte, error) {
+	res, err := app.Test(httptest.NewRequest(method, path, nil))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	return res.StatusCode, body, err
+}
+
+// send sends a request to app, with body as JSON unless it is empty, and
+// returns the status and the decoded response.
+func send(app *fiber.App, method, path, body string) (int, response, error) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+
+	res, err := app.Test(req)
+	if err != nil {
+		return 0, response{}, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, response{}, err
+	}
+
+	var decoded response
+	err = json.Unmarshal(resBody, &decoded)
+
+	return res.StatusCode, decoded, err
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type UserHandlerSuite struct {
+	suite.Suite
+	userService *stubUserService
+	app         *fiber.App
+}
+
+func (suite *UserHandlerSuite) SetupTest() {
+	suite.userService = &stubUserService{}
+	userHandler := handlers.NewUserHandler(suite.userService, zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Post("/users/token/exchange", userHandler.ExchangeToken)
+	suite.app.Post("/users/token/refresh", userHandler.RefreshToken)
+	suite.app.Put("/users/:uid/roles", userHandler.SetUserRoles)
+	suite.app.Delete("/users/:uid/roles", userHandler.ClearUserRoles)
+}
+
+// post sends body as JSON to path and returns the status and the session
+// in the response, if any.
+func (suite *UserHandlerSuite) post(path, body string) (int, entities.Session) {
+	status, res, err := send(suite.app, fiber.MethodPost, path, body)
+	suite.Require().NoError(err)
+
+	var session entities.Session
+	if status == fiber.StatusOK {
+		suite.Require().NoError(json.Unmarshal(res.Data, &session))
+	}
+
+	return status, session
+}
+
+func (suite *UserHandlerSuite) TestExchangeTokenReturnsTheSession() {
+	suite.userService.session = &entities.Session{UID: "uid", IDToken: "id-token", RefreshToken: "refresh-token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	status, session := suite.post("/users/token/exchange", `{"email":"test@test.com","password":"testpassword"}`)
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal("test@test.com", suite.userService.email)
+	suite.Equal("testpassword", suite.userService.password)
+	suite.Equal("id-token", session.IDToken)
+	suite.Equal("refresh-token", session.RefreshToken)
+}
+
+func (suite *UserHandlerSuite) TestExchangeTokenRejectsInvalidCredentials() {
+	suite.userService.err = identity.ErrInvalidCredentials
+
+	status, _ := suite.post("/users/token/exchange", `{"email":"test@test.com","password":"wrongpassword"}`)
+	suite.Equal(fiber.StatusUnauthorized, status)
+}
+
+func (suite *UserHandlerSuite) TestExchangeTokenValidatesTheBody() {
+	status, _ := suite.post("/users/token/exchange", `{"email":"not-an-email","password":"testpassword"}`)
+	suite.Equal(fiber.StatusUnprocessableEntity, status)
+
+	status, _ = suite.post("/users/token/exchange", `{"email":`)
+	suite.Equal(fiber.StatusBadRequest, status)
+
+	suite.Empty(suite.userService.email)
+}
+
+func (suite *UserHandlerSuite) TestExchangeTokenFailures() {
+	suite.userService.err = errors.New("identity provider is down")
+
+	status, _ := suite.post("/users/token/exchange", `{"email":"test@test.com","password":"testpassword"}`)
+	suite.Equal(fiber.StatusInternalServerError, status)
+}
+
+func (suite *UserHandlerSuite) TestRefreshTokenReturnsTheSession() {
+	suite.userService.session = &entities.Session{UID: "uid", IDToken: "new-id-token", RefreshToken: "new-refresh-token"}
+
+	status, session := suite.post("/users/token/refresh", `{"refreshToken":"refresh-token"}`)
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal("refresh-token", suite.userService.refreshToken)
+	suite.Equal("new-id-token", session.IDToken)
+}
+
+func (suite *UserHandlerSuite) TestRefreshTokenRejectsInvalidTokens() {
+	suite.userService.err = identity.ErrInvalidToken
+
+	status, _ := suite.post("/users/token/refresh", `{"refreshToken":"expired"}`)
+	suite.Equal(fiber.StatusUnauthorized, status)
+
+	status, _ = suite.post("/users/token/refresh", `{}`)
+	suite.Equal(fiber.StatusUnprocessableEntity, status)
+}
+
+func (suite *UserHandlerSuite) TestSetUserRolesReplacesTheRoles() {
+	status, res, err := send(suite.app, fiber.MethodPut, "/users/uid/roles", `{"roles":["reader","partner"]}`)
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal("uid", suite.userService.uid)
+	suite.Equal([]string{entities.RoleReader, entities.RolePartner}, suite.userService.roles)
+	suite.JSONEq(`"uid"`, string(res.Data))
+}
+
+func (suite *UserHandlerSuite) TestSetUserRolesValidatesTheBody() {
+	for _, body := range []string{`{"roles":[]}`, `{"roles":["owner"]}`, `{}`} {
+		status, _, err := send(suite.app, fiber.MethodPut, "/users/uid/roles", body)
+		suite.Require().NoError(err)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, body)
+	}
+
+	suite.Empty(suite.userService.uid)
+}
+
+func (suite *UserHandlerSuite) TestClearUserRolesRemovesTheRoles() {
+	suite.userService.roles = []string{entities.RoleAdmin}
+
+	status, _, err := send(suite.app, fiber.MethodDelete, "/users/uid/roles", "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal("uid", suite.userService.uid)
+	suite.Nil(suite.userService.roles)
+}
+
+func (suite *UserHandlerSuite) TestRoleUpdateFailures() {
+	suite.userService.err = errors.New("identity provider is down")
+
+	status, _, err := send(suite.app, fiber.MethodPut, "/users/uid/roles", `{"roles":["reader"]}`)
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusInternalServerError, status)
+
+	status, _, err = send(suite.app, fiber.MethodDelete, "/users/uid/roles", "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusInternalServerError, status)
+}
+
+func TestUserHandlerSuite(t *testing.T) {
+	suite.Run(t, new(UserHandlerSuite))
+}
+
+// stubUserService records the credentials and roles it is given and answers
+// with session or err. Methods the tests do not call are left unimplemented.
+type stubUserService struct {
+	services.UserService
+	email        string
+	password     string
+	refreshToken string
+	uid          string
+	roles        []string
+	session      *entities.Session
+	err          error
+}
+
+func (sus *stubUserService) ExchangeCredentials(email, password string) (*entities.Session, error) {
+	sus.email, sus.password = email, password
+	return sus.session, sus.err
+}
+
+func (sus *stubUserService) RefreshSession(refreshToken string) (*entities.Session, error) {
+	sus.refreshToken = refreshToken
+	return sus.session, sus.err
+}
+
+func (sus *stubUserService) SetUserRoles(uid string, roles []string) (*string, error) {
+	if sus.err != nil {
+		return nil, sus.err
+	}
+
+	sus.uid, sus.roles = uid, roles
+	return &uid, nil
+}
+
+func (sus *stubUserService) ClearUserRoles(uid string) (*string, error) {
+	if sus.err != nil {
+		return nil, sus.err
+	}
+
+	sus.uid, sus.roles = uid, nil
+	return &uid, nil
+}
@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/handlers"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type ValidationSuite struct {
+	suite.Suite
+	weatherService   *stubWeatherService
+	geocodingService *stubGeocodingService
+	summaryService   *stubSummaryService
+	app              *fiber.App
+}
+
+func (suite *ValidationSuite) SetupTest() {
+	suite.weatherService = &stubWeatherService{}
+	suite.geocodingService = &stubGeocodingService{}
+	suite.summaryService = &stubSummaryService{}
+
+	weatherHandler := handlers.NewWeatherHandler(suite.weatherService, zap.NewNop())
+	geocodingHandler := handlers.NewGeocodingHandler(suite.geocodingService, zap.NewNop())
+	airPollutionHandler := handlers.NewAirPollutionHandler(nil, nil, suite.summaryService, zap.NewNop())
+	usageHandler := handlers.NewUsageHandler(nil, zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Get("/weather/now", weatherHandler.GetCurrentWeather)
+	suite.app.Get("/geocode", geocodingHandler.GetGeocodeForCity)
+	suite.app.Get("/air-pollution/history", airPollutionHandler.GetHistoricalAirPollution)
+	suite.app.Get("/usage", func(ctx *fiber.Ctx) error {
+		ctx.Locals(middlewares.LocalsUID, "uid")
+		return ctx.Next()
+	}, usageHandler.GetUsage)
+}
+
+// get sends a GET to path and returns the status and the invalid fields in
+// the response, if any.
+func (suite *ValidationSuite) get(path string) (int, []utils.FieldError) {
+	status, res, err := send(suite.app, fiber.MethodGet, path, "")
+	suite.Require().NoError(err)
+
+	var fields []utils.FieldError
+	if status == fiber.StatusUnprocessableEntity {
+		suite.Require().NoError(json.Unmarshal(res.Data, &fields))
+	}
+
+	return status, fields
+}
+
+func (suite *ValidationSuite) TestValidLatLon() {
+	validLatLonPairs := []struct {
+		latString string
+		lonString string
+		lat       float32
+		lon       float32
+	}{
+		{"0", "0", 0.0, 0.0},
+		{"40.7128", "-74.0060", 40.7128, -74.0060},
+		{"-33.8688", "151.2093", -33.8688, 151.2093},
+		{"51.5074", "-0.1278", 51.5074, -0.1278},
+		{"-90", "180", -90.0, 180.0},
+		{"90", "-180", 90.0, -180.0},
+		{"37.7749", "-122.4194", 37.7749, -122.4194},
+		{"-45.0", "45.0", -45.0, 45.0},
+		{"25.7617", "-80.1918", 25.7617, -80.1918},
+	}
+
+	for _, latLon := range validLatLonPairs {
+		status, _ := suite.get(fmt.Sprintf("/weather/now?lat=%s&long=%s", latLon.latString, latLon.lonString))
+		suite.Equal(fiber.StatusOK, status)
+		suite.Equal(latLon.lat, suite.weatherService.lat)
+		suite.Equal(latLon.lon, suite.weatherService.lon)
+	}
+}
+
+func (suite *ValidationSuite) TestInvalidLatLon() {
+	invalidLatLonPairs := []struct {
+		query   string
+		message string
+	}{
+		{"long=0", "lat is required"},
+		{"lat=0", "long is required"},
+		{"lat=invalid&long=0", "lat must be a valid number"},
+		{"lat=0&long=invalid", "long must be a valid number"},
+		{"lat=invalid&long=invalid", "lat must be a valid number; long must be a valid number"},
+		{"lat=100&long=0", "lat must be less than or equal to 90"},
+		{"lat=-100&long=0", "lat must be greater than or equal to -90"},
+		{"lat=0&long=200", "long must be less than or equal to 180"},
+		{"lat=0&long=-200", "long must be greater than or equal to -180"},
+	}
+
+	for _, latLon := range invalidLatLonPairs {
+		status, fields := suite.get("/weather/now?" + latLon.query)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, latLon.query)
+		suite.Equal(latLon.message, (&utils.ValidationError{Fields: fields}).Error(), latLon.query)
+	}
+
+	suite.False(suite.weatherService.called)
+}
+
+func (suite *ValidationSuite) TestNonNumericParametersAreTypeErrors() {
+	status, fields := suite.get("/weather/now?lat=invalid&long=invalid")
+	suite.Equal(fiber.StatusUnprocessableEntity, status)
+	suite.Equal([]utils.FieldError{
+		{Field: "lat", Rule: "type", Param: "number", Message: "lat must be a valid number"},
+		{Field: "long", Rule: "type", Param: "number", Message: "long must be a valid number"},
+	}, fields)
+
+	status, fields = suite.get("/geocode?city=Paris&limit=invalid")
+	suite.Equal(fiber.StatusUnprocessableEntity, status)
+	suite.Equal([]utils.FieldError{
+		{Field: "limit", Rule: "type", Param: "integer", Message: "limit must be a valid integer"},
+	}, fields)
+}
+
+func (suite *ValidationSuite) TestValidCityAndLimit() {
+	for _, city := range []string{"San Francisco", "New York", "Paris", "Tokyo", "New Delhi"} {
+		status, _ := suite.get("/geocode?city=" + url.QueryEscape(city))
+		suite.Equal(fiber.StatusOK, status)
+		suite.Equal(city, suite.geocodingService.city)
+		suite.Equal(5, suite.geocodingService.limit)
+	}
+
+	for limit := 1; limit <= 10; limit++ {
+		status, _ := suite.get(fmt.Sprintf("/geocode?city=Paris&limit=%d", limit))
+		suite.Equal(fiber.StatusOK, status)
+		suite.Equal(limit, suite.geocodingService.limit)
+	}
+}
+
+func (suite *ValidationSuite) TestInvalidCityAndLimit() {
+	invalidQueries := []struct {
+		query   string
+		message string
+	}{
+		{"limit=5", "city is required"},
+		{"city=", "city is required"},
+		{"city=Paris&limit=-1", "limit must be at least 1"},
+		{"city=Paris&limit=0", "limit must be at least 1"},
+		{"city=Paris&limit=11", "limit must be at most 10"},
+	}
+
+	for _, invalid := range invalidQueries {
+		status, fields := suite.get("/geocode?" + invalid.query)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, invalid.query)
+		suite.Equal(invalid.message, (&utils.ValidationError{Fields: fields}).Error(), invalid.query)
+	}
+
+	suite.Empty(suite.geocodingService.city)
+}
+
+func (suite *ValidationSuite) TestInvalidMonthsAreValidationErrors() {
+	for _, month := range []string{"2024-13", "March", "2024-3-1"} {
+		status, fields := suite.get("/usage?month=" + month)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, month)
+		suite.Require().Len(fields, 1)
+		suite.Equal("month", fields[0].Field)
+	}
+}
+
+func (suite *ValidationSuite) TestTooLongHistoryRangesAreBadRequests() {
+	suite.summaryService.err = fmt.Errorf("%w: it may span at most %s", services.ErrHistoryRangeTooLong, 24*time.Hour)
+
+	status, res, err := send(suite.app, fiber.MethodGet, "/air-pollution/history?lat=0&long=0&start=1700000000&end=1700172800", "")
+	suite.Require().NoError(err)
+	suite.Equal(fiber.StatusBadRequest, status)
+	suite.Equal(fiber.StatusBadRequest, res.Status)
+	suite.Equal("the history range is too long", res.Error)
+	suite.Equal(suite.summaryService.err.Error(), res.Message)
+}
+
+func (suite *ValidationSuite) TestHistoryRangesAreBoundedByUpstreamHistory() {
+	now := time.Now().Unix()
+
+	invalidQueries := []struct {
+		query   string
+		message string
+	}{
+		{"start=0&end=172800", "start must be greater than or equal to 1606435200"},
+		{fmt.Sprintf("start=%d&end=%d", now, now+3600), "end must not be in the future"},
+	}
+
+	for _, invalid := range invalidQueries {
+		status, fields := suite.get("/air-pollution/history?lat=0&long=0&" + invalid.query)
+		suite.Equal(fiber.StatusUnprocessableEntity, status, invalid.query)
+		suite.Equal(invalid.message, (&utils.ValidationError{Fields: fields}).Error(), invalid.query)
+	}
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(ValidationSuite))
+}
+
+// stubWeatherService records the coordinates it is asked for and fails
+// with err when it is set.
+type stubWeatherService struct {
+	services.WeatherService
+	called   bool
+	lat, lon float32
+	err      error
+}
+
+func (sws *stubWeatherService) GetCurrentWeather(_ context.Context, lat, lon float32) (*entities.CurrentWeather, error) {
+	sws.called, sws.lat, sws.lon = true, lat, lon
+	if sws.err != nil {
+		return nil, sws.err
+	}
+
+	return &entities.CurrentWeather{}, nil
+}
+
+// stubGeocodingService records the city and limit it is asked for.
+type stubGeocodingService struct {
+	services.GeocodingService
+	city  string
+	limit int
+}
+
+func (sgs *stubGeocodingService) GetGeocodeForCity(_ context.Context, city string, limit int) (*entities.Coord, error) {
+	sgs.city, sgs.limit = city, limit
+	return &entities.Coord{}, nil
+}
+
+// stubSummaryService answers every history summary with err.
+type stubSummaryService struct {
+	services.AirPollutionSummaryService
+	err error
+}
+
+func (sss *stubSummaryService) GetHistorySummary(context.Context, float32, float32, int64, int64, string) (*entities.HistoricalAirPollutionResponse, error) {
+	return nil, sss.err
+}
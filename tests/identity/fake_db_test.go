@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	insertColumnsPattern = regexp.MustCompile(`\(("[a-z_]+"(?:,"[a-z_]+")*)\) VALUES`)
+	setColumnPattern     = regexp.MustCompile(`"([a-z_]+)"=\$(\d+)`)
+	whereColumnPattern   = regexp.MustCompile(`WHERE "?([a-z_]+)"? = \$(\d+)`)
+)
+
+// fakeUsers is an in-memory local_users table behind a database/sql driver
+// that understands the few statements GORM sends for the local identity
+// provider, so the provider can be tested without Postgres.
+type fakeUsers struct {
+	mu      sync.Mutex
+	columns []string
+	rows    []map[string]driver.Value
+}
+
+// openFakeUsers returns a GORM handle on a new empty table.
+func openFakeUsers() (*gorm.DB, *fakeUsers, error) {
+	users := &fakeUsers{}
+	sqlDB := sql.OpenDB(fakeUsersConnector{users: users})
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, users, nil
+}
+
+// row returns a copy of the row where column holds value, or nil.
+func (fu *fakeUsers) row(column string, value driver.Value) map[string]driver.Value {
+	fu.mu.Lock()
+	defer fu.mu.Unlock()
+
+	for _, row := range fu.rows {
+		if row[column] == value {
+			copied := make(map[string]driver.Value, len(row))
+			for key, value := range row {
+				copied[key] = value
+			}
+
+			return copied
+		}
+	}
+
+	return nil
+}
+
+func (fu *fakeUsers) exec(query string, args []driver.Value) (int64, error) {
+	fu.mu.Lock()
+	defer fu.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		match := insertColumnsPattern.FindStringSubmatch(query)
+		if match == nil {
+			return 0, fmt.Errorf("unsupported insert: %s", query)
+		}
+
+		fu.columns = strings.Split(strings.ReplaceAll(match[1], `"`, ""), ",")
+
+		row := make(map[string]driver.Value, len(fu.columns))
+		for i, column := range fu.columns {
+			row[column] = args[i]
+		}
+
+		fu.rows = append(fu.rows, row)
+		return 1, nil
+	case strings.HasPrefix(query, "UPDATE"):
+		column, value, err := where(query, args)
+		if err != nil {
+			return 0, err
+		}
+
+		var updated int64
+		for _, row := range fu.rows {
+			if row[column] != value {
+				continue
+			}
+
+			for _, set := range setColumnPattern.FindAllStringSubmatch(query, -1) {
+				position, _ := strconv.Atoi(set[2])
+				row[set[1]] = args[position-1]
+			}
+			updated++
+		}
+
+		return updated, nil
+	case strings.HasPrefix(query, "DELETE"):
+		column, value, err := where(query, args)
+		if err != nil {
+			return 0, err
+		}
+
+		kept := fu.rows[:0]
+		for _, row := range fu.rows {
+			if row[column] != value {
+				kept = append(kept, row)
+			}
+		}
+
+		deleted := int64(len(fu.rows) - len(kept))
+		fu.rows = kept
+		return deleted, nil
+	}
+
+	return 0, fmt.Errorf("unsupported statement: %s", query)
+}
+
+func (fu *fakeUsers) query(query string, args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(query, "SELECT") {
+		return nil, fmt.Errorf("unsupported query: %s", query)
+	}
+
+	column, value, err := where(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &fakeRows{columns: fu.columns}
+	if row := fu.row(column, value); row != nil {
+		rows.values = row
+	}
+
+	return rows, nil
+}
+
+// where returns the column and value of the single condition of query.
+func where(query string, args []driver.Value) (string, driver.Value, error) {
+	match := whereColumnPattern.FindStringSubmatch(query)
+	if match == nil {
+		return "", nil, fmt.Errorf("unsupported condition: %s", query)
+	}
+
+	position, _ := strconv.Atoi(match[2])
+	return match[1], args[position-1], nil
+}
+
+type fakeUsersDriver struct{}
+
+func (fakeUsersDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("fake users are opened through their connector")
+}
+
+type fakeUsersConnector struct {
+	users *fakeUsers
+}
+
+func (fc fakeUsersConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{users: fc.users}, nil
+}
+
+func (fakeUsersConnector) Driver() driver.Driver {
+	return fakeUsersDriver{}
+}
+
+type fakeConn struct {
+	users *fakeUsers
+}
+
+func (fc fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{users: fc.users, query: query}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	users *fakeUsers
+	query string
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (fs fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	affected, err := fs.users.exec(fs.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+func (fs fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return fs.users.query(fs.query, args)
+}
+
+// fakeRows holds at most one row.
+type fakeRows struct {
+	columns []string
+	values  map[string]driver.Value
+}
+
+func (fr *fakeRows) Columns() []string {
+	return fr.columns
+}
+
+func (fr *fakeRows) Close() error {
+	return nil
+}
+
+func (fr *fakeRows) Next(dest []driver.Value) error {
+	if fr.values == nil {
+		return io.EOF
+	}
+
+	for i, column := range fr.columns {
+		dest[i] = fr.values[column]
+	}
+	fr.values = nil
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type LocalProviderSuite struct {
+	suite.Suite
+	ctx      context.Context
+	db       *gorm.DB
+	users    *fakeUsers
+	conf     config.IdentityConfig
+	provider identity.Provider
+}
+
+func (suite *LocalProviderSuite) SetupTest() {
+	var err error
+
+	suite.ctx = context.Background()
+	suite.db, suite.users, err = openFakeUsers()
+	suite.Require().NoError(err)
+
+	suite.conf = config.IdentityConfig{
+		Provider:        identity.ProviderLocal,
+		JWTAlgorithm:    "HS256",
+		JWTSecret:       testSecret,
+		JWTIssuer:       "weather-wrapper-test",
+		TokenTTL:        time.Hour,
+		RefreshTokenTTL: 24 * time.Hour,
+		ActionURL:       "http://localhost:8181/api/v1/users/action",
+	}
+
+	suite.provider, err = identity.NewLocalProvider(suite.db, suite.conf)
+	suite.Require().NoError(err)
+}
+
+func (suite *LocalProviderSuite) createUser(email, password string) string {
+	uid, err := suite.provider.CreateUser(suite.ctx, &entities.UserDetails{
+		Email:       email,
+		PhoneNumber: "+911234567890",
+		Password:    password,
+		DisplayName: "TEST",
+	})
+	suite.Require().NoError(err)
+
+	return uid
+}
+
+// oobCode returns the code of an action link.
+func (suite *LocalProviderSuite) oobCode(link string) string {
+	parsed, err := url.Parse(link)
+	suite.Require().NoError(err)
+
+	return parsed.Query().Get("oobCode")
+}
+
+func (suite *LocalProviderSuite) TestPasswordsAreStoredHashed() {
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	row := suite.users.row("uid", uid)
+	suite.Require().NotNil(row)
+
+	passwordHash, _ := row["password_hash"].(string)
+	suite.NotEqual("testpassword", passwordHash)
+	suite.NoError(bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("testpassword")))
+}
+
+func (suite *LocalProviderSuite) TestSignInIssuesVerifiableTokens() {
+	uid := suite.createUser("test@test.com", "testpassword")
+	suite.Require().NoError(suite.provider.SetCustomClaims(suite.ctx, uid, map[string]interface{}{"roles": []string{entities.RoleAdmin}}))
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+	suite.Equal(uid, session.UID)
+	suite.WithinDuration(time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+
+	token, err := suite.provider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.Require().NoError(err)
+	suite.Equal(uid, token.UID)
+	suite.Equal("test@test.com", token.Claims["email"])
+	suite.Equal([]interface{}{entities.RoleAdmin}, token.Claims["roles"])
+}
+
+func (suite *LocalProviderSuite) TestWrongPasswordsAndUnknownEmailsAreRejected() {
+	suite.createUser("test@test.com", "testpassword")
+
+	_, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "wrongpassword")
+	suite.ErrorIs(err, identity.ErrInvalidCredentials)
+
+	_, err = suite.provider.SignInWithPassword(suite.ctx, "other@test.com", "testpassword")
+	suite.ErrorIs(err, identity.ErrInvalidCredentials)
+}
+
+func (suite *LocalProviderSuite) TestRefreshTokensOnlyRefresh() {
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	_, err = suite.provider.VerifyIDToken(suite.ctx, session.RefreshToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	_, err = suite.provider.RefreshSession(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	refreshed, err := suite.provider.RefreshSession(suite.ctx, session.RefreshToken)
+	suite.Require().NoError(err)
+	suite.Equal(uid, refreshed.UID)
+
+	_, err = suite.provider.VerifyIDToken(suite.ctx, refreshed.IDToken)
+	suite.NoError(err)
+}
+
+func (suite *LocalProviderSuite) TestTokensOfOtherKeysAndExpiredTokensAreRejected() {
+	suite.createUser("test@test.com", "testpassword")
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	otherConf := suite.conf
+	otherConf.JWTSecret = "fedcba9876543210fedcba9876543210"
+	otherProvider, err := identity.NewLocalProvider(suite.db, otherConf)
+	suite.Require().NoError(err)
+
+	_, err = otherProvider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	otherConf = suite.conf
+	otherConf.JWTIssuer = "someone-else"
+	otherProvider, err = identity.NewLocalProvider(suite.db, otherConf)
+	suite.Require().NoError(err)
+
+	_, err = otherProvider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	expiredConf := suite.conf
+	expiredConf.TokenTTL = -time.Minute
+	expiredProvider, err := identity.NewLocalProvider(suite.db, expiredConf)
+	suite.Require().NoError(err)
+
+	expired, err := expiredProvider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	_, err = suite.provider.VerifyIDToken(suite.ctx, expired.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+}
+
+func (suite *LocalProviderSuite) TestDisabledUsersAreRejected() {
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.provider.UpdateUser(suite.ctx, uid, &entities.UserDetails{
+		Email:       "test@test.com",
+		PhoneNumber: "+911234567890",
+		DisplayName: "TEST",
+		Disabled:    true,
+	}))
+
+	_, err = suite.provider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	_, err = suite.provider.RefreshSession(suite.ctx, session.RefreshToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	_, err = suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.ErrorIs(err, identity.ErrInvalidCredentials)
+}
+
+func (suite *LocalProviderSuite) TestDeletedUsersAreRejected() {
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.provider.DeleteUser(suite.ctx, uid))
+
+	_, err = suite.provider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+}
+
+func (suite *LocalProviderSuite) TestEdDSATokens() {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	suite.Require().NoError(err)
+
+	edConf := suite.conf
+	edConf.JWTAlgorithm = "EdDSA"
+	edConf.JWTPrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	edProvider, err := identity.NewLocalProvider(suite.db, edConf)
+	suite.Require().NoError(err)
+
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	session, err := edProvider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	token, err := edProvider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.Require().NoError(err)
+	suite.Equal(uid, token.UID)
+
+	// EdDSA tokens do not verify with the HS256 key.
+	_, err = suite.provider.VerifyIDToken(suite.ctx, session.IDToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+}
+
+func (suite *LocalProviderSuite) TestShortSecretsAreRejected() {
+	shortConf := suite.conf
+	shortConf.JWTSecret = "too-short"
+
+	_, err := identity.NewLocalProvider(suite.db, shortConf)
+	suite.Error(err)
+}
+
+func (suite *LocalProviderSuite) TestVerifyEmailLinks() {
+	uid := suite.createUser("test@test.com", "testpassword")
+
+	link, err := suite.provider.EmailVerificationLink(suite.ctx, "test@test.com")
+	suite.Require().NoError(err)
+	suite.Contains(link, suite.conf.ActionURL+"?")
+	suite.Contains(link, "mode="+identity.ActionVerifyEmail)
+
+	// The code only applies to the action it was issued for.
+	err = suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, suite.oobCode(link), "newpassword")
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	suite.NoError(suite.provider.ApplyAction(suite.ctx, identity.ActionVerifyEmail, suite.oobCode(link), ""))
+	suite.Equal(true, suite.users.row("uid", uid)["email_verified"])
+}
+
+func (suite *LocalProviderSuite) TestResetPasswordLinks() {
+	suite.createUser("test@test.com", "testpassword")
+
+	link, err := suite.provider.PasswordResetLink(suite.ctx, "test@test.com")
+	suite.Require().NoError(err)
+
+	err = suite.provider.ApplyAction(suite.ctx, identity.ActionVerifyEmail, suite.oobCode(link), "")
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	err = suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, "not-a-code", "newpassword")
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	suite.NoError(suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, suite.oobCode(link), "newpassword"))
+
+	_, err = suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.ErrorIs(err, identity.ErrInvalidCredentials)
+
+	_, err = suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "newpassword")
+	suite.NoError(err)
+}
+
+func (suite *LocalProviderSuite) TestResetCodesAreSingleUse() {
+	suite.createUser("test@test.com", "testpassword")
+
+	link, err := suite.provider.PasswordResetLink(suite.ctx, "test@test.com")
+	suite.Require().NoError(err)
+
+	suite.NoError(suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, suite.oobCode(link), "newpassword"))
+
+	err = suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, suite.oobCode(link), "otherpassword")
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	_, err = suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "newpassword")
+	suite.NoError(err)
+}
+
+func (suite *LocalProviderSuite) TestResetsEndExistingSessions() {
+	suite.createUser("test@test.com", "testpassword")
+
+	session, err := suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "testpassword")
+	suite.Require().NoError(err)
+
+	link, err := suite.provider.PasswordResetLink(suite.ctx, "test@test.com")
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.provider.ApplyAction(suite.ctx, identity.ActionResetPassword, suite.oobCode(link), "newpassword"))
+
+	_, err = suite.provider.RefreshSession(suite.ctx, session.RefreshToken)
+	suite.ErrorIs(err, identity.ErrInvalidToken)
+
+	session, err = suite.provider.SignInWithPassword(suite.ctx, "test@test.com", "newpassword")
+	suite.Require().NoError(err)
+
+	_, err = suite.provider.RefreshSession(suite.ctx, session.RefreshToken)
+	suite.NoError(err)
+}
+
+func TestLocalProviderSuite(t *testing.T) {
+	suite.Run(t, new(LocalProviderSuite))
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/mailer"
+	"github.com/stretchr/testify/suite"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+type TemplatesSuite struct {
+	suite.Suite
+}
+
+func (suite *TemplatesSuite) TestRenderVerifyEmail() {
+	email, err := mailer.Render(mailer.TemplateVerifyEmail, mailer.LinkData{
+		Email: "test@test.com",
+		Link:  "https://example.com/action?mode=verifyEmail&oobCode=abc",
+	})
+	suite.Nil(err)
+	suite.Equal("Verify your email address", email.Subject)
+	suite.Contains(email.Text, "test@test.com")
+	suite.Contains(email.Text, "https://example.com/action?mode=verifyEmail&oobCode=abc")
+	suite.Contains(email.HTML, `href="https://example.com/action?mode=verifyEmail&amp;oobCode=abc"`)
+	suite.True(strings.HasPrefix(email.HTML, "<!DOCTYPE html>"))
+}
+
+func (suite *TemplatesSuite) TestRenderResetPassword() {
+	email, err := mailer.Render(mailer.TemplateResetPassword, mailer.LinkData{
+		Email: "<b>test@test.com</b>",
+		Link:  "https://example.com/action",
+	})
+	suite.Nil(err)
+	suite.Equal("Reset your password", email.Subject)
+	suite.Contains(email.Text, "<b>test@test.com</b>")
+	suite.Contains(email.HTML, "&lt;b&gt;test@test.com&lt;/b&gt;")
+}
+
+func (suite *TemplatesSuite) TestRenderUnknownTemplate() {
+	email, err := mailer.Render("unknown", nil)
+	suite.Nil(email)
+	suite.NotNil(err)
+}
+
+func (suite *TemplatesSuite) TestBuildMessage() {
+	message, err := mailer.BuildMessage(
+		&mail.Address{Name: "Weather Wrapper", Address: "no-reply@weather-wrapper.local"},
+		&mail.Address{Address: "test@test.com"},
+		&mailer.Email{Subject: "Réinitialiser", Text: "text body", HTML: "<p>html body</p>"},
+	)
+	suite.Nil(err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(message)))
+	suite.Nil(err)
+	suite.Equal(`"Weather Wrapper" <no-reply@weather-wrapper.local>`, parsed.Header.Get("From"))
+	suite.Equal("<test@test.com>", parsed.Header.Get("To"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	suite.Nil(err)
+	suite.Equal("Réinitialiser", subject)
+	suite.True(strings.HasPrefix(parsed.Header.Get("Content-Type"), "multipart/alternative"))
+	suite.Contains(string(message), "text body")
+	suite.Contains(string(message), "<p>html body</p>")
+}
+
+func TestTemplatesSuite(t *testing.T) {
+	suite.Run(t, &TemplatesSuite{})
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type AuthSuite struct {
+	suite.Suite
+	apiKeys *fakeAPIKeys
+	users   *fakeAccounts
+	app     *fiber.App
+}
+
+// caller is what the protected handler sees of the request.
+type caller struct {
+	UID      string `json:"uid"`
+	APIKeyID string `json:"apiKeyId"`
+	Plan     string `json:"plan"`
+}
+
+func (suite *AuthSuite) SetupTest() {
+	suite.apiKeys = &fakeAPIKeys{keys: map[string]entities.APIKey{}}
+	suite.users = &fakeAccounts{accounts: map[string]fakeAccount{
+		"owner": {claims: map[string]interface{}{middlewares.ClaimPlan: "pro"}},
+	}}
+
+	authMiddleware := middlewares.NewAuthMiddleware(suite.users, services.NewAPIKeyService(suite.apiKeys, suite.users, zap.NewNop()), zap.NewNop())
+
+	suite.app = fiber.New()
+	suite.app.Get("/weather", authMiddleware.Authenticate, authMiddleware.RequireScope(entities.ScopeWeatherRead), func(ctx *fiber.Ctx) error {
+		return ctx.JSON(caller{
+			UID:      middlewares.GetUID(ctx),
+			APIKeyID: middlewares.GetAPIKeyID(ctx),
+			Plan:     middlewares.GetPlan(ctx),
+		})
+	})
+}
+
+// addKey stores a key of ownerUID with scopes and returns its secret.
+func (suite *AuthSuite) addKey(ownerUID string, scopes ...string) (string, string) {
+	key, prefix, keyHash, err := utils.GenerateAPIKey()
+	suite.Require().NoError(err)
+
+	id := prefix + "-id"
+	suite.apiKeys.put(entities.APIKey{ID: id, OwnerUID: ownerUID, Prefix: prefix, KeyHash: keyHash, Scopes: scopes})
+
+	return id, key
+}
+
+// get calls GET /weather with headers and returns the status and, on
+// success, the caller the handler saw.
+func (suite *AuthSuite) get(headers map[string]string) (int, caller) {
+	req := httptest.NewRequest(fiber.MethodGet, "/weather", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	var seen caller
+	if res.StatusCode == fiber.StatusOK {
+		body, err := io.ReadAll(res.Body)
+		suite.Require().NoError(err)
+		suite.Require().NoError(json.Unmarshal(body, &seen))
+	}
+
+	return res.StatusCode, seen
+}
+
+func (suite *AuthSuite) TestValidKeysAuthenticateAsTheirOwner() {
+	id, key := suite.addKey("owner", entities.ScopeWeatherRead)
+
+	status, seen := suite.get(map[string]string{middlewares.HeaderAPIKey: key})
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal(caller{UID: "owner", APIKeyID: id, Plan: "pro"}, seen)
+	suite.NotNil(suite.apiKeys.get(id).LastUsedAt)
+}
+
+func (suite *AuthSuite) TestKeysFollowThePlanOfTheirOwner() {
+	_, key := suite.addKey("owner", entities.ScopeWeatherRead)
+
+	suite.users.setClaims("owner", map[string]interface{}{})
+
+	status, seen := suite.get(map[string]string{middlewares.HeaderAPIKey: key})
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal("free", seen.Plan)
+}
+
+func (suite *AuthSuite) TestUnknownRevokedAndExpiredKeysAreRejected() {
+	revokedID, revoked := suite.addKey("owner", entities.ScopeWeatherRead)
+	expiredID, expired := suite.addKey("owner", entities.ScopeWeatherRead)
+
+	now := time.Now()
+	suite.apiKeys.update(revokedID, func(apiKey *entities.APIKey) { apiKey.RevokedAt = &now })
+	suite.apiKeys.update(expiredID, func(apiKey *entities.APIKey) { apiKey.ExpiresAt = &now })
+
+	for _, key := range []string{"ww_unknown", revoked, expired} {
+		status, _ := suite.get(map[string]string{middlewares.HeaderAPIKey: key})
+		suite.Equal(fiber.StatusUnauthorized, status, key)
+	}
+}
+
+func (suite *AuthSuite) TestKeysOfDeletedAndDisabledOwnersAreRejected() {
+	_, deleted := suite.addKey("deleted", entities.ScopeWeatherRead)
+	_, disabled := suite.addKey("disabled", entities.ScopeWeatherRead)
+	suite.users.accounts["disabled"] = fakeAccount{disabled: true}
+
+	for _, key := range []string{deleted, disabled} {
+		status, _ := suite.get(map[string]string{middlewares.HeaderAPIKey: key})
+		suite.Equal(fiber.StatusUnauthorized, status, key)
+	}
+}
+
+func (suite *AuthSuite) TestKeysWithoutTheScopeAreForbidden() {
+	_, key := suite.addKey("owner", entities.ScopeAirRead, entities.ScopeGeocodeRead)
+
+	status, _ := suite.get(map[string]string{middlewares.HeaderAPIKey: key})
+	suite.Equal(fiber.StatusForbidden, status)
+}
+
+func (suite *AuthSuite) TestIDTokensAreNotLimitedByScopes() {
+	status, seen := suite.get(map[string]string{fiber.HeaderAuthorization: "Bearer owner-token"})
+	suite.Equal(fiber.StatusOK, status)
+	suite.Equal(caller{UID: "owner", Plan: "free"}, seen)
+
+	status, _ = suite.get(map[string]string{fiber.HeaderAuthorization: "Bearer forged"})
+	suite.Equal(fiber.StatusUnauthorized, status)
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
+
+// fakeAPIKeys keeps API keys in memory, by id.
+type fakeAPIKeys struct {
+	repository.APIKeyRepository
+	mu   sync.Mutex
+	keys map[string]entities.APIKey
+}
+
+func (fak *fakeAPIKeys) put(apiKey entities.APIKey) {
+	fak.mu.Lock()
+	defer fak.mu.Unlock()
+
+	fak.keys[apiKey.ID] = apiKey
+}
+
+func (fak *fakeAPIKeys) get(id string) entities.APIKey {
+	fak.mu.Lock()
+	defer fak.mu.Unlock()
+
+	return fak.keys[id]
+}
+
+func (fak *fakeAPIKeys) update(id string, change func(apiKey *entities.APIKey)) {
+	fak.mu.Lock()
+	defer fak.mu.Unlock()
+
+	apiKey := fak.keys[id]
+	change(&apiKey)
+	fak.keys[id] = apiKey
+}
+
+func (fak *fakeAPIKeys) GetAPIKeyByHash(_ context.Context, keyHash string) (*entities.APIKey, error) {
+	fak.mu.Lock()
+	defer fak.mu.Unlock()
+
+	for _, apiKey := range fak.keys {
+		if apiKey.KeyHash == keyHash {
+			return &apiKey, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (fak *fakeAPIKeys) TouchAPIKey(_ context.Context, id string, usedAt time.Time) error {
+	fak.update(id, func(apiKey *entities.APIKey) { apiKey.LastUsedAt = &usedAt })
+	return nil
+}
+
+type fakeAccount struct {
+	disabled bool
+	claims   map[string]interface{}
+}
+
+// fakeAccounts is an identity provider whose ID tokens are "<uid>-token".
+type fakeAccounts struct {
+	identity.Provider
+	mu       sync.Mutex
+	accounts map[string]fakeAccount
+}
+
+func (fa *fakeAccounts) setClaims(uid string, claims map[string]interface{}) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	account := fa.accounts[uid]
+	account.claims = claims
+	fa.accounts[uid] = account
+}
+
+func (fa *fakeAccounts) GetActiveClaims(_ context.Context, uid string) (map[string]interface{}, error) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	account, ok := fa.accounts[uid]
+	if !ok {
+		return nil, identity.ErrUserNotFound
+	}
+
+	if account.disabled {
+		return nil, identity.ErrUserDisabled
+	}
+
+	return account.claims, nil
+}
+
+func (fa *fakeAccounts) VerifyIDToken(_ context.Context, idToken string) (*identity.Token, error) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	for uid := range fa.accounts {
+		if idToken == uid+"-token" {
+			return &identity.Token{UID: uid, Claims: map[string]interface{}{}}, nil
+		}
+	}
+
+	return nil, identity.ErrInvalidToken
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"testing"
+)
+
+type AuthorizationSuite struct {
+	suite.Suite
+}
+
+// request calls PUT /users/:uid on behalf of callerUID with roles, as
+// AuthMiddleware.VerifyToken would leave them, and returns the status.
+func (suite *AuthorizationSuite) request(callerUID, targetUID string, roles ...string) int {
+	app := fiber.New()
+
+	rawRoles := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		rawRoles = append(rawRoles, role)
+	}
+
+	app.Put("/users/:uid", func(ctx *fiber.Ctx) error {
+		if callerUID != "" {
+			ctx.Locals(middlewares.LocalsUID, callerUID)
+			ctx.Locals(middlewares.LocalsClaims, map[string]interface{}{middlewares.ClaimRoles: rawRoles})
+		}
+
+		return ctx.Next()
+	}, middlewares.NewAuthorizationMiddleware(zap.NewNop()).RequireOwnerOrAdmin, func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusNoContent)
+	})
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodPut, "/users/"+targetUID, nil))
+	suite.Require().NoError(err)
+
+	return res.StatusCode
+}
+
+func (suite *AuthorizationSuite) TestOwnerIsAllowed() {
+	suite.Equal(fiber.StatusNoContent, suite.request("owner", "owner", entities.RoleReader))
+}
+
+func (suite *AuthorizationSuite) TestAdminIsAllowed() {
+	suite.Equal(fiber.StatusNoContent, suite.request("admin", "owner", entities.RoleAdmin))
+	suite.Equal(fiber.StatusNoContent, suite.request("admin", "admin", entities.RoleAdmin))
+}
+
+func (suite *AuthorizationSuite) TestOtherUserIsDenied() {
+	suite.Equal(fiber.StatusForbidden, suite.request("other", "owner", entities.RoleReader))
+	suite.Equal(fiber.StatusForbidden, suite.request("other", "owner"))
+}
+
+func (suite *AuthorizationSuite) TestAnonymousCallerIsDenied() {
+	suite.Equal(fiber.StatusUnauthorized, suite.request("", "owner"))
+}
+
+func TestAuthorizationSuite(t *testing.T) {
+	suite.Run(t, &AuthorizationSuite{})
+}
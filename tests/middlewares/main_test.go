@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain sets the configuration the middlewares read before any test
+// loads it, since it is loaded once per test binary.
+func TestMain(m *testing.M) {
+	env := map[string]string{
+		config.RateLimitEnabled: "true",
+		config.RateLimitWindow:  "60",
+		config.RateLimitRules:   "weather:free=2,weather:pro=3",
+		config.DefaultPlan:      "free",
+		config.UsageQuotas:      "free=2",
+	}
+
+	for key, value := range env {
+		if err := os.Setenv(key, value); err != nil {
+			log.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	os.Exit(m.Run())
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type RateLimitSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+	app   *fiber.App
+}
+
+func (suite *RateLimitSuite) SetupTest() {
+	var err error
+	// miniredis runs the sliding window script itself, so the tests cover
+	// the Lua the limiter ships.
+	suite.redis, err = miniredis.Run()
+	suite.Require().NoError(err)
+
+	suite.app = newLimitedApp(redis.NewClient(&redis.Options{Addr: suite.redis.Addr()}))
+}
+
+func (suite *RateLimitSuite) TearDownTest() {
+	suite.redis.Close()
+}
+
+// newLimitedApp serves /weather and /geocode behind the limiter of their
+// group, authenticating callers from the X-Uid and X-Plan headers.
+func newLimitedApp(redisClient *redis.Client) *fiber.App {
+	app := fiber.New()
+	rateLimitMiddleware := middlewares.NewRateLimitMiddleware(redisClient, zap.NewNop())
+
+	authenticate := func(ctx *fiber.Ctx) error {
+		if uid := ctx.Get("X-Uid"); uid != "" {
+			ctx.Locals(middlewares.LocalsUID, uid)
+		}
+
+		if plan := ctx.Get("X-Plan"); plan != "" {
+			ctx.Locals(middlewares.LocalsClaims, map[string]interface{}{middlewares.ClaimPlan: plan})
+		}
+
+		return ctx.Next()
+	}
+
+	ok := func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	}
+
+	app.Get("/weather", authenticate, rateLimitMiddleware.Limit("weather"), ok)
+	app.Get("/geocode", authenticate, rateLimitMiddleware.Limit("geocode"), ok)
+
+	return app
+}
+
+func (suite *RateLimitSuite) request(path, uid, plan string) *http.Response {
+	req := httptest.NewRequest(fiber.MethodGet, path, nil)
+	req.Header.Set("X-Uid", uid)
+	req.Header.Set("X-Plan", plan)
+
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	return res
+}
+
+func (suite *RateLimitSuite) TestRejectsRequestsOverTheLimit() {
+	for remaining := 1; remaining >= 0; remaining-- {
+		res := suite.request("/weather", "alice", "")
+		suite.Equal(fiber.StatusOK, res.StatusCode)
+		suite.Equal("2", res.Header.Get(middlewares.HeaderRateLimitLimit))
+		suite.Equal(strconv.Itoa(remaining), res.Header.Get(middlewares.HeaderRateLimitRemaining))
+	}
+
+	res := suite.request("/weather", "alice", "")
+	suite.Equal(fiber.StatusTooManyRequests, res.StatusCode)
+	suite.Equal("0", res.Header.Get(middlewares.HeaderRateLimitRemaining))
+	suite.Equal("60", res.Header.Get(fiber.HeaderRetryAfter))
+	suite.Equal("60", res.Header.Get(middlewares.HeaderRateLimitReset))
+}
+
+func (suite *RateLimitSuite) TestCallersHaveTheirOwnWindows() {
+	suite.request("/weather", "alice", "")
+	suite.request("/weather", "alice", "")
+
+	suite.Equal(fiber.StatusOK, suite.request("/weather", "bob", "").StatusCode)
+	suite.Equal(fiber.StatusTooManyRequests, suite.request("/weather", "alice", "").StatusCode)
+	suite.Len(suite.redis.Keys(), 2)
+}
+
+func (suite *RateLimitSuite) TestPlansHaveTheirOwnLimits() {
+	suite.Equal("3", suite.request("/weather", "alice", "pro").Header.Get(middlewares.HeaderRateLimitLimit))
+
+	// Plans without a limit in the group get the one of the default plan.
+	suite.Equal("2", suite.request("/weather", "bob", "enterprise").Header.Get(middlewares.HeaderRateLimitLimit))
+}
+
+func (suite *RateLimitSuite) TestGroupsWithoutLimitsAreNotLimited() {
+	for i := 0; i < 3; i++ {
+		res := suite.request("/geocode", "alice", "")
+		suite.Equal(fiber.StatusOK, res.StatusCode)
+		suite.Empty(res.Header.Get(middlewares.HeaderRateLimitLimit))
+	}
+
+	suite.Empty(suite.redis.Keys())
+}
+
+func (suite *RateLimitSuite) TestAllowsRequestsWhileRedisIsDown() {
+	suite.redis.Close()
+
+	for i := 0; i < 3; i++ {
+		res := suite.request("/weather", "alice", "")
+		suite.Equal(fiber.StatusOK, res.StatusCode)
+		suite.Empty(res.Header.Get(middlewares.HeaderRateLimitLimit))
+	}
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
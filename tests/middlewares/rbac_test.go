@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"testing"
+)
+
+type RBACSuite struct {
+	suite.Suite
+	app *fiber.App
+}
+
+func (suite *RBACSuite) SetupTest() {
+	rbacMiddleware := middlewares.NewRBACMiddleware(middlewares.RolePolicy{
+		"PUT /api/v1/users/:uid":          {},
+		"PUT /api/v1/users/:uid/roles":    {entities.RoleAdmin},
+		"DELETE /api/v1/users/:uid/roles": {entities.RoleAdmin},
+	}, zap.NewNop())
+
+	// authenticate leaves the roles of the X-Roles header as
+	// AuthMiddleware.VerifyToken would.
+	authenticate := func(ctx *fiber.Ctx) error {
+		var rawRoles []interface{}
+		if role := ctx.Get("X-Roles"); role != "" {
+			rawRoles = append(rawRoles, role)
+		}
+
+		ctx.Locals(middlewares.LocalsUID, "caller")
+		ctx.Locals(middlewares.LocalsClaims, map[string]interface{}{middlewares.ClaimRoles: rawRoles})
+
+		return ctx.Next()
+	}
+
+	ok := func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusNoContent)
+	}
+
+	suite.app = fiber.New()
+	users := suite.app.Group("/api/v1/users")
+	users.Put("/:uid", authenticate, rbacMiddleware.Authorize, ok)
+	users.Put("/:uid/roles", authenticate, rbacMiddleware.Authorize, ok)
+	users.Delete("/:uid/roles", authenticate, rbacMiddleware.Authorize, ok)
+	users.Post("/:uid/undeclared", authenticate, rbacMiddleware.Authorize, ok)
+}
+
+func (suite *RBACSuite) request(method, path, role string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Roles", role)
+
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	return res.StatusCode
+}
+
+func (suite *RBACSuite) TestUndeclaredRoutesAreForbidden() {
+	suite.Equal(fiber.StatusForbidden, suite.request(fiber.MethodPost, "/api/v1/users/owner/undeclared", entities.RoleAdmin))
+}
+
+func (suite *RBACSuite) TestEmptyRoleListsAllowAnyCaller() {
+	suite.Equal(fiber.StatusNoContent, suite.request(fiber.MethodPut, "/api/v1/users/owner", ""))
+	suite.Equal(fiber.StatusNoContent, suite.request(fiber.MethodPut, "/api/v1/users/owner", entities.RoleReader))
+}
+
+func (suite *RBACSuite) TestRolesAreAdminOnly() {
+	for _, method := range []string{fiber.MethodPut, fiber.MethodDelete} {
+		suite.Equal(fiber.StatusForbidden, suite.request(method, "/api/v1/users/owner/roles", entities.RoleReader), method)
+		suite.Equal(fiber.StatusForbidden, suite.request(method, "/api/v1/users/owner/roles", ""), method)
+		suite.Equal(fiber.StatusNoContent, suite.request(method, "/api/v1/users/owner/roles", entities.RoleAdmin), method)
+	}
+}
+
+func TestRBACSuite(t *testing.T) {
+	suite.Run(t, new(RBACSuite))
+}
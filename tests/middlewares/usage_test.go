@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/middlewares"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type UsageSuite struct {
+	suite.Suite
+	usage *fakeUsage
+	app   *fiber.App
+}
+
+func (suite *UsageSuite) SetupTest() {
+	suite.usage = &fakeUsage{requests: map[string]int64{}}
+	usageMiddleware := middlewares.NewUsageMiddleware(services.NewUsageService(suite.usage, zap.NewNop()), zap.NewNop())
+
+	// authenticate leaves the caller of the X-Uid, X-Key and X-Plan headers
+	// as AuthMiddleware.Authenticate would.
+	authenticate := func(ctx *fiber.Ctx) error {
+		ctx.Locals(middlewares.LocalsUID, ctx.Get("X-Uid"))
+		ctx.Locals(middlewares.LocalsAPIKeyID, ctx.Get("X-Key"))
+		ctx.Locals(middlewares.LocalsPlan, ctx.Get("X-Plan"))
+
+		return ctx.Next()
+	}
+
+	suite.app = fiber.New()
+	suite.app.Get("/weather", authenticate, usageMiddleware.Meter, func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+}
+
+func (suite *UsageSuite) request(headers map[string]string) int {
+	req := httptest.NewRequest(fiber.MethodGet, "/weather", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	return res.StatusCode
+}
+
+func (suite *UsageSuite) TestRequestsOverTheQuotaAreRejected() {
+	for i := 0; i < 2; i++ {
+		suite.Equal(fiber.StatusOK, suite.request(map[string]string{"X-Uid": "alice"}))
+	}
+
+	suite.Equal(fiber.StatusTooManyRequests, suite.request(map[string]string{"X-Uid": "alice"}))
+	suite.Equal(fiber.StatusOK, suite.request(map[string]string{"X-Uid": "bob"}))
+}
+
+func (suite *UsageSuite) TestAPIKeysCountAgainstTheirOwner() {
+	suite.Equal(fiber.StatusOK, suite.request(map[string]string{"X-Uid": "alice"}))
+	suite.Equal(fiber.StatusOK, suite.request(map[string]string{"X-Uid": "alice", "X-Key": "first"}))
+	suite.Equal(fiber.StatusTooManyRequests, suite.request(map[string]string{"X-Uid": "alice", "X-Key": "second"}))
+}
+
+func (suite *UsageSuite) TestPlansWithoutAQuotaAreNotLimited() {
+	for i := 0; i < 3; i++ {
+		suite.Equal(fiber.StatusOK, suite.request(map[string]string{"X-Uid": "alice", "X-Plan": "pro"}))
+	}
+
+	suite.Empty(suite.usage.requests)
+}
+
+func TestUsageSuite(t *testing.T) {
+	suite.Run(t, new(UsageSuite))
+}
+
+// fakeUsage counts the monthly requests of every subject in memory.
+type fakeUsage struct {
+	repository.UsageRepository
+	mu       sync.Mutex
+	requests map[string]int64
+}
+
+func (fu *fakeUsage) IncrementMonthlyRequests(_ context.Context, subject, month string) (int64, error) {
+	fu.mu.Lock()
+	defer fu.mu.Unlock()
+
+	fu.requests[subject+"_"+month]++
+	return fu.requests[subject+"_"+month], nil
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type AirPollutionRepositorySuite struct {
+	suite.Suite
+	ctx              context.Context
+	cache            *countingCache
+	airPollutionRepo repository.AirPollutionRepository
+	location         geo.Location
+	// dayStart is the start of a UTC day well in the past.
+	dayStart int64
+}
+
+func (suite *AirPollutionRepositorySuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.cache = &countingCache{Cache: cache.NewLRUCache(100)}
+	suite.airPollutionRepo = repository.NewAirPollutionRepository(suite.cache, config.CacheConfig{
+		TTLPolicies: map[string]config.TTLPolicy{
+			"air_pollution_history": {Policy: config.TTLImmutablePast, Soft: 0, Hard: time.Hour, Retention: 30 * 24 * time.Hour},
+		},
+	}, zap.NewNop())
+	suite.location = geo.Snap(config.SnapRule{Scheme: config.SnapGrid, Step: 0.05}, 12.9716, 77.5946)
+	suite.dayStart = time.Now().Add(-72 * time.Hour).Truncate(24 * time.Hour).Unix()
+}
+
+// hourly returns a datapoint for every hour from start to end.
+func hourly(start, end int64) []entities.AirPollutionDatapoint {
+	var datapoints []entities.AirPollutionDatapoint
+	for dt := start; dt <= end; dt += 3600 {
+		datapoints = append(datapoints, entities.AirPollutionDatapoint{Dt: int(dt)})
+	}
+
+	return datapoints
+}
+
+func (suite *AirPollutionRepositorySuite) TestOverlappingRangesOnlyMissTheRest() {
+	start, end := suite.dayStart+6*3600, suite.dayStart+12*3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end, hourly(start, end)))
+
+	window, err := suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start+3600, end-3600)
+	suite.NoError(err)
+	suite.Empty(window.Missing)
+	suite.Len(window.Datapoints, 5)
+	suite.False(window.FetchedAt.IsZero())
+
+	window, err = suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, suite.dayStart, suite.dayStart+18*3600)
+	suite.NoError(err)
+	suite.Equal([]repository.TimeRange{
+		{Start: suite.dayStart, End: start - 3600},
+		{Start: end + 3600, End: suite.dayStart + 18*3600},
+	}, window.Missing)
+	suite.Len(window.Datapoints, 7)
+	suite.Equal(int(start), window.Datapoints[0].Dt)
+}
+
+func (suite *AirPollutionRepositorySuite) TestGapsWithinAnHourAreNotMissing() {
+	start, end := suite.dayStart+6*3600, suite.dayStart+12*3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end-1, hourly(start, end-1)))
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, end+1, end+3*3600, hourly(end+3600, end+3*3600)))
+
+	window, err := suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start, end+3*3600)
+	suite.NoError(err)
+	suite.Equal([]repository.TimeRange{{Start: end, End: end}}, window.Missing)
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, end, end, hourly(end, end)))
+
+	window, err = suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start-1800, end+3*3600+1800)
+	suite.NoError(err)
+	suite.Empty(window.Missing)
+	suite.Len(window.Datapoints, 10)
+}
+
+func (suite *AirPollutionRepositorySuite) TestRangesSpanDays() {
+	start, end := suite.dayStart+20*3600, suite.dayStart+28*3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end, hourly(start, end)))
+
+	window, err := suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start, end)
+	suite.NoError(err)
+	suite.Empty(window.Missing)
+	suite.Len(window.Datapoints, 9)
+
+	for i := 1; i < len(window.Datapoints); i++ {
+		suite.Less(window.Datapoints[i-1].Dt, window.Datapoints[i].Dt)
+	}
+
+	keys, err := suite.cache.Scan(suite.ctx, "weather-wrapper:v1:historical_air_pollution_*")
+	suite.NoError(err)
+	suite.Len(keys, 2)
+}
+
+func (suite *AirPollutionRepositorySuite) TestDaysAreReadAndWrittenAtOnce() {
+	start, end := suite.dayStart-48*3600, suite.dayStart+3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end, hourly(start, end)))
+	suite.Equal(1, suite.cache.reads)
+	suite.Equal(1, suite.cache.writes)
+
+	_, err := suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start, end)
+	suite.NoError(err)
+	suite.Equal(2, suite.cache.reads)
+}
+
+func (suite *AirPollutionRepositorySuite) TestPastRangesAreImmutable() {
+	start, end := suite.dayStart, suite.dayStart+3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end, hourly(start, end)))
+
+	keys, err := suite.cache.Scan(suite.ctx, "weather-wrapper:v1:historical_air_pollution_*")
+	suite.NoError(err)
+	suite.Require().Len(keys, 1)
+
+	ttl, err := suite.cache.TTL(suite.ctx, keys[0])
+	suite.NoError(err)
+	suite.Greater(ttl, 29*24*time.Hour)
+}
+
+func (suite *AirPollutionRepositorySuite) TestRecentRangesExpire() {
+	end := time.Now().Truncate(time.Hour).Unix()
+	start := end - 3600
+
+	suite.NoError(suite.airPollutionRepo.AddHistory(suite.ctx, suite.location, start, end, hourly(start, end)))
+
+	// The soft TTL of the policy is 0, so the range has to be fetched again
+	// while its datapoints are kept until then.
+	window, err := suite.airPollutionRepo.GetHistory(suite.ctx, suite.location, start, end)
+	suite.NoError(err)
+	suite.Equal([]repository.TimeRange{{Start: start, End: end}}, window.Missing)
+	suite.NotEmpty(window.Datapoints)
+}
+
+func TestAirPollutionRepositorySuite(t *testing.T) {
+	suite.Run(t, new(AirPollutionRepositorySuite))
+}
+
+// countingCache counts the reads and writes of values it is given.
+type countingCache struct {
+	cache.Cache
+	reads, writes int
+}
+
+func (cc *countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	cc.reads++
+	return cc.Cache.Get(ctx, key)
+}
+
+func (cc *countingCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	cc.reads++
+	return cc.Cache.MGet(ctx, keys...)
+}
+
+func (cc *countingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cc.writes++
+	return cc.Cache.Set(ctx, key, value, ttl)
+}
+
+func (cc *countingCache) MSet(ctx context.Context, items ...cache.Item) error {
+	cc.writes++
+	return cc.Cache.MSet(ctx, items...)
+}
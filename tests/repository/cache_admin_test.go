@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type CacheAdminRepositorySuite struct {
+	suite.Suite
+	ctx            context.Context
+	cache          cache.Cache
+	cacheAdminRepo repository.CacheAdminRepository
+	bengaluru      geo.Location
+	london         geo.Location
+}
+
+func (suite *CacheAdminRepositorySuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.cache = cache.NewLRUCache(100)
+	suite.cacheAdminRepo = repository.NewCacheAdminRepository(suite.cache, zap.NewNop())
+
+	rule := config.SnapRule{Scheme: config.SnapGeohash, Precision: 6}
+	suite.bengaluru = geo.Snap(rule, 12.9716, 77.5946)
+	suite.london = geo.Snap(rule, 51.5074, -0.1278)
+
+	weatherRepo := repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop())
+	suite.Require().NoError(weatherRepo.SetCurrentWeather(suite.ctx, suite.bengaluru, &entities.CurrentWeather{Name: "Bengaluru"}))
+	suite.Require().NoError(weatherRepo.SetCurrentWeather(suite.ctx, suite.london, &entities.CurrentWeather{Name: "London"}))
+	suite.Require().NoError(weatherRepo.SetFiveDayForecast(suite.ctx, suite.bengaluru, &entities.Forecast{}))
+
+	fallbackRepo := repository.NewFallbackRepository(suite.cache, time.Hour, zap.NewNop())
+	params := upstream.LatLonParams(float32(suite.bengaluru.Lat), float32(suite.bengaluru.Lon))
+	suite.Require().NoError(fallbackRepo.SetLastKnown(suite.ctx, string(upstream.CurrentWeather), params, &entities.CurrentWeather{}))
+
+	_, err := repository.NewLockRepository(suite.cache, zap.NewNop()).Acquire(suite.ctx, "current_weather", time.Minute)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.cache.Set(suite.ctx, "other_app:session", []byte("keep"), 0))
+}
+
+func (suite *CacheAdminRepositorySuite) TestListEntries() {
+	entries, truncated, err := suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{}, 10)
+	suite.NoError(err)
+	suite.False(truncated)
+	suite.Len(entries, 4)
+
+	for _, entry := range entries {
+		suite.NotEmpty(entry.Resource)
+		suite.NotNil(entry.FetchedAt)
+		suite.Greater(entry.TTL, int64(0))
+	}
+
+	entries, truncated, err = suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{}, 2)
+	suite.NoError(err)
+	suite.True(truncated)
+	suite.Len(entries, 2)
+}
+
+func (suite *CacheAdminRepositorySuite) TestListEntriesByResourceAndBox() {
+	box := &geo.Box{MinLat: 12, MinLon: 77, MaxLat: 13, MaxLon: 78}
+
+	entries, _, err := suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{Box: box}, 10)
+	suite.NoError(err)
+	suite.Len(entries, 3)
+
+	entries, _, err = suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{Resource: string(upstream.CurrentWeather), Box: box}, 10)
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Equal(suite.bengaluru.Cell, entries[0].Cell)
+	suite.InDelta(suite.bengaluru.Lat, *entries[0].Lat, 1e-9)
+}
+
+func (suite *CacheAdminRepositorySuite) TestUnsnappedAndHistoryEntriesAreLocated() {
+	paris := geo.Snap(config.SnapRule{}, 48.8566, 2.3522)
+	weatherRepo := repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop())
+	suite.Require().NoError(weatherRepo.SetCurrentWeather(suite.ctx, paris, &entities.CurrentWeather{Name: "Paris"}))
+
+	airPollutionRepo := repository.NewAirPollutionRepository(suite.cache, config.CacheConfig{}, zap.NewNop())
+	suite.Require().NoError(airPollutionRepo.AddHistory(suite.ctx, paris, 1704067200, 1704070800, []entities.AirPollutionDatapoint{{Dt: 1704067200}}))
+
+	box := &geo.Box{MinLat: 48, MinLon: 2, MaxLat: 49, MaxLon: 3}
+
+	entries, _, err := suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{Box: box}, 10)
+	suite.NoError(err)
+	suite.Require().Len(entries, 2)
+
+	for _, entry := range entries {
+		suite.Equal(paris.Cell, entry.Cell)
+		suite.Require().NotNil(entry.Lat)
+		suite.InDelta(48.8566, *entry.Lat, 1e-5)
+		suite.InDelta(2.3522, *entry.Lon, 1e-5)
+	}
+}
+
+func (suite *CacheAdminRepositorySuite) TestGetEntryStaysInNamespace() {
+	entry, err := suite.cacheAdminRepo.GetEntry(suite.ctx, "other_app:session")
+	suite.NoError(err)
+	suite.Nil(entry)
+
+	entry, err = suite.cacheAdminRepo.GetEntry(suite.ctx, "weather-wrapper:v1:lock_current_weather")
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.Empty(entry.Resource)
+	suite.Nil(entry.FetchedAt)
+}
+
+func (suite *CacheAdminRepositorySuite) TestPurgeByLocation() {
+	point := &geo.Box{MinLat: 51.5074, MinLon: -0.1278, MaxLat: 51.5074, MaxLon: -0.1278}
+
+	deleted, err := suite.cacheAdminRepo.Purge(suite.ctx, repository.CacheFilter{Box: point})
+	suite.NoError(err)
+	suite.Equal(1, deleted)
+
+	entries, _, err := suite.cacheAdminRepo.ListEntries(suite.ctx, repository.CacheFilter{Resource: string(upstream.CurrentWeather)}, 10)
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Equal(suite.bengaluru.Cell, entries[0].Cell)
+}
+
+func (suite *CacheAdminRepositorySuite) TestFlushKeepsOtherApplicationsAndQuotas() {
+	suite.Require().NoError(suite.cache.Set(suite.ctx, "weather-wrapper:v1:rate_limit_weather_ip:127.0.0.1", []byte("1"), time.Minute))
+	suite.Require().NoError(suite.cache.Set(suite.ctx, "weather-wrapper:v1:usage_quota_uid_2024-01", []byte("1"), time.Hour))
+
+	deleted, err := suite.cacheAdminRepo.Flush(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(6, deleted)
+
+	keys, err := suite.cache.Scan(suite.ctx, "*")
+	suite.NoError(err)
+	suite.ElementsMatch([]string{"other_app:session", "weather-wrapper:v1:usage_quota_uid_2024-01"}, keys)
+}
+
+func TestCacheAdminRepositorySuite(t *testing.T) {
+	suite.Run(t, new(CacheAdminRepositorySuite))
+}
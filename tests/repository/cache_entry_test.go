@@ -0,0 +1,24 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type CacheEntrySuite struct {
+	suite.Suite
+}
+
+func (suite *CacheEntrySuite) TestIsStale() {
+	fresh := &repository.CacheEntry[string]{FetchedAt: time.Now(), StaleAt: time.Now().Add(time.Minute)}
+	suite.False(fresh.IsStale())
+
+	stale := &repository.CacheEntry[string]{FetchedAt: time.Now().Add(-time.Hour), StaleAt: time.Now().Add(-time.Minute)}
+	suite.True(stale.IsStale())
+}
+
+func TestCacheEntrySuite(t *testing.T) {
+	suite.Run(t, new(CacheEntrySuite))
+}
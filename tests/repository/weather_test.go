@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type WeatherRepositorySuite struct {
+	suite.Suite
+	ctx   context.Context
+	cache cache.Cache
+	rule  config.SnapRule
+}
+
+func (suite *WeatherRepositorySuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.cache = cache.NewLRUCache(100)
+	suite.rule = config.SnapRule{Scheme: config.SnapGeohash, Precision: 6}
+}
+
+func (suite *WeatherRepositorySuite) TestNearbyLocationsShareEntries() {
+	weatherRepo := repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop())
+
+	err := weatherRepo.SetCurrentWeather(suite.ctx, geo.Snap(suite.rule, 12.971599, 77.594566), &entities.CurrentWeather{Name: "Bengaluru"})
+	suite.NoError(err)
+
+	entry, err := weatherRepo.GetCurrentWeather(suite.ctx, geo.Snap(suite.rule, 12.9716, 77.5946))
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.Equal("Bengaluru", entry.Value.Name)
+	suite.False(entry.IsStale())
+}
+
+func (suite *WeatherRepositorySuite) TestReadsLegacyKeys() {
+	// Legacy keys hold the bare weather, without its fetch time.
+	legacyJSON, err := json.Marshal(entities.CurrentWeather{Name: "Legacy"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.cache.Set(suite.ctx, "current_weather_12.971600_77.594597", legacyJSON, 40*time.Minute))
+
+	location := geo.Snap(suite.rule, 12.9716, 77.5946)
+
+	entry, err := repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop()).GetCurrentWeather(suite.ctx, location)
+	suite.NoError(err)
+	suite.Nil(entry)
+
+	entry, err = repository.NewWeatherRepository(suite.cache, config.CacheConfig{ReadLegacyKeys: true}, zap.NewNop()).GetCurrentWeather(suite.ctx, location)
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.Equal("Legacy", entry.Value.Name)
+
+	// It is served stale, as fetched a hard TTL of an hour before it
+	// expires.
+	suite.True(entry.IsStale())
+	suite.WithinDuration(time.Now().Add(-20*time.Minute), entry.FetchedAt, time.Second)
+
+	// The legacy entry is copied to the cell for the rest of its TTL.
+	ttl, err := suite.cache.TTL(suite.ctx, "weather-wrapper:v1:current_weather_"+location.Cell)
+	suite.NoError(err)
+	suite.InDelta(40*time.Minute, ttl, float64(time.Second))
+
+	entry, err = repository.NewWeatherRepository(suite.cache, config.CacheConfig{}, zap.NewNop()).GetCurrentWeather(suite.ctx, location)
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.Equal("Legacy", entry.Value.Name)
+	suite.True(entry.IsStale())
+}
+
+func (suite *WeatherRepositorySuite) TestReadsLegacyCityKeys() {
+	// Cities were cached as plain strings.
+	suite.Require().NoError(suite.cache.Set(suite.ctx, "reverse_geocode_12.971600_77.594597", []byte("Bengaluru"), time.Hour))
+
+	geocodingRepo := repository.NewGeocodingRepository(suite.cache, config.CacheConfig{ReadLegacyKeys: true}, zap.NewNop())
+
+	entry, err := geocodingRepo.GetCityFromLatLon(suite.ctx, geo.Snap(suite.rule, 12.9716, 77.5946))
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.Equal("Bengaluru", entry.Value)
+	suite.True(entry.IsStale())
+}
+
+func (suite *WeatherRepositorySuite) TestForecastIsFreshUntilTheNextBoundary() {
+	weatherRepo := repository.NewWeatherRepository(suite.cache, config.CacheConfig{
+		TTLPolicies: map[string]config.TTLPolicy{
+			"weather_forecast": {Policy: config.TTLForecastBoundary, Interval: 3 * time.Hour, Hard: time.Hour},
+		},
+	}, zap.NewNop())
+	location := geo.Snap(suite.rule, 12.9716, 77.5946)
+
+	suite.NoError(weatherRepo.SetFiveDayForecast(suite.ctx, location, &entities.Forecast{}))
+
+	entry, err := weatherRepo.GetFiveDayForecast(suite.ctx, location)
+	suite.NoError(err)
+	suite.Require().NotNil(entry)
+	suite.True(entry.StaleAt.After(entry.FetchedAt))
+	suite.LessOrEqual(entry.StaleAt.Sub(entry.FetchedAt), 3*time.Hour)
+	suite.Zero(entry.StaleAt.Unix() % int64((3 * time.Hour).Seconds()))
+
+	ttl, err := suite.cache.TTL(suite.ctx, "weather-wrapper:v1:five_day_weather_"+location.Cell)
+	suite.NoError(err)
+	suite.InDelta(time.Until(entry.StaleAt.Add(time.Hour)), ttl, float64(time.Second))
+}
+
+func TestWeatherRepositorySuite(t *testing.T) {
+	suite.Run(t, new(WeatherRepositorySuite))
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/geo"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type AdviceServiceSuite struct {
+	suite.Suite
+	ctx                 context.Context
+	airPollutionService *stubAirPollutionService
+	adviceService       services.AdviceService
+}
+
+func (suite *AdviceServiceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.airPollutionService = &stubAirPollutionService{
+		current: &entities.AirPollution{
+			List: []entities.AirPollutionDatapoint{{Dt: 1700001800, Components: entities.AirPollutionComponents{PM25: 12}}},
+		},
+		forecast: &entities.AirPollution{
+			List: []entities.AirPollutionDatapoint{
+				{Dt: 1699996400, Components: entities.AirPollutionComponents{PM25: 200}},
+				{Dt: 1700000000, Components: entities.AirPollutionComponents{PM25: 5}},
+				{Dt: 1700003600, Components: entities.AirPollutionComponents{PM25: 5}},
+				{Dt: 1700007200, Components: entities.AirPollutionComponents{PM25: 40}},
+			},
+		},
+	}
+	suite.adviceService = services.NewAdviceService(suite.airPollutionService, zap.NewNop())
+}
+
+func (suite *AdviceServiceSuite) TestForecastStartsAtTheCurrentHour() {
+	advice, err := suite.adviceService.GetAdvice(suite.ctx, 12.97, 77.59, aqi.USEPA, "en")
+	suite.Require().NoError(err)
+	suite.Equal(1700001800, advice.Current.Dt)
+	suite.Equal(56, advice.Current.AQI.Value)
+
+	suite.Require().Len(advice.Outlook, 2)
+	suite.Equal(1700000000, advice.Outlook[0].Start)
+	suite.Equal(1700007200, advice.Outlook[0].End)
+	suite.Require().NotEmpty(advice.SafestWindows)
+	suite.Equal(1700000000, advice.SafestWindows[0].Start)
+}
+
+func (suite *AdviceServiceSuite) TestAdviceReportsTheLeastFreshFetch() {
+	fetchedAt := time.Now().Add(-time.Hour)
+
+	// The current air pollution marks its freshness last, after the forecast
+	// has been served from the last known good response.
+	suite.airPollutionService.mark = func(ctx context.Context, endpoint upstream.Endpoint) {
+		if endpoint == upstream.CurrentAirPollution {
+			time.Sleep(20 * time.Millisecond)
+			freshness.MarkLocation(ctx, geo.Location{Lat: 12.97, Lon: 77.59})
+			freshness.MarkCached(ctx, time.Now())
+			return
+		}
+
+		freshness.MarkLastKnownGood(ctx, fetchedAt)
+	}
+
+	ctx := freshness.NewContext(suite.ctx)
+	_, err := suite.adviceService.GetAdvice(ctx, 12.97, 77.59, aqi.USEPA, "en")
+	suite.Require().NoError(err)
+
+	state, stateFetchedAt := freshness.FromContext(ctx).State()
+	suite.Equal(freshness.LastKnownGood, state)
+	suite.True(fetchedAt.Equal(stateFetchedAt))
+	suite.Require().NotNil(freshness.FromContext(ctx).Location())
+	suite.Equal(12.97, freshness.FromContext(ctx).Location().Lat)
+}
+
+func (suite *AdviceServiceSuite) TestFailedFetchesFailTheAdvice() {
+	suite.airPollutionService.forecastErr = upstream.ErrUnavailable
+
+	_, err := suite.adviceService.GetAdvice(suite.ctx, 12.97, 77.59, aqi.USEPA, "en")
+	suite.ErrorIs(err, upstream.ErrUnavailable)
+}
+
+func (suite *AdviceServiceSuite) TestEmptyCurrentAirPollutionFailsTheAdvice() {
+	suite.airPollutionService.current = &entities.AirPollution{}
+
+	_, err := suite.adviceService.GetAdvice(suite.ctx, 12.97, 77.59, aqi.USEPA, "en")
+	suite.ErrorIs(err, aqi.ErrNoDatapoints)
+}
+
+func TestAdviceServiceSuite(t *testing.T) {
+	suite.Run(t, new(AdviceServiceSuite))
+}
+
+// stubAirPollutionService serves fixed air pollution, and no history unless
+// it is set, letting mark record
+// the freshness of every fetch on its context.
+type stubAirPollutionService struct {
+	current, forecast, history *entities.AirPollution
+	forecastErr                error
+	mark                       func(ctx context.Context, endpoint upstream.Endpoint)
+}
+
+func (sas *stubAirPollutionService) GetCurrentAirPollution(ctx context.Context, _, _ float32) (*entities.AirPollution, error) {
+	if sas.mark != nil {
+		sas.mark(ctx, upstream.CurrentAirPollution)
+	}
+
+	return sas.current, nil
+}
+
+func (sas *stubAirPollutionService) GetAirPollutionForecast(ctx context.Context, _, _ float32) (*entities.AirPollution, error) {
+	if sas.mark != nil {
+		sas.mark(ctx, upstream.AirPollutionForecast)
+	}
+
+	return sas.forecast, sas.forecastErr
+}
+
+func (sas *stubAirPollutionService) GetHistoricalAirPollution(context.Context, float32, float32, int64, int64) (*entities.AirPollution, error) {
+	if sas.history == nil {
+		return nil, upstream.ErrUnavailable
+	}
+
+	return sas.history, nil
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type AirPollutionSummarySuite struct {
+	suite.Suite
+	ctx                 context.Context
+	airPollutionService *stubAirPollutionService
+	weatherService      *stubWeatherService
+	summaryService      services.AirPollutionSummaryService
+}
+
+func (suite *AirPollutionSummarySuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.airPollutionService = &stubAirPollutionService{
+		forecast: &entities.AirPollution{
+			Coord: entities.Coord{Lat: 12.95, Lon: 77.6},
+			List:  []entities.AirPollutionDatapoint{{Dt: 1700000000, Components: entities.AirPollutionComponents{PM25: 12}}},
+		},
+	}
+	suite.weatherService = &stubWeatherService{timezone: 19800}
+	suite.summaryService = services.NewAirPollutionSummaryService(suite.airPollutionService, suite.weatherService, zap.NewNop())
+}
+
+func (suite *AirPollutionSummarySuite) TestTimezoneIsFetchedAlongsideTheForecast() {
+	// The weather is only answered once the forecast is being fetched.
+	forecastStarted := make(chan struct{})
+	suite.airPollutionService.mark = func(_ context.Context, endpoint upstream.Endpoint) {
+		if endpoint == upstream.AirPollutionForecast {
+			close(forecastStarted)
+		}
+	}
+	suite.weatherService.wait = forecastStarted
+
+	forecast, err := suite.summaryService.GetForecastSummary(suite.ctx, 12.97, 77.59, aqi.USEPA)
+	suite.Require().NoError(err)
+	suite.Equal(19800, forecast.TimezoneOffset)
+	suite.False(forecast.TimezoneEstimated)
+	suite.Len(forecast.Days, 1)
+}
+
+func (suite *AirPollutionSummarySuite) TestFallbackTimezoneIsReported() {
+	suite.weatherService.err = upstream.ErrUnavailable
+
+	forecast, err := suite.summaryService.GetForecastSummary(suite.ctx, 12.97, 77.59, aqi.USEPA)
+	suite.Require().NoError(err)
+	suite.Equal(5*3600, forecast.TimezoneOffset)
+	suite.True(forecast.TimezoneEstimated)
+}
+
+func (suite *AirPollutionSummarySuite) TestHistoryAcrossADaylightSavingTimeChangeIsEstimated() {
+	// 2024-03-30 and 2024-04-01 are on both sides of the change of
+	// Europe/London and Atlantic/Azores, one of which is at UTC at any time.
+	suite.weatherService.timezone = 0
+	suite.airPollutionService.history = &entities.AirPollution{
+		List: []entities.AirPollutionDatapoint{
+			{Dt: 1711792800, Components: entities.AirPollutionComponents{PM25: 12}},
+			{Dt: 1711965600, Components: entities.AirPollutionComponents{PM25: 12}},
+		},
+	}
+
+	history, err := suite.summaryService.GetHistorySummary(suite.ctx, 51.5, -0.12, 1711792800, 1711965600, aqi.USEPA)
+	suite.Require().NoError(err)
+	suite.Equal(0, history.TimezoneOffset)
+	suite.True(history.TimezoneEstimated)
+	suite.Len(history.Days, 2)
+}
+
+func (suite *AirPollutionSummarySuite) TestFailedFetchesFailTheSummary() {
+	suite.airPollutionService.forecastErr = upstream.ErrUnavailable
+
+	_, err := suite.summaryService.GetForecastSummary(suite.ctx, 12.97, 77.59, aqi.USEPA)
+	suite.ErrorIs(err, upstream.ErrUnavailable)
+}
+
+func TestAirPollutionSummarySuite(t *testing.T) {
+	suite.Run(t, new(AirPollutionSummarySuite))
+}
+
+// stubWeatherService reports the current weather at timezone seconds from
+// UTC, once wait is closed if it is set, or fails with err.
+type stubWeatherService struct {
+	services.WeatherService
+	timezone int
+	err      error
+	wait     chan struct{}
+}
+
+func (sws *stubWeatherService) GetCurrentWeather(ctx context.Context, _, _ float32) (*entities.CurrentWeather, error) {
+	if sws.wait != nil {
+		select {
+		case <-sws.wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return nil, errors.New("the weather was not fetched alongside the air pollution")
+		}
+	}
+
+	if sws.err != nil {
+		return nil, sws.err
+	}
+
+	return &entities.CurrentWeather{TimeZone: sws.timezone}, nil
+}
@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type AirPollutionServiceSuite struct {
+	suite.Suite
+	ctx                 context.Context
+	upstream            *fakeUpstream
+	fallbackCache       cache.Cache
+	airPollutionService services.AirPollutionService
+	// datapoints is whether upstream has datapoints for history requests.
+	datapoints bool
+	// down is whether upstream is unavailable.
+	down bool
+	// overlap makes upstream answer history requests with the hour before
+	// their start too.
+	overlap bool
+	// downFrom, when set, makes upstream unavailable for history requests
+	// starting at or after it.
+	downFrom int64
+	// dayStart is the start of a UTC day well in the past.
+	dayStart int64
+	conf     config.Config
+	// ranges are the history ranges asked upstream.
+	ranges   []repository.TimeRange
+	rangesMu sync.Mutex
+}
+
+func (suite *AirPollutionServiceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.datapoints = true
+	suite.down = false
+	suite.overlap = false
+	suite.downFrom = 0
+	suite.ranges = nil
+	suite.conf = *config.GetConfig()
+	suite.dayStart = time.Now().Add(-72 * time.Hour).Truncate(24 * time.Hour).Unix()
+
+	suite.upstream = newFakeUpstream(func(_ context.Context, endpoint upstream.Endpoint, params url.Values) (any, error) {
+		start, _ := strconv.ParseInt(params.Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(params.Get("end"), 10, 64)
+
+		suite.rangesMu.Lock()
+		suite.ranges = append(suite.ranges, repository.TimeRange{Start: start, End: end})
+		suite.rangesMu.Unlock()
+
+		if suite.down || (suite.downFrom != 0 && start >= suite.downFrom) {
+			return nil, upstream.ErrUnavailable
+		}
+
+		if !suite.datapoints {
+			return entities.AirPollution{}, nil
+		}
+
+		from := start
+		if suite.overlap {
+			from -= 3600
+		}
+
+		var datapoints []entities.AirPollutionDatapoint
+		for dt := (from + 3599) / 3600 * 3600; dt <= end; dt += 3600 {
+			datapoints = append(datapoints, entities.AirPollutionDatapoint{Dt: int(dt)})
+		}
+
+		return entities.AirPollution{List: datapoints}, nil
+	})
+
+	suite.fallbackCache = cache.NewLRUCache(1000)
+	suite.airPollutionService = suite.newService()
+}
+
+// newService returns a service with an empty cache, sharing only the last
+// known good responses with the other services of the test.
+func (suite *AirPollutionServiceSuite) newService() services.AirPollutionService {
+	conf := &suite.conf
+	sharedCache := cache.NewLRUCache(1000)
+	logger := zap.NewNop()
+
+	return services.NewAirPollutionService(
+		repository.NewAirPollutionRepository(sharedCache, conf.CacheConfig, logger),
+		repository.NewFallbackRepository(suite.fallbackCache, conf.UpstreamConfig.FallbackRetention, logger),
+		repository.NewLockRepository(sharedCache, logger),
+		suite.upstream,
+		services.NewRefreshPool(conf.CacheConfig.RefreshWorkers, logger),
+		conf,
+		logger,
+	)
+}
+
+func (suite *AirPollutionServiceSuite) TestStoredHistoryIsNotFetchedAgain() {
+	start, end := suite.dayStart, suite.dayStart+12*3600
+
+	history, err := suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, start, end)
+	suite.Require().NoError(err)
+	suite.Len(history.List, 13)
+
+	history, err = suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, start, end)
+	suite.Require().NoError(err)
+	suite.Len(history.List, 13)
+	suite.Equal(1, suite.upstream.callsTo(upstream.HistoricalAirPollution))
+}
+
+func (suite *AirPollutionServiceSuite) TestEmptyHistoryIsNotStored() {
+	start, end := suite.dayStart, suite.dayStart+12*3600
+	suite.datapoints = false
+
+	history, err := suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, start, end)
+	suite.Require().NoError(err)
+	suite.Empty(history.List)
+
+	suite.datapoints = true
+
+	history, err = suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, start, end)
+	suite.Require().NoError(err)
+	suite.Len(history.List, 13)
+	suite.Equal(2, suite.upstream.callsTo(upstream.HistoricalAirPollution))
+}
+
+func (suite *AirPollutionServiceSuite) TestLastKnownHistoryIsKeptPerDay() {
+	// Two ranges that together cover the first day, and part of the next.
+	_, err := suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+12*3600)
+	suite.Require().NoError(err)
+	_, err = suite.airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart+13*3600, suite.dayStart+30*3600)
+	suite.Require().NoError(err)
+
+	suite.down = true
+	airPollutionService := suite.newService()
+
+	// A range that was never requested as such is served from the days.
+	ctx := freshness.NewContext(suite.ctx)
+	history, err := airPollutionService.GetHistoricalAirPollution(ctx, 12.97, 77.59, suite.dayStart+6*3600, suite.dayStart+20*3600)
+	suite.Require().NoError(err)
+	suite.Len(history.List, 15)
+	suite.Equal(int(suite.dayStart+6*3600), history.List[0].Dt)
+	state, _ := freshness.FromContext(ctx).State()
+	suite.Equal(freshness.LastKnownGood, state)
+
+	// A day never fetched is not made up.
+	_, err = airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart+20*3600, suite.dayStart+50*3600)
+	suite.ErrorIs(err, upstream.ErrUnavailable)
+}
+
+func (suite *AirPollutionServiceSuite) TestTooLongRangesAreRejected() {
+	suite.conf.AirPollutionConfig.HistoryMaxSpan = 24 * time.Hour
+	airPollutionService := suite.newService()
+
+	_, err := airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+24*3600)
+	suite.NoError(err)
+
+	_, err = airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+24*3600+1)
+	suite.ErrorIs(err, services.ErrHistoryRangeTooLong)
+
+	// Spans this long overflow a Duration.
+	_, err = airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart-10_000_000_000, suite.dayStart)
+	suite.ErrorIs(err, services.ErrHistoryRangeTooLong)
+
+	suite.Equal(1, suite.upstream.callsTo(upstream.HistoricalAirPollution))
+}
+
+func (suite *AirPollutionServiceSuite) TestLongRangesAreFetchedInAlignedChunks() {
+	suite.conf.AirPollutionConfig.HistoryChunk = 24 * time.Hour
+	airPollutionService := suite.newService()
+
+	start, end := suite.dayStart+12*3600, suite.dayStart+60*3600
+
+	history, err := airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, start, end)
+	suite.Require().NoError(err)
+	suite.Len(history.List, 49)
+
+	suite.ElementsMatch([]repository.TimeRange{
+		{Start: start, End: suite.dayStart + 24*3600 - 1},
+		{Start: suite.dayStart + 24*3600, End: suite.dayStart + 48*3600 - 1},
+		{Start: suite.dayStart + 48*3600, End: end},
+	}, suite.ranges)
+}
+
+func (suite *AirPollutionServiceSuite) TestDatapointsAreSortedAndDeduplicated() {
+	suite.conf.AirPollutionConfig.HistoryChunk = 6 * time.Hour
+	airPollutionService := suite.newService()
+
+	_, err := airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+12*3600)
+	suite.Require().NoError(err)
+
+	// The stored hours are served along with the fetched ones around them,
+	// which repeat some of them, each hour once and in order.
+	suite.overlap = true
+
+	history, err := airPollutionService.GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart+6*3600, suite.dayStart+24*3600)
+	suite.Require().NoError(err)
+	suite.Require().Len(history.List, 19)
+
+	for i, datapoint := range history.List {
+		suite.Equal(int(suite.dayStart)+(6+i)*3600, datapoint.Dt)
+	}
+}
+
+func (suite *AirPollutionServiceSuite) TestChunksReportTheLeastFreshOfThem() {
+	suite.conf.AirPollutionConfig.HistoryChunk = 24 * time.Hour
+
+	_, err := suite.newService().GetHistoricalAirPollution(suite.ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+48*3600-1)
+	suite.Require().NoError(err)
+
+	// The first day is fetched, the second is served from the last known
+	// good history, in whichever order the chunks end.
+	suite.downFrom = suite.dayStart + 24*3600
+
+	for i := 0; i < 5; i++ {
+		ctx := freshness.NewContext(suite.ctx)
+		history, err := suite.newService().GetHistoricalAirPollution(ctx, 12.97, 77.59, suite.dayStart, suite.dayStart+48*3600-1)
+		suite.Require().NoError(err)
+		suite.Len(history.List, 48)
+
+		state, fetchedAt := freshness.FromContext(ctx).State()
+		suite.Equal(freshness.LastKnownGood, state)
+		suite.False(fetchedAt.IsZero())
+	}
+}
+
+func TestAirPollutionServiceSuite(t *testing.T) {
+	suite.Run(t, new(AirPollutionServiceSuite))
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/metrics"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type CacheFailureSuite struct {
+	suite.Suite
+	ctx         context.Context
+	upstream    *fakeUpstream
+	redis       *downCache
+	refreshPool services.RefreshPool
+}
+
+func (suite *CacheFailureSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.upstream = newFakeUpstream(func(context.Context, upstream.Endpoint, url.Values) (any, error) {
+		return entities.CurrentWeather{Name: "London"}, nil
+	})
+	suite.redis = &downCache{}
+	suite.refreshPool = services.NewRefreshPool(1, zap.NewNop())
+}
+
+func (suite *CacheFailureSuite) TearDownTest() {
+	suite.refreshPool.Stop()
+}
+
+// newService returns a service whose every cache call goes to store.
+func (suite *CacheFailureSuite) newService(store cache.Cache) services.WeatherService {
+	logger := zap.NewNop()
+	conf := config.GetConfig()
+
+	return services.NewWeatherService(
+		repository.NewWeatherRepository(store, conf.CacheConfig, logger),
+		repository.NewFallbackRepository(store, time.Hour, logger),
+		repository.NewLockRepository(store, logger),
+		suite.upstream,
+		suite.refreshPool,
+		conf,
+		logger,
+	)
+}
+
+// cacheErrors returns the number of failed cache calls of operation.
+func cacheErrors(operation string) int64 {
+	counter, _ := expvar.Get("cache_errors").(*expvar.Map).Get(operation).(*expvar.Int)
+	if counter == nil {
+		return 0
+	}
+
+	return counter.Value()
+}
+
+func (suite *CacheFailureSuite) TestFailedCacheCallsFallThroughToUpstream() {
+	weatherService := suite.newService(suite.redis)
+	reads, writes := cacheErrors(metrics.CacheRead), cacheErrors(metrics.CacheWrite)
+
+	for i := 0; i < 2; i++ {
+		currentWeather, err := weatherService.GetCurrentWeather(suite.ctx, 51.51, -0.13)
+		suite.Require().NoError(err)
+		suite.Equal("London", currentWeather.Name)
+	}
+
+	suite.Equal(2, suite.upstream.callsTo(upstream.CurrentWeather))
+	suite.Greater(cacheErrors(metrics.CacheRead)-reads, int64(0))
+	suite.Greater(cacheErrors(metrics.CacheWrite)-writes, int64(0))
+}
+
+func (suite *CacheFailureSuite) TestTheBreakerStopsCallingAFailingCache() {
+	breaker := cache.NewBreakerCache(suite.redis, 3, time.Hour)
+	weatherService := suite.newService(breaker)
+
+	for i := 0; i < 5; i++ {
+		currentWeather, err := weatherService.GetCurrentWeather(suite.ctx, 51.51, -0.13)
+		suite.Require().NoError(err)
+		suite.Equal("London", currentWeather.Name)
+	}
+
+	suite.Equal(cache.BreakerOpen, breaker.State())
+	suite.Equal(int32(3), suite.redis.calls.Load())
+	suite.Equal(5, suite.upstream.callsTo(upstream.CurrentWeather))
+}
+
+func TestCacheFailureSuite(t *testing.T) {
+	suite.Run(t, new(CacheFailureSuite))
+}
+
+var errRedisDown = errors.New("dial tcp: connection refused")
+
+// downCache counts the calls it is given and fails every one of them, like
+// Redis while it is unreachable.
+type downCache struct {
+	calls atomic.Int32
+}
+
+func (dc *downCache) Get(context.Context, string) ([]byte, error) {
+	dc.calls.Add(1)
+	return nil, errRedisDown
+}
+
+func (dc *downCache) MGet(context.Context, ...string) ([][]byte, error) {
+	dc.calls.Add(1)
+	return nil, errRedisDown
+}
+
+func (dc *downCache) Set(context.Context, string, []byte, time.Duration) error {
+	dc.calls.Add(1)
+	return errRedisDown
+}
+
+func (dc *downCache) MSet(context.Context, ...cache.Item) error {
+	dc.calls.Add(1)
+	return errRedisDown
+}
+
+func (dc *downCache) SetNX(context.Context, string, []byte, time.Duration) (bool, error) {
+	dc.calls.Add(1)
+	return false, errRedisDown
+}
+
+func (dc *downCache) TTL(context.Context, string) (time.Duration, error) {
+	dc.calls.Add(1)
+	return 0, errRedisDown
+}
+
+func (dc *downCache) Delete(context.Context, ...string) error {
+	dc.calls.Add(1)
+	return errRedisDown
+}
+
+func (dc *downCache) CompareAndDelete(context.Context, string, []byte) (bool, error) {
+	dc.calls.Add(1)
+	return false, errRedisDown
+}
+
+func (dc *downCache) Scan(context.Context, string) ([]string, error) {
+	dc.calls.Add(1)
+	return nil, errRedisDown
+}
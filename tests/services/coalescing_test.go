@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"expvar"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/metrics"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/SamPariatIL/weather-wrapper/usage"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type CoalescingSuite struct {
+	suite.Suite
+	ctx context.Context
+	// sharedCache is the cache the replicas of a test share.
+	sharedCache cache.Cache
+	upstream    *fakeUpstream
+	// gate holds upstream calls until it is closed.
+	gate        chan struct{}
+	conf        config.Config
+	refreshPool services.RefreshPool
+}
+
+func (suite *CoalescingSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.sharedCache = cache.NewLRUCache(100)
+	suite.gate = make(chan struct{})
+
+	gate := suite.gate
+	suite.upstream = newFakeUpstream(func(ctx context.Context, endpoint upstream.Endpoint, params url.Values) (any, error) {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		return entities.CurrentWeather{Name: "London"}, nil
+	})
+
+	suite.conf = *config.GetConfig()
+	suite.conf.CacheConfig.LockWait = 2 * time.Second
+	suite.conf.CacheConfig.LockPollInterval = 10 * time.Millisecond
+	suite.refreshPool = services.NewRefreshPool(1, zap.NewNop())
+}
+
+func (suite *CoalescingSuite) TearDownTest() {
+	select {
+	case <-suite.gate:
+	default:
+		close(suite.gate)
+	}
+
+	suite.refreshPool.Stop()
+}
+
+// newReplica returns a service sharing the cache, and so the locks, with the
+// other replicas of the test, as another instance of the server would.
+func (suite *CoalescingSuite) newReplica() services.WeatherService {
+	logger := zap.NewNop()
+
+	return services.NewWeatherService(
+		repository.NewWeatherRepository(suite.sharedCache, suite.conf.CacheConfig, logger),
+		repository.NewFallbackRepository(suite.sharedCache, time.Hour, logger),
+		repository.NewLockRepository(suite.sharedCache, logger),
+		suite.upstream,
+		suite.refreshPool,
+		&suite.conf,
+		logger,
+	)
+}
+
+// getWeather gets the current weather in a metered request and returns the
+// usage record of the request.
+func (suite *CoalescingSuite) getWeather(weatherService services.WeatherService) (usage.Record, error) {
+	event := usage.NewEvent("ip:127.0.0.1", "", time.Now())
+	ctx := usage.NewContext(suite.ctx, event)
+
+	currentWeather, err := weatherService.GetCurrentWeather(ctx, 51.51, -0.13)
+	if err == nil {
+		suite.Equal("London", currentWeather.Name)
+	}
+
+	return event.Finish("GET /weather/now", 200, 0), err
+}
+
+// waitForUpstream waits until calls upstream calls have started.
+func (suite *CoalescingSuite) waitForUpstream(calls int) {
+	suite.Require().Eventually(func() bool {
+		return suite.upstream.callsTo(upstream.CurrentWeather) == calls
+	}, time.Second, 5*time.Millisecond)
+}
+
+// coalescing returns the value of counter of the current weather endpoint.
+func coalescing(counter string) int64 {
+	endpointVar, _ := expvar.Get("coalescing").(*expvar.Map).Get(string(upstream.CurrentWeather)).(*expvar.Map)
+	if endpointVar == nil {
+		return 0
+	}
+
+	counterVar, _ := endpointVar.Get(counter).(*expvar.Int)
+	if counterVar == nil {
+		return 0
+	}
+
+	return counterVar.Value()
+}
+
+func (suite *CoalescingSuite) TestConcurrentMissesShareOneFetch() {
+	weatherService := suite.newReplica()
+	coalesced := coalescing(metrics.CoalescedInProcess)
+	fetches := coalescing(metrics.UpstreamFetches)
+
+	records := make([]usage.Record, 10)
+
+	var requests sync.WaitGroup
+	for i := range records {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+
+			var err error
+			records[i], err = suite.getWeather(weatherService)
+			suite.NoError(err)
+		}()
+	}
+
+	suite.waitForUpstream(1)
+	// Give the other requests the time to join the fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(suite.gate)
+	requests.Wait()
+
+	suite.Equal(1, suite.upstream.callsTo(upstream.CurrentWeather))
+	suite.Equal(int64(1), coalescing(metrics.UpstreamFetches)-fetches)
+	suite.Equal(int64(9), coalescing(metrics.CoalescedInProcess)-coalesced)
+
+	// The leader called upstream, the followers were served by its fetch.
+	var upstreamCalls, cacheHits int
+	for _, record := range records {
+		if record.UpstreamCall {
+			upstreamCalls++
+		}
+		if record.CacheHit {
+			cacheHits++
+		}
+	}
+
+	suite.Equal(1, upstreamCalls)
+	suite.Equal(9, cacheHits)
+}
+
+func (suite *CoalescingSuite) TestReplicasWaitForTheLockHolder() {
+	leader, follower := suite.newReplica(), suite.newReplica()
+	coalesced := coalescing(metrics.CoalescedDistributed)
+
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := suite.getWeather(leader)
+		leaderDone <- err
+	}()
+
+	suite.waitForUpstream(1)
+
+	followerDone := make(chan usage.Record, 1)
+	go func() {
+		record, err := suite.getWeather(follower)
+		suite.NoError(err)
+		followerDone <- record
+	}()
+
+	suite.Eventually(func() bool {
+		return coalescing(metrics.CoalescedDistributed)-coalesced == 1
+	}, time.Second, 5*time.Millisecond)
+
+	close(suite.gate)
+	suite.NoError(<-leaderDone)
+
+	record := <-followerDone
+	suite.True(record.CacheHit)
+	suite.False(record.UpstreamCall)
+	suite.Equal(1, suite.upstream.callsTo(upstream.CurrentWeather))
+}
+
+func (suite *CoalescingSuite) TestReplicasStopWaitingForASlowLockHolder() {
+	suite.conf.CacheConfig.LockWait = 50 * time.Millisecond
+	leader, follower := suite.newReplica(), suite.newReplica()
+	timeouts := coalescing(metrics.LockWaitTimeouts)
+
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := suite.getWeather(leader)
+		leaderDone <- err
+	}()
+
+	suite.waitForUpstream(1)
+
+	followerDone := make(chan usage.Record, 1)
+	go func() {
+		record, err := suite.getWeather(follower)
+		suite.NoError(err)
+		followerDone <- record
+	}()
+
+	suite.waitForUpstream(2)
+	suite.Equal(int64(1), coalescing(metrics.LockWaitTimeouts)-timeouts)
+
+	close(suite.gate)
+	suite.NoError(<-leaderDone)
+	suite.True((<-followerDone).UpstreamCall)
+}
+
+func (suite *CoalescingSuite) TestLocksOutliveTheFetch() {
+	weatherService := suite.newReplica()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := suite.getWeather(weatherService)
+		done <- err
+	}()
+
+	suite.waitForUpstream(1)
+
+	keys, err := suite.sharedCache.Scan(suite.ctx, "weather-wrapper:v1:lock_*")
+	suite.Require().NoError(err)
+	suite.Require().Len(keys, 1)
+
+	// A fetch may take a timeout per attempt and the longest backoff
+	// between them.
+	upstreamConf := suite.conf.UpstreamConfig
+	fetch := upstreamConf.Timeout*time.Duration(upstreamConf.Retries+1) + upstreamConf.RetryMaxDelay*time.Duration(upstreamConf.Retries)
+
+	ttl, err := suite.sharedCache.TTL(suite.ctx, keys[0])
+	suite.Require().NoError(err)
+	suite.Greater(ttl, fetch-time.Second)
+	suite.GreaterOrEqual(ttl, suite.conf.CacheConfig.LockTTL-time.Second)
+
+	close(suite.gate)
+	suite.NoError(<-done)
+}
+
+func TestCoalescingSuite(t *testing.T) {
+	suite.Run(t, new(CoalescingSuite))
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"net/url"
+	"sync"
+)
+
+// fakeUpstream answers upstream calls with respond and counts them per
+// endpoint.
+type fakeUpstream struct {
+	mu      sync.Mutex
+	calls   map[upstream.Endpoint]int
+	respond func(ctx context.Context, endpoint upstream.Endpoint, params url.Values) (any, error)
+}
+
+func newFakeUpstream(respond func(ctx context.Context, endpoint upstream.Endpoint, params url.Values) (any, error)) *fakeUpstream {
+	return &fakeUpstream{calls: make(map[upstream.Endpoint]int), respond: respond}
+}
+
+func (fu *fakeUpstream) Get(ctx context.Context, endpoint upstream.Endpoint, params url.Values, v any) error {
+	fu.mu.Lock()
+	fu.calls[endpoint]++
+	fu.mu.Unlock()
+
+	response, err := fu.respond(ctx, endpoint, params)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, v)
+}
+
+func (fu *fakeUpstream) Breakers() []upstream.BreakerStatus {
+	return nil
+}
+
+// callsTo returns the number of calls made to endpoint.
+func (fu *fakeUpstream) callsTo(endpoint upstream.Endpoint) int {
+	fu.mu.Lock()
+	defer fu.mu.Unlock()
+
+	return fu.calls[endpoint]
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"cmp"
+	"context"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/usage"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type UsageServiceSuite struct {
+	suite.Suite
+	usageRepo    *rollupRecorder
+	usageService services.UsageService
+}
+
+func (suite *UsageServiceSuite) SetupTest() {
+	suite.usageRepo = &rollupRecorder{}
+	suite.usageService = services.NewUsageService(suite.usageRepo, zap.NewNop())
+	suite.usageService.Start()
+}
+
+func (suite *UsageServiceSuite) TestRollsRequestsUpPerPrincipalRouteAndDay() {
+	day := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	suite.usageService.Record(usage.Record{Principal: "uid:alice", UID: "alice", Route: "GET /weather", CacheHit: true, Latency: 10 * time.Millisecond, Timestamp: day.Add(time.Hour)})
+	suite.usageService.Record(usage.Record{Principal: "uid:alice", UID: "alice", Route: "GET /weather", UpstreamCall: true, Latency: 30 * time.Millisecond, Timestamp: day.Add(23 * time.Hour)})
+	suite.usageService.Record(usage.Record{Principal: "uid:alice", UID: "alice", Route: "GET /weather", Latency: 5 * time.Millisecond, Timestamp: day.Add(25 * time.Hour)})
+	suite.usageService.Record(usage.Record{Principal: "key:k1", UID: "alice", Route: "GET /weather", CacheHit: true, Timestamp: day})
+
+	suite.usageService.Stop()
+
+	suite.Equal([]entities.UsageRollup{
+		{Principal: "key:k1", Route: "GET /weather", Day: day, UID: "alice", Requests: 1, CacheHits: 1},
+		{Principal: "uid:alice", Route: "GET /weather", Day: day, UID: "alice", Requests: 2, CacheHits: 1, CacheMisses: 1, UpstreamCalls: 1, TotalLatencyMs: 40},
+		{Principal: "uid:alice", Route: "GET /weather", Day: day.AddDate(0, 0, 1), UID: "alice", Requests: 1, CacheMisses: 1, TotalLatencyMs: 5},
+	}, suite.usageRepo.flushed())
+}
+
+func (suite *UsageServiceSuite) TestStopFlushesBufferedRecords() {
+	for i := 0; i < 100; i++ {
+		suite.usageService.Record(usage.Record{Principal: "ip:127.0.0.1", Route: "GET /geocode", Timestamp: time.Now()})
+	}
+
+	suite.usageService.Stop()
+
+	rollups := suite.usageRepo.flushed()
+	suite.Require().Len(rollups, 1)
+	suite.Equal(int64(100), rollups[0].Requests)
+}
+
+func (suite *UsageServiceSuite) TestStopWithoutRecordsFlushesNothing() {
+	suite.usageService.Stop()
+
+	suite.Empty(suite.usageRepo.flushed())
+}
+
+func TestUsageServiceSuite(t *testing.T) {
+	suite.Run(t, new(UsageServiceSuite))
+}
+
+// rollupRecorder keeps the rollups flushed to it.
+type rollupRecorder struct {
+	mu      sync.Mutex
+	rollups []entities.UsageRollup
+}
+
+func (rr *rollupRecorder) UpsertUsageRollups(_ context.Context, rollups []entities.UsageRollup) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.rollups = append(rr.rollups, rollups...)
+	return nil
+}
+
+func (rr *rollupRecorder) GetDailyUsage(context.Context, string, time.Time, time.Time) ([]entities.UsageTotals, error) {
+	return nil, nil
+}
+
+func (rr *rollupRecorder) GetMonthlyUsage(context.Context, string, time.Time, time.Time) ([]entities.UsageTotals, error) {
+	return nil, nil
+}
+
+func (rr *rollupRecorder) IncrementMonthlyRequests(context.Context, string, string) (int64, error) {
+	return 0, nil
+}
+
+// flushed returns the flushed rollups sorted by principal and day.
+func (rr *rollupRecorder) flushed() []entities.UsageRollup {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rollups := slices.Clone(rr.rollups)
+	slices.SortFunc(rollups, func(a, b entities.UsageRollup) int {
+		return cmp.Or(cmp.Compare(a.Principal, b.Principal), a.Day.Compare(b.Day))
+	})
+
+	return rollups
+}
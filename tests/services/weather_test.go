@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/freshness"
+	"github.com/SamPariatIL/weather-wrapper/repository"
+	"github.com/SamPariatIL/weather-wrapper/services"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type WeatherServiceSuite struct {
+	suite.Suite
+	ctx           context.Context
+	upstream      *fakeUpstream
+	fallbackCache cache.Cache
+	// version is the version of the weather upstream answers with.
+	version atomic.Int32
+	// down is whether upstream is unavailable.
+	down atomic.Bool
+	// block, when set, holds upstream calls until it is closed or the call
+	// is cancelled.
+	block chan struct{}
+	// softTTL is the soft TTL of the current weather of the next service.
+	softTTL        time.Duration
+	refreshPool    services.RefreshPool
+	weatherService services.WeatherService
+}
+
+func (suite *WeatherServiceSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.version.Store(1)
+	suite.down.Store(false)
+	suite.block = nil
+	suite.softTTL = time.Hour
+
+	suite.upstream = newFakeUpstream(func(ctx context.Context, endpoint upstream.Endpoint, params url.Values) (any, error) {
+		if suite.block != nil {
+			select {
+			case <-suite.block:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if suite.down.Load() {
+			return nil, upstream.ErrUnavailable
+		}
+
+		return entities.CurrentWeather{Name: fmt.Sprintf("v%d", suite.version.Load())}, nil
+	})
+
+	suite.fallbackCache = cache.NewLRUCache(100)
+	suite.weatherService = suite.newService(16)
+}
+
+func (suite *WeatherServiceSuite) TearDownTest() {
+	if suite.block != nil {
+		close(suite.block)
+	}
+
+	suite.refreshPool.Stop()
+}
+
+// newService returns a service with an empty cache and a refresh pool of
+// size, sharing only the last known good responses with the other services
+// of the test.
+func (suite *WeatherServiceSuite) newService(size int) services.WeatherService {
+	logger := zap.NewNop()
+
+	conf := *config.GetConfig()
+	conf.CacheConfig.TTLPolicies = map[string]config.TTLPolicy{
+		string(upstream.CurrentWeather): {Policy: config.TTLFixed, Soft: suite.softTTL, Hard: time.Hour},
+	}
+
+	sharedCache := cache.NewLRUCache(100)
+	suite.refreshPool = services.NewRefreshPool(size, logger)
+
+	return services.NewWeatherService(
+		repository.NewWeatherRepository(sharedCache, conf.CacheConfig, logger),
+		repository.NewFallbackRepository(suite.fallbackCache, time.Hour, logger),
+		repository.NewLockRepository(sharedCache, logger),
+		suite.upstream,
+		suite.refreshPool,
+		&conf,
+		logger,
+	)
+}
+
+// getWeather returns the name of the current weather at lat and the state of
+// its freshness.
+func (suite *WeatherServiceSuite) getWeather(weatherService services.WeatherService, lat float32) (string, string, error) {
+	ctx := freshness.NewContext(suite.ctx)
+
+	currentWeather, err := weatherService.GetCurrentWeather(ctx, lat, 77.59)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, _ := freshness.FromContext(ctx).State()
+	return currentWeather.Name, state, nil
+}
+
+func (suite *WeatherServiceSuite) TestFreshEntriesAreServedFromTheCache() {
+	name, state, err := suite.getWeather(suite.weatherService, 12.97)
+	suite.Require().NoError(err)
+	suite.Equal("v1", name)
+	suite.Equal(freshness.Fresh, state)
+
+	suite.version.Store(2)
+
+	name, _, err = suite.getWeather(suite.weatherService, 12.97)
+	suite.Require().NoError(err)
+	suite.Equal("v1", name)
+	suite.Equal(1, suite.upstream.callsTo(upstream.CurrentWeather))
+}
+
+func (suite *WeatherServiceSuite) TestStaleEntriesAreServedWhileRefreshed() {
+	suite.softTTL = 0
+	weatherService := suite.newService(16)
+
+	_, _, err := suite.getWeather(weatherService, 12.97)
+	suite.Require().NoError(err)
+
+	suite.version.Store(2)
+
+	name, state, err := suite.getWeather(weatherService, 12.97)
+	suite.Require().NoError(err)
+	suite.Equal("v1", name)
+	suite.Equal(freshness.Stale, state)
+
+	suite.Eventually(func() bool {
+		name, _, err := suite.getWeather(weatherService, 12.97)
+		return err == nil && name == "v2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *WeatherServiceSuite) TestFailedRefreshesKeepTheStaleEntry() {
+	suite.softTTL = 0
+	weatherService := suite.newService(16)
+
+	_, _, err := suite.getWeather(weatherService, 12.97)
+	suite.Require().NoError(err)
+
+	suite.down.Store(true)
+
+	for i := 0; i < 3; i++ {
+		name, state, err := suite.getWeather(weatherService, 12.97)
+		suite.Require().NoError(err)
+		suite.Equal("v1", name)
+		suite.Equal(freshness.Stale, state)
+	}
+}
+
+func (suite *WeatherServiceSuite) TestRefreshesAreBoundedAndStopped() {
+	suite.softTTL = 0
+	weatherService := suite.newService(1)
+
+	for _, lat := range []float32{10, 20, 30} {
+		_, _, err := suite.getWeather(weatherService, lat)
+		suite.Require().NoError(err)
+	}
+
+	// Every refresh hangs until it is cancelled.
+	suite.block = make(chan struct{})
+
+	for _, lat := range []float32{10, 20, 30} {
+		_, state, err := suite.getWeather(weatherService, lat)
+		suite.Require().NoError(err)
+		suite.Equal(freshness.Stale, state)
+	}
+
+	suite.Eventually(func() bool {
+		return suite.upstream.callsTo(upstream.CurrentWeather) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	// The one slot stays taken, so no other refresh starts.
+	time.Sleep(50 * time.Millisecond)
+	suite.Equal(4, suite.upstream.callsTo(upstream.CurrentWeather))
+
+	stopped := make(chan struct{})
+	go func() {
+		suite.refreshPool.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		suite.Fail("Stop did not cancel the running refresh")
+	}
+
+	// Stale entries found after Stop are not refreshed.
+	_, _, err := suite.getWeather(weatherService, 20)
+	suite.Require().NoError(err)
+	time.Sleep(50 * time.Millisecond)
+	suite.Equal(4, suite.upstream.callsTo(upstream.CurrentWeather))
+}
+
+func (suite *WeatherServiceSuite) TestLastKnownGoodIsServedWhileUpstreamIsDown() {
+	_, _, err := suite.getWeather(suite.weatherService, 12.97)
+	suite.Require().NoError(err)
+
+	suite.down.Store(true)
+	weatherService := suite.newService(16)
+
+	name, state, err := suite.getWeather(weatherService, 12.97)
+	suite.Require().NoError(err)
+	suite.Equal("v1", name)
+	suite.Equal(freshness.LastKnownGood, state)
+
+	// Without a last known good response the failure is returned.
+	_, _, err = suite.getWeather(weatherService, 45)
+	suite.ErrorIs(err, upstream.ErrUnavailable)
+}
+
+func (suite *WeatherServiceSuite) TestLastKnownGoodIsNotServedForOtherFailures() {
+	_, _, err := suite.getWeather(suite.weatherService, 12.97)
+	suite.Require().NoError(err)
+
+	weatherService := services.NewWeatherService(
+		repository.NewWeatherRepository(cache.NewLRUCache(100), config.GetConfig().CacheConfig, zap.NewNop()),
+		repository.NewFallbackRepository(suite.fallbackCache, time.Hour, zap.NewNop()),
+		repository.NewLockRepository(cache.NewLRUCache(100), zap.NewNop()),
+		newFakeUpstream(func(context.Context, upstream.Endpoint, url.Values) (any, error) {
+			return nil, upstream.ErrUnauthorized
+		}),
+		suite.refreshPool,
+		config.GetConfig(),
+		zap.NewNop(),
+	)
+
+	_, _, err = suite.getWeather(weatherService, 12.97)
+	suite.ErrorIs(err, upstream.ErrUnauthorized)
+}
+
+func TestWeatherServiceSuite(t *testing.T) {
+	suite.Run(t, new(WeatherServiceSuite))
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/aqi"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/summary"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+// ist is the offset of India Standard Time from UTC, in seconds.
+const ist = 19800
+
+type DailySuite struct {
+	suite.Suite
+}
+
+func hour(dt int, pm25, pm10 float64) entities.AirPollutionDatapoint {
+	return entities.AirPollutionDatapoint{
+		Dt:         dt,
+		Components: entities.AirPollutionComponents{PM25: pm25, PM10: pm10},
+	}
+}
+
+func (suite *DailySuite) TestGroupsByLocalDay() {
+	// 2024-01-01 17:00, 18:00 and 19:00 UTC are 22:30, 23:30 and 00:30 IST.
+	days, err := summary.Daily([]entities.AirPollutionDatapoint{
+		hour(1704135600, 50, 40),
+		hour(1704128400, 10, 30),
+		hour(1704132000, 20, 50),
+	}, ist, aqi.USEPA)
+	suite.NoError(err)
+	suite.Len(days, 2)
+
+	first := days[0]
+	suite.Equal("2024-01-01", first.Date)
+	suite.Equal(2, first.Hours)
+	suite.Equal(entities.PollutantSummary{Min: 10, Mean: 15, Max: 20}, first.Pollutants[aqi.PM25])
+	suite.Equal(entities.PollutantSummary{Min: 30, Mean: 40, Max: 50}, first.Pollutants[aqi.PM10])
+	suite.Equal(1704132000, first.PeakAQI.Dt)
+	suite.Equal("23:30", first.PeakAQI.Hour)
+	suite.Equal(71, first.PeakAQI.AQI.Value)
+	suite.Equal(1, first.ExceedanceHours[aqi.PM25])
+	suite.Equal(1, first.ExceedanceHours[aqi.PM10])
+	suite.Contains(first.ExceedanceHours, aqi.O3)
+	suite.Zero(first.ExceedanceHours[aqi.O3])
+	suite.NotContains(first.ExceedanceHours, aqi.NH3)
+
+	second := days[1]
+	suite.Equal("2024-01-02", second.Date)
+	suite.Equal(1, second.Hours)
+	suite.Equal("00:30", second.PeakAQI.Hour)
+	suite.Equal(137, second.PeakAQI.AQI.Value)
+	suite.Equal(aqi.PM25, second.PeakAQI.AQI.DominantPollutant)
+}
+
+func (suite *DailySuite) TestUTC() {
+	days, err := summary.Daily([]entities.AirPollutionDatapoint{
+		hour(1704128400, 10, 30),
+		hour(1704135600, 50, 40),
+	}, 0, aqi.EUCAQI)
+	suite.NoError(err)
+	suite.Len(days, 1)
+	suite.Equal("2024-01-01", days[0].Date)
+	suite.Equal("19:00", days[0].PeakAQI.Hour)
+	suite.Equal(aqi.EUCAQI, days[0].PeakAQI.AQI.Standard)
+}
+
+func (suite *DailySuite) TestEmpty() {
+	days, err := summary.Daily(nil, ist, aqi.USEPA)
+	suite.NoError(err)
+	suite.Empty(days)
+}
+
+func (suite *DailySuite) TestUnknownStandard() {
+	_, err := summary.Daily([]entities.AirPollutionDatapoint{hour(0, 1, 1)}, 0, "owm")
+	suite.ErrorIs(err, aqi.ErrUnknownStandard)
+}
+
+func TestDailySuite(t *testing.T) {
+	suite.Run(t, &DailySuite{})
+}
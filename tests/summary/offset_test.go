@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/summary"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+// cet and cest are the offsets of Central European Time and its summer time
+// from UTC, in seconds.
+const (
+	cet  = 3600
+	cest = 7200
+)
+
+type OffsetSuite struct {
+	suite.Suite
+	winter time.Time
+}
+
+func (suite *OffsetSuite) SetupTest() {
+	suite.winter = time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+}
+
+func date(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+}
+
+func (suite *OffsetSuite) TestRangesAcrossADaylightSavingTimeChange() {
+	suite.True(summary.OffsetMayChange(cet, suite.winter, date(2024, time.March, 20), date(2024, time.April, 5)))
+}
+
+func (suite *OffsetSuite) TestRangesWithinTheSeasonOfAnotherOffset() {
+	suite.True(summary.OffsetMayChange(cet, suite.winter, date(2023, time.July, 1), date(2023, time.July, 31)))
+}
+
+func (suite *OffsetSuite) TestRangesWithinTheCurrentSeason() {
+	suite.False(summary.OffsetMayChange(cet, suite.winter, date(2023, time.December, 1), date(2024, time.January, 10)))
+	suite.False(summary.OffsetMayChange(cest, time.Date(2024, time.July, 15, 0, 0, 0, 0, time.UTC), date(2024, time.June, 1), date(2024, time.July, 1)))
+}
+
+func (suite *OffsetSuite) TestOffsetsWithoutDaylightSavingTime() {
+	suite.False(summary.OffsetMayChange(ist, suite.winter, date(2023, time.January, 1), date(2024, time.January, 1)))
+}
+
+func TestOffsetSuite(t *testing.T) {
+	suite.Run(t, &OffsetSuite{})
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type ClientSuite struct {
+	suite.Suite
+	calls  atomic.Int32
+	status atomic.Int32
+	server *httptest.Server
+	client upstream.Client
+}
+
+func (suite *ClientSuite) SetupTest() {
+	suite.calls.Store(0)
+	suite.status.Store(http.StatusOK)
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.calls.Add(1)
+		w.WriteHeader(int(suite.status.Load()))
+		_, _ = w.Write([]byte(`{"name":"London"}`))
+	}))
+
+	suite.client = upstream.NewClient(&config.Config{
+		WeatherConfig: config.WeatherConfig{BaseURL: suite.server.URL},
+		UpstreamConfig: config.UpstreamConfig{
+			Timeout:             time.Second,
+			Retries:             2,
+			RetryBaseDelay:      time.Millisecond,
+			RetryMaxDelay:       time.Millisecond * 5,
+			BreakerThreshold:    2,
+			BreakerOpenDuration: time.Millisecond * 50,
+		},
+	}, zap.NewNop())
+}
+
+func (suite *ClientSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ClientSuite) get() error {
+	var v map[string]any
+	return suite.client.Get(context.Background(), upstream.CurrentWeather, nil, &v)
+}
+
+func (suite *ClientSuite) breakerState() string {
+	for _, breaker := range suite.client.Breakers() {
+		if breaker.Endpoint == upstream.CurrentWeather {
+			return breaker.State
+		}
+	}
+
+	return ""
+}
+
+func (suite *ClientSuite) TestRetriesTransientFailures() {
+	suite.status.Store(http.StatusServiceUnavailable)
+
+	err := suite.get()
+	suite.True(errors.Is(err, upstream.ErrUnavailable))
+	suite.Equal(int32(3), suite.calls.Load())
+}
+
+func (suite *ClientSuite) TestDoesNotRetryClientErrors() {
+	suite.status.Store(http.StatusNotFound)
+
+	err := suite.get()
+	suite.True(errors.Is(err, upstream.ErrNotFound))
+	suite.Equal(int32(1), suite.calls.Load())
+	suite.Equal(upstream.BreakerClosed, suite.breakerState())
+}
+
+func (suite *ClientSuite) TestBreakerOpensAndRecovers() {
+	suite.status.Store(http.StatusInternalServerError)
+
+	suite.Error(suite.get())
+	suite.Equal(upstream.BreakerClosed, suite.breakerState())
+	suite.Error(suite.get())
+	suite.Equal(upstream.BreakerOpen, suite.breakerState())
+
+	calls := suite.calls.Load()
+	err := suite.get()
+	suite.True(errors.Is(err, upstream.ErrCircuitOpen))
+	suite.True(errors.Is(err, upstream.ErrUnavailable))
+	suite.Equal(calls, suite.calls.Load())
+
+	time.Sleep(time.Millisecond * 60)
+	suite.status.Store(http.StatusOK)
+
+	suite.NoError(suite.get())
+	suite.Equal(upstream.BreakerClosed, suite.breakerState())
+}
+
+func (suite *ClientSuite) TestFailedProbeReopensBreaker() {
+	suite.status.Store(http.StatusInternalServerError)
+
+	suite.Error(suite.get())
+	suite.Error(suite.get())
+
+	time.Sleep(time.Millisecond * 60)
+
+	suite.Error(suite.get())
+	suite.Equal(upstream.BreakerOpen, suite.breakerState())
+}
+
+func (suite *ClientSuite) TestRateLimitsDoNotOpenBreaker() {
+	suite.status.Store(http.StatusTooManyRequests)
+
+	for i := 0; i < 3; i++ {
+		err := suite.get()
+		suite.True(errors.Is(err, upstream.ErrRateLimited))
+	}
+
+	suite.Equal(upstream.BreakerClosed, suite.breakerState())
+	suite.Equal(int32(3), suite.calls.Load())
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
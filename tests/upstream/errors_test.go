@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"errors"
+	"github.com/SamPariatIL/weather-wrapper/upstream"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type ErrorsSuite struct {
+	suite.Suite
+}
+
+func (suite *ErrorsSuite) TestAPIErrorClassification() {
+	statusErrors := []struct {
+		statusCode int
+		expected   error
+	}{
+		{http.StatusBadRequest, upstream.ErrInvalidInput},
+		{http.StatusUnauthorized, upstream.ErrUnauthorized},
+		{http.StatusForbidden, upstream.ErrUnauthorized},
+		{http.StatusNotFound, upstream.ErrNotFound},
+		{http.StatusUnprocessableEntity, upstream.ErrInvalidInput},
+		{http.StatusTooManyRequests, upstream.ErrRateLimited},
+		{http.StatusInternalServerError, upstream.ErrUnavailable},
+		{http.StatusBadGateway, upstream.ErrUnavailable},
+		{http.StatusServiceUnavailable, upstream.ErrUnavailable},
+	}
+
+	for _, statusError := range statusErrors {
+		err := error(&upstream.APIError{Endpoint: upstream.CurrentWeather, StatusCode: statusError.statusCode})
+		suite.True(errors.Is(err, statusError.expected), statusError.statusCode)
+	}
+}
+
+func (suite *ErrorsSuite) TestAPIErrorMessage() {
+	err := &upstream.APIError{
+		Endpoint:   upstream.CurrentWeather,
+		StatusCode: http.StatusUnauthorized,
+		Code:       401,
+		Message:    "Invalid API key",
+	}
+	suite.Equal("current_weather responded with status 401: Invalid API key", err.Error())
+
+	err.Message = ""
+	suite.Equal("current_weather responded with status 401", err.Error())
+}
+
+func (suite *ErrorsSuite) TestLatLonParams() {
+	params := upstream.LatLonParams(12.971599, -77.5946, "units", "metric", "dangling")
+	suite.Equal("12.971599", params.Get("lat"))
+	suite.Equal("-77.5946", params.Get("lon"))
+	suite.Equal("metric", params.Get("units"))
+	suite.False(params.Has("dangling"))
+}
+
+func TestErrorsSuite(t *testing.T) {
+	suite.Run(t, &ErrorsSuite{})
+}
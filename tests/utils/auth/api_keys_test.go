@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type APIKeysSuite struct {
+	suite.Suite
+}
+
+func (suite *APIKeysSuite) TestGenerateAPIKey() {
+	key, prefix, keyHash, err := utils.GenerateAPIKey()
+	suite.Nil(err)
+	suite.True(strings.HasPrefix(key, "ww_"))
+	suite.Len(key, 67)
+	suite.True(strings.HasPrefix(key, prefix))
+	suite.Len(prefix, 9)
+	suite.Equal(utils.HashAPIKey(key), keyHash)
+	suite.NotContains(keyHash, key)
+
+	otherKey, _, otherKeyHash, err := utils.GenerateAPIKey()
+	suite.Nil(err)
+	suite.NotEqual(key, otherKey)
+	suite.NotEqual(keyHash, otherKeyHash)
+}
+
+func (suite *APIKeysSuite) TestHashAPIKey() {
+	suite.Equal(utils.HashAPIKey("ww_test"), utils.HashAPIKey("ww_test"))
+	suite.NotEqual(utils.HashAPIKey("ww_test"), utils.HashAPIKey("ww_Test"))
+	suite.Len(utils.HashAPIKey("ww_test"), 64)
+}
+
+func TestAPIKeysSuite(t *testing.T) {
+	suite.Run(t, &APIKeysSuite{})
+}
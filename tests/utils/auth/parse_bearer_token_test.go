@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ParseBearerTokenSuite struct {
+	suite.Suite
+}
+
+func (suite *ParseBearerTokenSuite) TestValidBearerToken() {
+	validHeaders := []struct {
+		header string
+		token  string
+	}{
+		{"Bearer abc.def.ghi", "abc.def.ghi"},
+		{"bearer abc.def.ghi", "abc.def.ghi"},
+		{"BEARER abc.def.ghi", "abc.def.ghi"},
+		{"  Bearer   abc.def.ghi  ", "abc.def.ghi"},
+	}
+
+	for _, pair := range validHeaders {
+		token, err := utils.ParseBearerToken(pair.header)
+		suite.Nil(err)
+		suite.Equal(pair.token, token)
+	}
+}
+
+func (suite *ParseBearerTokenSuite) TestInvalidBearerToken() {
+	invalidHeaders := []struct {
+		header      string
+		expectedErr string
+	}{
+		{"", "authorization header is required"},
+		{"abc.def.ghi", "authorization header must use the Bearer scheme"},
+		{"Basic dXNlcjpwYXNz", "authorization header must use the Bearer scheme"},
+		{"   ", "authorization header is required"},
+		{"Bearer ", "bearer token is empty"},
+		{"Bearer abc def", "bearer token is malformed"},
+	}
+
+	for _, pair := range invalidHeaders {
+		token, err := utils.ParseBearerToken(pair.header)
+		suite.NotNil(err)
+		suite.Equal(pair.expectedErr, err.Error())
+		suite.Equal("", token)
+	}
+}
+
+func TestParseBearerTokenSuite(t *testing.T) {
+	suite.Run(t, &ParseBearerTokenSuite{})
+}
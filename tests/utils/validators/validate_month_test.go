@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ValidateMonthSuite struct {
+	suite.Suite
+}
+
+func (suite *ValidateMonthSuite) TestValidMonth() {
+	validMonths := []struct {
+		monthString string
+		month       time.Time
+	}{
+		{"2024-01", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-12", time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC)},
+		{"1999-06", time.Date(1999, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, pair := range validMonths {
+		month, err := utils.ValidateMonth(pair.monthString)
+		suite.Nil(err)
+		suite.Equal(pair.month, month)
+	}
+}
+
+func (suite *ValidateMonthSuite) TestDefaultMonth() {
+	now := time.Now().UTC()
+
+	month, err := utils.ValidateMonth("")
+	suite.Nil(err)
+	suite.Equal(now.Year(), month.Year())
+	suite.Equal(now.Month(), month.Month())
+	suite.Equal(1, month.Day())
+}
+
+func (suite *ValidateMonthSuite) TestInvalidMonth() {
+	invalidMonths := []string{"2024", "2024-13", "24-01", "2024-01-01", "january"}
+
+	for _, monthString := range invalidMonths {
+		_, err := utils.ValidateMonth(monthString)
+		suite.EqualError(err, "month must be formatted as YYYY-MM")
+	}
+}
+
+func TestValidateMonthSuite(t *testing.T) {
+	suite.Run(t, &ValidateMonthSuite{})
+}
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/SamPariatIL/weather-wrapper/utils"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ValidateStructSuite struct {
+	suite.Suite
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+// fieldErrors returns the field and rule of every failed field.
+func (suite *ValidateStructSuite) fieldErrors(s any) map[string]string {
+	err := utils.ValidateStruct(s)
+	if err == nil {
+		return nil
+	}
+
+	validationErr, ok := err.(*utils.ValidationError)
+	suite.True(ok)
+
+	fields := make(map[string]string, len(validationErr.Fields))
+	for _, field := range validationErr.Fields {
+		suite.NotEmpty(field.Message)
+		fields[field.Field] = field.Rule
+	}
+
+	return fields
+}
+
+func (suite *ValidateStructSuite) TestValidLatLon() {
+	validLatLonPairs := []struct {
+		lat float64
+		lon float64
+	}{
+		{0, 0},
+		{40.7128, -74.0060},
+		{-33.8688, 151.2093},
+		{51.5074, -0.1278},
+		{-90, 180},
+		{90, -180},
+		{37.7749, -122.4194},
+		{-45.0, 45.0},
+		{25.7617, -80.1918},
+	}
+
+	for _, latLon := range validLatLonPairs {
+		suite.Nil(utils.ValidateStruct(&entities.LatLonQuery{
+			Lat: float64Ptr(latLon.lat),
+			Lon: float64Ptr(latLon.lon),
+		}))
+	}
+}
+
+func (suite *ValidateStructSuite) TestInvalidLatLon() {
+	invalidLatLonPairs := []struct {
+		lat            *float64
+		lon            *float64
+		expectedFields map[string]string
+	}{
+		{nil, float64Ptr(0), map[string]string{"lat": "required"}},
+		{float64Ptr(0), nil, map[string]string{"long": "required"}},
+		{nil, nil, map[string]string{"lat": "required", "long": "required"}},
+		{float64Ptr(100), float64Ptr(0), map[string]string{"lat": "lte"}},
+		{float64Ptr(-100), float64Ptr(0), map[string]string{"lat": "gte"}},
+		{float64Ptr(0), float64Ptr(200), map[string]string{"long": "lte"}},
+		{float64Ptr(0), float64Ptr(-200), map[string]string{"long": "gte"}},
+	}
+
+	for _, latLon := range invalidLatLonPairs {
+		suite.Equal(latLon.expectedFields, suite.fieldErrors(&entities.LatLonQuery{Lat: latLon.lat, Lon: latLon.lon}))
+	}
+}
+
+func (suite *ValidateStructSuite) TestGeocodeQuery() {
+	for limit := 1; limit <= 10; limit++ {
+		suite.Nil(utils.ValidateStruct(&entities.GeocodeQuery{City: "London", Limit: limit}))
+	}
+
+	suite.Equal(map[string]string{"city": "required"}, suite.fieldErrors(&entities.GeocodeQuery{Limit: 5}))
+	suite.Equal(map[string]string{"limit": "min"}, suite.fieldErrors(&entities.GeocodeQuery{City: "London", Limit: -1}))
+	suite.Equal(map[string]string{"limit": "max"}, suite.fieldErrors(&entities.GeocodeQuery{City: "London", Limit: 11}))
+}
+
+func (suite *ValidateStructSuite) TestHistoricalAirPollutionQuery() {
+	query := entities.HistoricalAirPollutionQuery{
+		Lat:   float64Ptr(12.97),
+		Lon:   float64Ptr(77.59),
+		Start: int64Ptr(1606435200),
+		End:   int64Ptr(1606482999),
+	}
+	suite.Nil(utils.ValidateStruct(&query))
+
+	query.End = int64Ptr(1606435200)
+	suite.Nil(utils.ValidateStruct(&query))
+
+	query.End = int64Ptr(1606435199)
+	suite.Equal(map[string]string{"end": "gtefield"}, suite.fieldErrors(&query))
+
+	// Upstream keeps no history before 2020-11-27.
+	query.Start, query.End = int64Ptr(1606435199), int64Ptr(1606482999)
+	suite.Equal(map[string]string{"start": "gte"}, suite.fieldErrors(&query))
+
+	query.Start, query.End = int64Ptr(-1), int64Ptr(10)
+	suite.Equal(map[string]string{"start": "gte"}, suite.fieldErrors(&query))
+
+	// Up to now and a few minutes of clock skew, but not beyond.
+	now := time.Now().Unix()
+	query.Start, query.End = int64Ptr(now-3600), int64Ptr(now+60)
+	suite.Nil(utils.ValidateStruct(&query))
+
+	query.End = int64Ptr(now + 3600)
+	suite.Equal(map[string]string{"end": "notfuture"}, suite.fieldErrors(&query))
+
+	query.Start, query.End = nil, nil
+	suite.Equal(map[string]string{"start": "required", "end": "required"}, suite.fieldErrors(&query))
+}
+
+func (suite *ValidateStructSuite) TestUserDetails() {
+	photoURL := "https://example.com/photo.jpg"
+	user := entities.UserDetails{
+		Email:       "test@test.com",
+		PhoneNumber: "+911234567890",
+		Password:    "testpassword",
+		DisplayName: "TEST",
+		PhotoURL:    &photoURL,
+	}
+	suite.Nil(utils.ValidateStruct(&user))
+
+	user.PhotoURL = nil
+	suite.Nil(utils.ValidateStruct(&user))
+
+	invalidPhotoURL := "not a url"
+	suite.Equal(map[string]string{
+		"email":       "email",
+		"phoneNumber": "e164",
+		"password":    "min",
+		"name":        "max",
+		"photoURL":    "url",
+	}, suite.fieldErrors(&entities.UserDetails{
+		Email:       "test",
+		PhoneNumber: "1234",
+		Password:    "short",
+		DisplayName: "a display name that is far too long",
+		PhotoURL:    &invalidPhotoURL,
+	}))
+
+	suite.Equal(map[string]string{
+		"email":       "required",
+		"phoneNumber": "required",
+		"password":    "required",
+		"name":        "required",
+	}, suite.fieldErrors(&entities.UserDetails{}))
+}
+
+func (suite *ValidateStructSuite) TestRolesBody() {
+	suite.Nil(utils.ValidateStruct(&entities.RolesBody{Roles: []string{entities.RoleAdmin}}))
+	suite.Nil(utils.ValidateStruct(&entities.RolesBody{Roles: entities.Roles}))
+
+	suite.Equal(map[string]string{"roles": "required"}, suite.fieldErrors(&entities.RolesBody{}))
+	suite.Equal(map[string]string{"roles": "min"}, suite.fieldErrors(&entities.RolesBody{Roles: []string{}}))
+	suite.Equal(map[string]string{"roles[1]": "role"}, suite.fieldErrors(&entities.RolesBody{Roles: []string{"admin", "owner"}}))
+	suite.Equal(map[string]string{"roles[0]": "role"}, suite.fieldErrors(&entities.RolesBody{Roles: []string{"Admin"}}))
+}
+
+func (suite *ValidateStructSuite) TestCreateAPIKeyBody() {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	suite.Nil(utils.ValidateStruct(&entities.CreateAPIKeyBody{Name: "nightly-sync", Scopes: entities.APIKeyScopes}))
+	suite.Nil(utils.ValidateStruct(&entities.CreateAPIKeyBody{
+		Name:      "nightly-sync",
+		Scopes:    []string{entities.ScopeWeatherRead},
+		ExpiresAt: &future,
+	}))
+
+	suite.Equal(map[string]string{
+		"name":      "required",
+		"scopes[1]": "scope",
+		"expiresAt": "future",
+	}, suite.fieldErrors(&entities.CreateAPIKeyBody{
+		Scopes:    []string{entities.ScopeAirRead, "air:write"},
+		ExpiresAt: &past,
+	}))
+
+	suite.Equal(map[string]string{"scopes": "required"}, suite.fieldErrors(&entities.CreateAPIKeyBody{Name: "nightly-sync"}))
+}
+
+func (suite *ValidateStructSuite) TestCredentialsBody() {
+	suite.Nil(utils.ValidateStruct(&entities.CredentialsBody{Email: "test@test.com", Password: "testpassword"}))
+	suite.Equal(map[string]string{
+		"email":    "required",
+		"password": "required",
+	}, suite.fieldErrors(&entities.CredentialsBody{}))
+}
+
+func (suite *ValidateStructSuite) TestUsageQuery() {
+	suite.Nil(utils.ValidateStruct(&entities.UsageQuery{}))
+	suite.Nil(utils.ValidateStruct(&entities.UsageQuery{Month: "2024-10"}))
+	suite.Equal(map[string]string{"month": "datetime"}, suite.fieldErrors(&entities.UsageQuery{Month: "2024-13"}))
+	suite.Equal(map[string]string{"month": "datetime"}, suite.fieldErrors(&entities.UsageQuery{Month: "October"}))
+}
+
+func (suite *ValidateStructSuite) TestAirPollutionQuery() {
+	suite.Nil(utils.ValidateStruct(&entities.AirPollutionQuery{Lat: float64Ptr(0), Lon: float64Ptr(0)}))
+	suite.Nil(utils.ValidateStruct(&entities.AirPollutionQuery{Lat: float64Ptr(0), Lon: float64Ptr(0), Standard: "eu_caqi"}))
+	suite.Equal(map[string]string{"standard": "oneof"}, suite.fieldErrors(&entities.AirPollutionQuery{Lat: float64Ptr(0), Lon: float64Ptr(0), Standard: "owm"}))
+}
+
+func TestValidateStructSuite(t *testing.T) {
+	suite.Run(t, &ValidateStructSuite{})
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/vendors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type CacheBackendSuite struct {
+	suite.Suite
+	// shared stands in for Redis.
+	shared cache.Cache
+}
+
+func (suite *CacheBackendSuite) SetupTest() {
+	suite.shared = cache.NewLRUCache(10)
+}
+
+func (suite *CacheBackendSuite) TestBackends() {
+	for _, backend := range []string{config.CacheBackendRedis, config.CacheBackendMemory, config.CacheBackendTiered} {
+		store, err := vendors.NewCache(config.CacheConfig{Backend: backend, LRUSize: 10}, suite.shared)
+		suite.NoError(err, backend)
+		suite.NotNil(store, backend)
+	}
+}
+
+func (suite *CacheBackendSuite) TestUnknownBackendsAreRejected() {
+	_, err := vendors.NewCache(config.CacheConfig{Backend: "memcached", LRUSize: 10}, suite.shared)
+	suite.ErrorContains(err, "memcached")
+}
+
+func (suite *CacheBackendSuite) TestLRUBackendsNeedAPositiveSize() {
+	for _, backend := range []string{config.CacheBackendMemory, config.CacheBackendTiered} {
+		for _, size := range []int{0, -1} {
+			_, err := vendors.NewCache(config.CacheConfig{Backend: backend, LRUSize: size}, suite.shared)
+			suite.Error(err, backend)
+		}
+	}
+
+	// Redis alone does not use the LRU.
+	_, err := vendors.NewCache(config.CacheConfig{Backend: config.CacheBackendRedis}, suite.shared)
+	suite.NoError(err)
+}
+
+func TestCacheBackendSuite(t *testing.T) {
+	suite.Run(t, new(CacheBackendSuite))
+}
@@ -0,0 +1,207 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize caps how much of an error response is read.
+const maxErrorBodySize = 64 << 10
+
+type Client interface {
+	// Get calls endpoint with params and decodes the JSON response into v.
+	// The API key is added by the client. Transient failures are retried,
+	// and while the circuit breaker of endpoint is open Get fails fast with
+	// ErrCircuitOpen.
+	Get(ctx context.Context, endpoint Endpoint, params url.Values, v any) error
+	// Breakers returns the state of the circuit breaker of every endpoint.
+	Breakers() []BreakerStatus
+}
+
+type client struct {
+	httpClient     *http.Client
+	endpoints      map[Endpoint]endpointConfig
+	breakers       map[Endpoint]*breaker
+	timeout        time.Duration
+	timeouts       map[string]time.Duration
+	retries        int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	logger         *zap.Logger
+}
+
+func NewClient(conf *config.Config, zl *zap.Logger) Client {
+	endpoints := endpointConfigs(conf)
+
+	breakers := make(map[Endpoint]*breaker, len(endpoints))
+	for endpoint := range endpoints {
+		breakers[endpoint] = newBreaker(conf.UpstreamConfig.BreakerThreshold, conf.UpstreamConfig.BreakerOpenDuration)
+	}
+
+	return &client{
+		httpClient:     &http.Client{},
+		endpoints:      endpoints,
+		breakers:       breakers,
+		timeout:        conf.UpstreamConfig.Timeout,
+		timeouts:       conf.UpstreamConfig.Timeouts,
+		retries:        conf.UpstreamConfig.Retries,
+		retryBaseDelay: conf.UpstreamConfig.RetryBaseDelay,
+		retryMaxDelay:  conf.UpstreamConfig.RetryMaxDelay,
+		logger:         zl,
+	}
+}
+
+func (c *client) Get(ctx context.Context, endpoint Endpoint, params url.Values, v any) error {
+	endpointConf, ok := c.endpoints[endpoint]
+	if !ok {
+		return fmt.Errorf("unknown upstream endpoint %q", endpoint)
+	}
+
+	b := c.breakers[endpoint]
+	if !b.allow() {
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, endpoint)
+	}
+
+	err := c.getWithRetries(ctx, endpoint, endpointConf, params, v)
+
+	// Neither a caller that went away nor a spent quota says anything about
+	// the health of upstream.
+	if ctx.Err() != nil || errors.Is(err, ErrRateLimited) {
+		b.abort()
+		return err
+	}
+
+	failed := err != nil && isFailure(err)
+	b.done(failed)
+
+	if failed && b.status(endpoint).State == BreakerOpen {
+		c.logger.Warn(fmt.Sprintf("Circuit breaker of %s is open", endpoint))
+	}
+
+	return err
+}
+
+func (c *client) Breakers() []BreakerStatus {
+	statuses := make([]BreakerStatus, 0, len(c.breakers))
+	for endpoint, b := range c.breakers {
+		statuses = append(statuses, b.status(endpoint))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+
+	return statuses
+}
+
+func (c *client) getWithRetries(ctx context.Context, endpoint Endpoint, endpointConf endpointConfig, params url.Values, v any) error {
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		err = c.get(ctx, endpoint, endpointConf, params, v)
+		if err == nil || attempt >= c.retries || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff(attempt, c.retryBaseDelay, c.retryMaxDelay)
+		c.logger.Info(fmt.Sprintf("Retrying %s in %s after attempt %d failed: %v", endpoint, delay, attempt+1, err))
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+func (c *client) get(ctx context.Context, endpoint Endpoint, endpointConf endpointConfig, params url.Values, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(endpoint))
+	defer cancel()
+
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("appid", endpointConf.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, buildURL(endpointConf)+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	started := time.Now()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The error embeds the URL, which carries the API key.
+		return fmt.Errorf("%w: %s request failed: %w", ErrUnavailable, endpoint, redact(err))
+	}
+	defer resp.Body.Close()
+
+	c.logger.Debug(fmt.Sprintf("%s responded with status %d in %s", endpoint, resp.StatusCode, time.Since(started)))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return decodeError(endpoint, resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("%w: failed to decode the %s response: %w", ErrBadPayload, endpoint, err)
+	}
+
+	return nil
+}
+
+func (c *client) timeoutFor(endpoint Endpoint) time.Duration {
+	if timeout, ok := c.timeouts[string(endpoint)]; ok {
+		return timeout
+	}
+
+	return c.timeout
+}
+
+// buildURL joins the base URL and path of an endpoint. Base URLs are host
+// names with an optional path, and default to https when no scheme is given.
+func buildURL(endpointConf endpointConfig) string {
+	baseURL := strings.TrimSuffix(endpointConf.baseURL, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	return baseURL + endpointConf.path
+}
+
+func decodeError(endpoint Endpoint, resp *http.Response) error {
+	apiErr := &APIError{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+	}
+
+	var body errorBody
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBodySize)).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+		apiErr.Code, _ = body.code()
+	}
+
+	return apiErr
+}
+
+// redact strips the URL from transport errors, so the API key never ends up
+// in logs or responses.
+func redact(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+
+	return err
+}
@@ -0,0 +1,35 @@
+package upstream
+
+import "github.com/SamPariatIL/weather-wrapper/config"
+
+// Endpoint names an OpenWeatherMap API the services call. The names are also
+// the keys of per-endpoint settings such as UPSTREAM_TIMEOUTS.
+type Endpoint string
+
+const (
+	CurrentWeather         Endpoint = "current_weather"
+	WeatherForecast        Endpoint = "weather_forecast"
+	GeocodeDirect          Endpoint = "geocode_direct"
+	GeocodeReverse         Endpoint = "geocode_reverse"
+	CurrentAirPollution    Endpoint = "current_air_pollution"
+	AirPollutionForecast   Endpoint = "air_pollution_forecast"
+	HistoricalAirPollution Endpoint = "air_pollution_history"
+)
+
+type endpointConfig struct {
+	baseURL string
+	path    string
+	apiKey  string
+}
+
+func endpointConfigs(conf *config.Config) map[Endpoint]endpointConfig {
+	return map[Endpoint]endpointConfig{
+		CurrentWeather:         {conf.WeatherConfig.BaseURL, "/weather", conf.WeatherConfig.APIKey},
+		WeatherForecast:        {conf.WeatherConfig.BaseURL, "/forecast", conf.WeatherConfig.APIKey},
+		GeocodeDirect:          {conf.GeocodeConfig.BaseURL, "/direct", conf.GeocodeConfig.APIKey},
+		GeocodeReverse:         {conf.GeocodeConfig.BaseURL, "/reverse", conf.GeocodeConfig.APIKey},
+		CurrentAirPollution:    {conf.AirPollutionConfig.BaseURL, "", conf.AirPollutionConfig.APIKey},
+		AirPollutionForecast:   {conf.AirPollutionConfig.BaseURL, "/forecast", conf.AirPollutionConfig.APIKey},
+		HistoricalAirPollution: {conf.AirPollutionConfig.BaseURL, "/history", conf.AirPollutionConfig.APIKey},
+	}
+}
@@ -0,0 +1,80 @@
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Upstream failures are classified into these errors, so callers can test
+// them with errors.Is without knowing OpenWeatherMap's status codes.
+var (
+	ErrUnauthorized = errors.New("upstream rejected the api key")
+	ErrNotFound     = errors.New("upstream has no data for the request")
+	ErrRateLimited  = errors.New("upstream rate limit exceeded")
+	ErrUnavailable  = errors.New("upstream is unavailable")
+	ErrBadPayload   = errors.New("upstream returned an unexpected payload")
+	ErrInvalidInput = errors.New("upstream rejected the request parameters")
+	// ErrCircuitOpen is returned without calling upstream while the circuit
+	// breaker of an endpoint is open. It is an ErrUnavailable.
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
+)
+
+// APIError is a non-2xx response from OpenWeatherMap, decoded from error
+// bodies such as {"cod":401,"message":"Invalid API key"}.
+type APIError struct {
+	Endpoint   Endpoint
+	StatusCode int
+	// Code is the cod field of the body, or 0 when there was none.
+	Code    int
+	Message string
+}
+
+func (ae *APIError) Error() string {
+	if ae.Message == "" {
+		return fmt.Sprintf("%s responded with status %d", ae.Endpoint, ae.StatusCode)
+	}
+
+	return fmt.Sprintf("%s responded with status %d: %s", ae.Endpoint, ae.StatusCode, ae.Message)
+}
+
+// Unwrap classifies the error by its status code.
+func (ae *APIError) Unwrap() error {
+	switch {
+	case ae.StatusCode == http.StatusUnauthorized || ae.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case ae.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case ae.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case ae.StatusCode >= http.StatusBadRequest && ae.StatusCode < http.StatusInternalServerError:
+		return ErrInvalidInput
+	default:
+		return ErrUnavailable
+	}
+}
+
+// errorBody is the error payload of OpenWeatherMap. cod is a number on some
+// APIs and a string on others.
+type errorBody struct {
+	Cod     json.RawMessage `json:"cod"`
+	Message string          `json:"message"`
+}
+
+func (eb errorBody) code() (int, bool) {
+	var code int
+	if err := json.Unmarshal(eb.Cod, &code); err == nil {
+		return code, true
+	}
+
+	var codeString string
+	if err := json.Unmarshal(eb.Cod, &codeString); err == nil {
+		if code, err = strconv.Atoi(codeString); err == nil {
+			return code, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,21 @@
+package upstream
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// LatLonParams builds the lat and lon parameters, followed by extra key value
+// pairs.
+func LatLonParams(lat, lon float32, extra ...string) url.Values {
+	params := url.Values{
+		"lat": {strconv.FormatFloat(float64(lat), 'f', -1, 32)},
+		"lon": {strconv.FormatFloat(float64(lon), 'f', -1, 32)},
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		params.Set(extra[i], extra[i+1])
+	}
+
+	return params
+}
@@ -0,0 +1,56 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// isTransient reports whether a failed call is worth retrying: transport
+// errors, timeouts of a single attempt and 5xx responses.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return errors.Is(err, ErrUnavailable)
+}
+
+// isFailure reports whether err says something is wrong with upstream, as
+// opposed to the request, and should count towards opening the breaker. Rate
+// limited calls do not: upstream is up, and only our quota is spent.
+func isFailure(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrBadPayload)
+}
+
+// backoff returns a random delay between 0 and the exponential backoff of the
+// attempt, capped at maxDelay ("full jitter").
+func backoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	delay := baseDelay << attempt
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return rand.N(delay + 1)
+}
+
+// sleep waits for delay, returning early with the error of ctx if it ends.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
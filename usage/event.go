@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type contextKey struct{}
+
+// Record describes a single metered request.
+type Record struct {
+	Principal    string
+	UID          string
+	Route        string
+	StatusCode   int
+	CacheHit     bool
+	UpstreamCall bool
+	Latency      time.Duration
+	Timestamp    time.Time
+}
+
+// Event collects the Record of an in-flight request. Services mark cache hits
+// and upstream calls on the event carried by the request context, possibly
+// from other goroutines.
+type Event struct {
+	mu     sync.Mutex
+	record Record
+}
+
+func NewEvent(principal, uid string, timestamp time.Time) *Event {
+	return &Event{
+		record: Record{
+			Principal: principal,
+			UID:       uid,
+			Timestamp: timestamp,
+		},
+	}
+}
+
+func NewContext(ctx context.Context, event *Event) context.Context {
+	return context.WithValue(ctx, contextKey{}, event)
+}
+
+// FromContext returns the event of the current request, or nil if the
+// request is not metered.
+func FromContext(ctx context.Context) *Event {
+	event, _ := ctx.Value(contextKey{}).(*Event)
+	return event
+}
+
+// MarkCacheHit records that the request was answered from the cache.
+func MarkCacheHit(ctx context.Context) {
+	if event := FromContext(ctx); event != nil {
+		event.mu.Lock()
+		event.record.CacheHit = true
+		event.mu.Unlock()
+	}
+}
+
+// MarkUpstreamCall records that the request triggered a call to the
+// upstream weather API.
+func MarkUpstreamCall(ctx context.Context) {
+	if event := FromContext(ctx); event != nil {
+		event.mu.Lock()
+		event.record.UpstreamCall = true
+		event.mu.Unlock()
+	}
+}
+
+// Finish stamps the outcome of the request and returns the final record.
+func (e *Event) Finish(route string, statusCode int, latency time.Duration) Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.record.Route = route
+	e.record.StatusCode = statusCode
+	e.record.Latency = latency
+
+	return e.record
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	apiKeyPrefix        = "ww_"
+	apiKeyRandomBytes   = 32
+	apiKeyDisplayLength = len(apiKeyPrefix) + 6
+)
+
+// GenerateAPIKey returns a new random API key along with the short prefix
+// shown to users and the hash that gets stored.
+func GenerateAPIKey() (key, prefix, keyHash string, err error) {
+	randomBytes := make([]byte, apiKeyRandomBytes)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + hex.EncodeToString(randomBytes)
+
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes a plaintext API key. Keys carry 256 bits of randomness,
+// so a fast hash is enough and allows lookups by hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
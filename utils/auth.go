@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+func ParseBearerToken(header string) (string, error) {
+	parts := strings.Fields(header)
+	if len(parts) == 0 {
+		return "", errors.New("authorization header is required")
+	}
+
+	if !strings.EqualFold(parts[0], "Bearer") {
+		return "", errors.New("authorization header must use the Bearer scheme")
+	}
+
+	if len(parts) == 1 {
+		return "", errors.New("bearer token is empty")
+	}
+
+	if len(parts) > 2 {
+		return "", errors.New("bearer token is malformed")
+	}
+
+	return parts[1], nil
+}
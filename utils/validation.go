@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/entities"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	validate     *validator.Validate
+	validateOnce sync.Once
+)
+
+// clockSkew is how far in the future a unix time may be and still pass the
+// notfuture rule, as the clocks of clients drift from ours.
+const clockSkew = 5 * time.Minute
+
+// FieldError describes why a single field failed validation. Field is the
+// name the client sent, taken from the json or query tag.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	messages := make([]string, len(ve.Fields))
+	for i, field := range ve.Fields {
+		messages[i] = field.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// ValidateStruct checks the validate tags of s. Failures are returned as a
+// *ValidationError listing every invalid field.
+func ValidateStruct(s any) error {
+	err := getValidator().Struct(s)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]FieldError, len(validationErrors))
+	for i, fieldError := range validationErrors {
+		fields[i] = FieldError{
+			Field:   fieldName(fieldError),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: fieldMessage(fieldError),
+		}
+	}
+
+	return &ValidationError{Fields: fields}
+}
+
+func getValidator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "query", "params"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+
+				if name != "" {
+					return name
+				}
+			}
+
+			return field.Name
+		})
+
+		_ = validate.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+			return slices.Contains(entities.Roles, fl.Field().String())
+		})
+
+		_ = validate.RegisterValidation("scope", func(fl validator.FieldLevel) bool {
+			return slices.Contains(entities.APIKeyScopes, fl.Field().String())
+		})
+
+		_ = validate.RegisterValidation("future", func(fl validator.FieldLevel) bool {
+			value, ok := fl.Field().Interface().(time.Time)
+			return ok && value.After(time.Now())
+		})
+
+		_ = validate.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+			return fl.Field().Int() <= time.Now().Add(clockSkew).Unix()
+		})
+	})
+
+	return validate
+}
+
+// fieldName returns the path of the field without the name of the top level
+// struct, e.g. "scopes[1]" rather than "CreateAPIKeyBody.scopes[1]".
+func fieldName(fieldError validator.FieldError) string {
+	_, name, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+
+	return name
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	field := fieldName(fieldError)
+	param := fieldError.Param()
+
+	switch fieldError.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "e164":
+		return fmt.Sprintf("%s must be a phone number in E.164 format", field)
+	case "url":
+		return fmt.Sprintf("%s must be a valid url", field)
+	case "min":
+		if isCollection(fieldError.Kind()) {
+			return fmt.Sprintf("%s must contain at least %s items", field, param)
+		}
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at least %s characters long", field, param)
+		}
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "max":
+		if isCollection(fieldError.Kind()) {
+			return fmt.Sprintf("%s must contain at most %s items", field, param)
+		}
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at most %s characters long", field, param)
+		}
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "gte":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, param)
+	case "lte":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, param)
+	case "gtefield":
+		return fmt.Sprintf("%s must not be before %s", field, strings.ToLower(param))
+	case "required_with":
+		return fmt.Sprintf("%s is required with %s", field, strings.ToLower(strings.Join(strings.Fields(param), ", ")))
+	case "required_without_all":
+		return fmt.Sprintf("%s is required without %s", field, strings.ToLower(strings.Join(strings.Fields(param), " or ")))
+	case "excluded_with":
+		return fmt.Sprintf("%s cannot be used with %s", field, strings.ToLower(param))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(param), ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must match the format %s", field, param)
+	case "role":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(entities.Roles, ", "))
+	case "scope":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(entities.APIKeyScopes, ", "))
+	case "future":
+		return fmt.Sprintf("%s must be in the future", field)
+	case "notfuture":
+		return fmt.Sprintf("%s must not be in the future", field)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, fieldError.Tag())
+	}
+}
+
+func isCollection(kind reflect.Kind) bool {
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
@@ -0,0 +1,66 @@
+package vendors
+
+import (
+	"fmt"
+	"github.com/SamPariatIL/weather-wrapper/cache"
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"log"
+)
+
+var (
+	cacheStore   cache.Cache
+	cacheBreaker cache.BreakerCache
+)
+
+func InitCache() error {
+	conf := config.GetConfig().CacheConfig
+
+	var shared cache.Cache
+	if conf.Backend != config.CacheBackendMemory {
+		cacheBreaker = cache.NewBreakerCache(cache.NewRedisCache(redisClient), conf.BreakerThreshold, conf.BreakerOpenDuration)
+		shared = cacheBreaker
+	}
+
+	store, err := NewCache(conf, shared)
+	if err != nil {
+		return err
+	}
+
+	cacheStore = store
+
+	log.Printf("Using the %s cache backend", conf.Backend)
+	return nil
+}
+
+// NewCache returns the cache backend selected by conf, on top of shared for
+// the backends using Redis.
+func NewCache(conf config.CacheConfig, shared cache.Cache) (cache.Cache, error) {
+	switch conf.Backend {
+	case config.CacheBackendRedis:
+		return shared, nil
+	case config.CacheBackendMemory, config.CacheBackendTiered:
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", conf.Backend)
+	}
+
+	if conf.LRUSize <= 0 {
+		return nil, fmt.Errorf("the %s cache backend needs a positive LRU size, got %d", conf.Backend, conf.LRUSize)
+	}
+
+	local := cache.NewLRUCache(conf.LRUSize)
+	if conf.Backend == config.CacheBackendMemory {
+		return local, nil
+	}
+
+	return cache.NewTieredCache(local, shared, conf.LocalTTL), nil
+}
+
+func GetCache() cache.Cache {
+	return cacheStore
+}
+
+// GetCacheBreaker returns the circuit breaker in front of Redis, or nil for
+// the memory backend.
+func GetCacheBreaker() cache.BreakerCache {
+	return cacheBreaker
+}
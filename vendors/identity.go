@@ -0,0 +1,37 @@
+package vendors
+
+import (
+	"github.com/SamPariatIL/weather-wrapper/config"
+	"github.com/SamPariatIL/weather-wrapper/identity"
+	"log"
+)
+
+var identityProvider identity.Provider
+
+// InitIdentityProvider sets up the configured identity provider. Firebase is
+// only initialized when it is the selected provider, so the server can run
+// offline with the local one. It must run after InitPostgres.
+func InitIdentityProvider() {
+	conf := config.GetConfig()
+
+	switch conf.IdentityConfig.Provider {
+	case identity.ProviderFirebase:
+		InitFirebaseAdmin()
+		identityProvider = identity.NewFirebaseProvider(GetFirebaseAuth(), conf.IdentityConfig.FirebaseAPIKey)
+	case identity.ProviderLocal:
+		var err error
+
+		identityProvider, err = identity.NewLocalProvider(GetPostgresDB(), conf.IdentityConfig)
+		if err != nil {
+			log.Fatalf("Failed to initialize the local identity provider: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown identity provider %q", conf.IdentityConfig.Provider)
+	}
+
+	log.Printf("Initialized %s identity provider!", conf.IdentityConfig.Provider)
+}
+
+func GetIdentityProvider() identity.Provider {
+	return identityProvider
+}
@@ -30,6 +30,13 @@ func InitPostgres() {
 	if err != nil {
 		log.Fatalf("Failed to connect to Postgres: %v", err)
 	}
+
+	err = postgresDB.AutoMigrate(config.EntityDBMapping()...)
+	if err != nil {
+		log.Fatalf("Failed to migrate Postgres: %v", err)
+	}
+
+	log.Println("Connected to Postgres!")
 }
 
 func GetPostgresDB() *gorm.DB {
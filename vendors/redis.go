@@ -16,13 +16,23 @@ func InitRedis() {
 		Addr:     conf.RedisConfig.Addr,
 		Password: conf.RedisConfig.Password,
 		DB:       conf.RedisConfig.DB,
+		// Without them a hung Redis would hold requests for the default
+		// socket timeouts instead of letting them fall through to upstream.
+		DialTimeout:  conf.RedisConfig.DialTimeout,
+		ReadTimeout:  conf.RedisConfig.ReadTimeout,
+		WriteTimeout: conf.RedisConfig.WriteTimeout,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), conf.RedisConfig.Timeout)
 	defer cancel()
 
 	_, err := redisClient.Ping(ctx).Result()
-	if err != nil {
+	if err != nil && conf.CacheConfig.Backend == config.CacheBackendMemory {
+		// The cache lives in process; rate limits and quotas let requests
+		// through while Redis is unreachable.
+		log.Printf("Failed to connect to Redis, continuing with the memory cache: %v", err)
+		return
+	} else if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
@@ -1,7 +1,15 @@
 package vendors
 
+import "log"
+
 func Setup() {
 	InitRedis()
-	InitFirebaseAdmin()
-	//InitPostgres()
+
+	err := InitCache()
+	if err != nil {
+		log.Fatalf("Failed to set up the cache: %v", err)
+	}
+
+	InitPostgres()
+	InitIdentityProvider()
 }